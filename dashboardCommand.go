@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/gitu/gitlab-util/pkg/glui"
+	"github.com/urfave/cli/v2"
+)
+
+// dashboardCommand shows the work of the current user in a single screen
+func dashboardCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "dashboard",
+		Usage: "show your assigned issues, merge requests awaiting your review, your open merge requests and their failed pipelines",
+		Action: func(c *cli.Context) error {
+			return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+				return glui.Dashboard(c.Context, mrm)
+			})
+		},
+	}
+}
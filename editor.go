@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const mergeRequestTemplateHelp = `
+# Enter the merge request title on the first line and the description below.
+# Lines starting with '#' are ignored, an empty title aborts.
+`
+
+// editMessage opens the template in $VISUAL or $EDITOR and splits the result into title (first line)
+// and description (the remaining lines)
+func editMessage(template string) (string, string, error) {
+	f, err := os.CreateTemp("", "gitlab-util-*.md")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(template + "\n" + mergeRequestTemplateHelp)
+	if err != nil {
+		return "", "", err
+	}
+	err = f.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", "", err
+	}
+
+	content, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", "", err
+	}
+	var lines []string
+	for _, l := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(l, "#") {
+			lines = append(lines, l)
+		}
+	}
+	title := ""
+	if len(lines) > 0 {
+		title = strings.TrimSpace(lines[0])
+		lines = lines[1:]
+	}
+	if title == "" {
+		return "", "", errors.New("aborted due to empty title")
+	}
+	return title, strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
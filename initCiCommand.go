@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ciJobTemplate is the scheduled auto-merge job written by init-ci. The job token can't approve or merge, the token
+// is taken from the masked GITLAB_TOKEN variable. The cache keeps the merge targets and their history between runs.
+var ciJobTemplate = template.Must(template.New("ci").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`# auto-merge {{ .Filter }} with gitlab-util, generated by gitlab-util init-ci
+#
+# - add a masked and protected ci/cd variable GITLAB_TOKEN with a token with api scope, the job token can't approve
+#   or merge merge requests
+# - add a pipeline schedule (build > pipeline schedules) running this job, e.g. every hour
+{{ .Job }}:
+  image: {{ quote .Image }}
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "schedule"
+  variables:
+    GITLAB_UTIL_DB_PATH: $CI_PROJECT_DIR/.gitlab-util
+    GITLAB_UTIL_LOG_JSON: "true"
+  cache:
+    key: gitlab-util-{{ .Job }}
+    paths:
+      - .gitlab-util/
+  script:
+    - go install github.com/gitu/gitlab-util@{{ .Version }}
+    - gitlab-util auto-merge{{ range .Args }} {{ . }}{{ end }} --enable-all-matching --once
+  # exit code 2: some merge requests are held (e.g. pipeline still running), they are retried by the next run
+  allow_failure:
+    exit_codes: [2]
+`))
+
+// ciJob are the values of ciJobTemplate
+type ciJob struct {
+	Job     string
+	Image   string
+	Version string
+	Filter  string
+	Args    []string
+}
+
+// initCiCommand generates a gitlab ci job running auto-merge on a schedule
+func initCiCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "init-ci",
+		Usage: "generate a gitlab ci job that runs auto-merge --once in scheduled pipelines, add it to .gitlab-ci.yml or include it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "author",
+				Usage: "author of the merge requests to auto merge (e.g. renovate-bot)",
+			},
+			&cli.StringFlag{
+				Name:  "reviewer",
+				Usage: "reviewer of the merge requests to auto merge",
+			},
+			&cli.StringFlag{
+				Name:  "reviewer-group",
+				Usage: "also auto merge the merge requests with a member of this group (path or id) as reviewer",
+			},
+			&cli.StringFlag{
+				Name:  "assignee",
+				Usage: "assignee of the merge requests to auto merge (username, None or Any)",
+			},
+			&cli.StringFlag{
+				Name:  "milestone",
+				Usage: "only auto merge merge requests of this milestone (title, None or Any)",
+			},
+			&cli.StringFlag{
+				Name:  "job",
+				Usage: "name of the generated job",
+				Value: "auto-merge",
+			},
+			&cli.StringFlag{
+				Name:  "image",
+				Usage: "image of the job, gitlab-util is installed with go install",
+				Value: "golang:1.22",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "file to write the job to (e.g. .gitlab/ci/auto-merge.yml to include it), - for stdout",
+				Value:   "-",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "overwrite an existing output file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if !hasMergeRequestFilter(c) {
+				return cli.Exit("init-ci needs --author, --reviewer, --reviewer-group and/or --assignee", 1)
+			}
+			job := ciJob{Job: c.String("job"), Image: c.String("image"), Version: version}
+			if job.Version == "dev" {
+				job.Version = "latest"
+			}
+			var filter []string
+			for _, name := range []string{"author", "reviewer", "reviewer-group", "assignee", "milestone"} {
+				if v := c.String(name); v != "" {
+					job.Args = append(job.Args, "--"+name, strconv.Quote(v))
+					filter = append(filter, name+" "+v)
+				}
+			}
+			job.Filter = "merge requests of " + strings.Join(filter, ", ")
+
+			if c.String("output") == "-" {
+				return ciJobTemplate.Execute(os.Stdout, job)
+			}
+			return writeCiJob(c.String("output"), c.Bool("force"), job)
+		},
+	}
+}
+
+// writeCiJob writes the job to path, an existing file is only replaced with force
+func writeCiJob(path string, force bool, job ciJob) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return cli.Exit(fmt.Sprintf("%s exists, use --force to overwrite it or include the job from another file", path), 1)
+	}
+	if err != nil {
+		return err
+	}
+	err = ciJobTemplate.Execute(f, job)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "wrote %s, include it from .gitlab-ci.yml and add a pipeline schedule\n", path)
+	return nil
+}
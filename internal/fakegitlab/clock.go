@@ -0,0 +1,119 @@
+package fakegitlab
+
+import (
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a ggl.Clock which only moves when it is advanced, so the scheduling of a MergeRequestManager runs
+// deterministically and hours of it are simulated in milliseconds
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// timer is a pending timer or the next tick of a ticker, period is 0 for timers
+type timer struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewClock creates a Clock starting at now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) NewTimer(d time.Duration) ggl.Timer {
+	return &stopper{clock: c, timer: c.add(d, 0)}
+}
+
+func (c *Clock) NewTicker(d time.Duration) ggl.Ticker {
+	if d <= 0 {
+		panic("fakegitlab: non-positive interval for NewTicker")
+	}
+	return &stopper{clock: c, timer: c.add(d, d)}
+}
+
+func (c *Clock) add(d time.Duration, period time.Duration) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{at: c.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Waiters is the number of running timers and tickers, a simulation advances the clock once the loops it drives are
+// waiting. Fired and stopped timers don't count.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Advance moves the clock by d and fires the timers which are due in the order of their time. Like time.Ticker a
+// ticker drops the ticks its reader is too slow for.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].at.Before(c.timers[j].at)
+		})
+		if len(c.timers) == 0 || c.timers[0].at.After(end) {
+			break
+		}
+		t := c.timers[0]
+		c.now = t.at
+		select {
+		case t.c <- t.at:
+		default:
+		}
+		if t.period > 0 {
+			t.at = t.at.Add(t.period)
+		} else {
+			c.timers = c.timers[1:]
+		}
+	}
+	c.now = end
+}
+
+// remove drops a stopped timer or ticker
+func (c *Clock) remove(t *timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// stopper is a timer or ticker of the clock
+type stopper struct {
+	clock *Clock
+	timer *timer
+}
+
+func (s *stopper) C() <-chan time.Time {
+	return s.timer.c
+}
+
+func (s *stopper) Stop() {
+	s.clock.remove(s.timer)
+}
@@ -0,0 +1,45 @@
+package fakegitlab_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"testing"
+	"time"
+)
+
+func TestClockWaiters(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := fakegitlab.NewClock(start)
+	fired := clock.NewTimer(time.Minute)
+	abandoned := clock.NewTimer(time.Minute)
+	ticker := clock.NewTicker(30 * time.Second)
+	if n := clock.Waiters(); n != 3 {
+		t.Fatalf("%d waiters, want 3", n)
+	}
+
+	abandoned.Stop()
+	clock.Advance(time.Minute)
+	if at := <-fired.C(); !at.Equal(start.Add(time.Minute)) {
+		t.Errorf("timer fired at %s", at)
+	}
+	// the ticker dropped the tick its reader was too slow for
+	if at := <-ticker.C(); !at.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("ticker ticked at %s", at)
+	}
+	select {
+	case at := <-abandoned.C():
+		t.Errorf("stopped timer fired at %s", at)
+	default:
+	}
+	if n := clock.Waiters(); n != 1 {
+		t.Errorf("%d waiters after the timers fired or stopped, want the ticker", n)
+	}
+
+	ticker.Stop()
+	fired.Stop()
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("%d waiters after stopping the ticker", n)
+	}
+	if !clock.Now().Equal(start.Add(time.Minute)) {
+		t.Errorf("now is %s", clock.Now())
+	}
+}
@@ -0,0 +1,40 @@
+package fakegitlab
+
+import (
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+)
+
+// Harness wires a MergeRequestManager to a fake gitlab and an in-memory database
+type Harness struct {
+	Server  *Server
+	Manager *ggl.MergeRequestManager
+	db      *pebble.DB
+}
+
+// NewHarness starts a fake gitlab with the scenario and creates a MergeRequestManager for it
+func NewHarness(scenario Scenario, opts ...ggl.Option) (*Harness, error) {
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	if err != nil {
+		return nil, err
+	}
+	s := New(scenario)
+	gl, err := s.Client()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	m, err := ggl.NewMergeRequestManager(append([]ggl.Option{ggl.WithGitLab(gl), ggl.WithDB(db)}, opts...)...)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &Harness{Server: s, Manager: m, db: db}, nil
+}
+
+// Close stops the fake gitlab and closes the database
+func (h *Harness) Close() error {
+	h.Server.Close()
+	return h.db.Close()
+}
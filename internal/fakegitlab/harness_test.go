@@ -0,0 +1,64 @@
+package fakegitlab_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenovateBumpAutoMerges(t *testing.T) {
+	ctx := context.Background()
+	scenario := fakegitlab.RenovateBump()
+	clock := fakegitlab.NewClock(time.Now())
+	h, err := fakegitlab.NewHarness(scenario, ggl.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.Manager.Author("renovate-bot")
+	if err := h.Manager.FetchMergeRequests(ctx); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := h.Manager.PullDiff(ctx, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Manager.ApproveAndMergeMergeRequest(ctx, 101, diff); err != nil {
+		t.Fatal(err)
+	}
+
+	var result ggl.OnceResult
+	for i := 0; i < 10 && result.Merged == 0; i++ {
+		result, err = h.Manager.ProcessOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Errors > 0 {
+			t.Fatalf("run %d failed: %+v", i, result)
+		}
+		clock.Advance(time.Minute)
+	}
+
+	mr := h.Server.MergeRequest(101)
+	if mr.State != "merged" || mr.MergedAt == nil {
+		t.Fatalf("merge request is %s, want merged", mr.State)
+	}
+	target, err := h.Manager.Target(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Active || target.Outcome.State != ggl.OutcomeMerged {
+		t.Errorf("target is active %v with outcome %s, want inactive and merged", target.Active, target.Outcome.State)
+	}
+	var states []string
+	for _, e := range target.History {
+		states = append(states, e.State)
+	}
+	want := []string{"enabled", string(ggl.OutcomeApproved), string(ggl.OutcomeRescheduled), string(ggl.OutcomeMerged)}
+	if !slices.Equal(states, want) {
+		t.Errorf("history is %v, want %v (approved, waited for the pipeline, merged)", states, want)
+	}
+}
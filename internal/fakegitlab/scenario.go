@@ -0,0 +1,187 @@
+package fakegitlab
+
+import (
+	"github.com/xanzy/go-gitlab"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// DetailedMergeStatuses are the detailed merge status values of gitlab, including ones the processing doesn't handle
+var DetailedMergeStatuses = []string{
+	"approvals_syncing", "blocked_status", "checking", "ci_must_pass", "ci_still_running", "commits_status", "conflict",
+	"discussions_not_resolved", "draft_status", "external_status_checks", "jira_association_missing", "locked_lfs_files",
+	"locked_paths", "mergeable", "need_rebase", "not_approved", "not_open", "policies_denied", "preparing",
+	"requested_changes", "security_policy_pipeline_check", "unchecked",
+}
+
+// Scenario is a fixture of the gitlab state a test starts from
+type Scenario struct {
+	Projects      []*gitlab.Project
+	MergeRequests []*gitlab.MergeRequest
+	Diffs         map[int][]*gitlab.MergeRequestDiff
+	// Labels are the labels available per project (by id)
+	Labels map[int][]*gitlab.Label
+	// Members are the members per project (by id)
+	Members map[int][]*gitlab.ProjectMember
+	// Bridges are the trigger jobs per pipeline (by id), merge requests reference their pipeline as HeadPipeline
+	Bridges map[int][]*gitlab.Bridge
+	// Statuses scripts the detailed merge status returned by consecutive reads of a merge request (by id)
+	Statuses map[int][]string
+	// Files are the files on the default branch per project (by id) and path
+	Files map[int]map[string]string
+	// GroupMembers are the members per group (by path)
+	GroupMembers map[string][]*gitlab.GroupMember
+	// ApprovalRules are the approval rules per merge request (by id), without rules there is no approval state
+	ApprovalRules map[int][]*gitlab.MergeRequestApprovalRule
+	// Jobs are the jobs per pipeline (by id) including retried ones, retrying the pipeline adds a pending attempt of
+	// each failed job
+	Jobs map[int][]*gitlab.Job
+	// TargetDiffs are the changes on the default branch per project (by id) since the merge requests branched off
+	TargetDiffs map[int][]*gitlab.Diff
+}
+
+// Project creates a project fixture
+func Project(id int, path string) *gitlab.Project {
+	return &gitlab.Project{
+		ID:                id,
+		Name:              path,
+		Path:              path,
+		PathWithNamespace: "group/" + path,
+		NameWithNamespace: "group / " + path,
+		DefaultBranch:     "main",
+		WebURL:            "https://gitlab.example.com/group/" + path,
+	}
+}
+
+// MergeRequest creates an open merge request fixture authored by author
+func MergeRequest(id int, project *gitlab.Project, iid int, title string, author string, status string) *gitlab.MergeRequest {
+	now := time.Now()
+	mr := &gitlab.MergeRequest{
+		ID:                  id,
+		IID:                 iid,
+		ProjectID:           project.ID,
+		Title:               title,
+		State:               "opened",
+		DetailedMergeStatus: status,
+		Author:              &gitlab.BasicUser{Username: author},
+		SHA:                 headSHA(id, 0),
+		SourceBranch:        "renovate/" + title,
+		TargetBranch:        project.DefaultBranch,
+		CreatedAt:           &now,
+		UpdatedAt:           &now,
+		WebURL:              project.WebURL + "/-/merge_requests/" + itoa(iid),
+	}
+	mr.DiffRefs.BaseSha = "base-" + itoa(id)
+	return mr
+}
+
+// Label creates a label fixture
+func Label(name string, color string) *gitlab.Label {
+	return &gitlab.Label{Name: name, Color: color, TextColor: "#FFFFFF"}
+}
+
+// Member creates an active project member fixture with developer access
+func Member(id int, username string) *gitlab.ProjectMember {
+	return &gitlab.ProjectMember{ID: id, Username: username, Name: username, State: "active", AccessLevel: gitlab.DeveloperPermissions}
+}
+
+// Bridge creates a trigger job fixture whose downstream pipeline in the project has the given status
+func Bridge(name string, project *gitlab.Project, pipelineID int, status string) *gitlab.Bridge {
+	return &gitlab.Bridge{
+		Name:               name,
+		Status:             status,
+		DownstreamPipeline: &gitlab.PipelineInfo{ID: pipelineID, ProjectID: project.ID, Status: status},
+	}
+}
+
+// Job creates a job fixture, ids are unique across pipelines
+func Job(id int, name string, status string) *gitlab.Job {
+	return &gitlab.Job{ID: id, Name: name, Status: status, Stage: "test"}
+}
+
+// Diff creates a single file diff fixture
+func Diff(path string, diff string) []*gitlab.MergeRequestDiff {
+	return []*gitlab.MergeRequestDiff{{OldPath: path, NewPath: path, Diff: diff}}
+}
+
+// RenovateBump is a scenario with one renovate merge request that needs approval, waits for its pipeline and then
+// becomes mergeable
+func RenovateBump() Scenario {
+	p := Project(1, "service")
+	mr := MergeRequest(101, p, 1, "Update module golang.org/x/net to v0.36.0", "renovate-bot", "not_approved")
+	return Scenario{
+		Projects:      []*gitlab.Project{p},
+		MergeRequests: []*gitlab.MergeRequest{mr},
+		Diffs: map[int][]*gitlab.MergeRequestDiff{
+			mr.ID: Diff("go.mod", "@@ -1 +1 @@\n-golang.org/x/net v0.23.0\n+golang.org/x/net v0.36.0\n"),
+		},
+		Statuses: map[int][]string{
+			mr.ID: {"not_approved", "ci_still_running", "mergeable"},
+		},
+	}
+}
+
+// Flapping is a scenario with one merge request starting in each detailed merge status, every read moves it to a
+// random status for the given number of reads. The same seed gives the same sequences, so a failing run can be
+// replayed.
+func Flapping(seed int64, reads int) Scenario {
+	rnd := rand.New(rand.NewSource(seed))
+	p := Project(1, "service")
+	scenario := Scenario{
+		Projects: []*gitlab.Project{p},
+		Diffs:    make(map[int][]*gitlab.MergeRequestDiff),
+		Statuses: make(map[int][]string),
+	}
+	for i, status := range DetailedMergeStatuses {
+		mr := MergeRequest(300+i, p, 10+i, "Update dependency "+status, "renovate-bot", status)
+		scenario.MergeRequests = append(scenario.MergeRequests, mr)
+		scenario.Diffs[mr.ID] = Diff("go.mod", "@@ -1 +1 @@\n-example.com/"+status+" v1.0.0\n+example.com/"+status+" v1.1.0\n")
+		seq := make([]string, reads)
+		for j := range seq {
+			seq[j] = DetailedMergeStatuses[rnd.Intn(len(DetailedMergeStatuses))]
+		}
+		scenario.Statuses[mr.ID] = seq
+	}
+	return scenario
+}
+
+// Mixed is a scenario with merge requests in terminal and blocking states across two projects
+func Mixed() Scenario {
+	p1, p2 := Project(1, "service"), Project(2, "frontend")
+	draft := MergeRequest(201, p1, 2, "Draft: Update dependency react", "renovate-bot", "draft_status")
+	conflict := MergeRequest(202, p2, 3, "Update dependency lodash", "renovate-bot", "conflict")
+	mergeable := MergeRequest(203, p2, 4, "Update dependency vite", "renovate-bot", "mergeable")
+	draft.Labels = gitlab.Labels{"dependencies"}
+	conflict.Labels = gitlab.Labels{"dependencies", "frontend"}
+	mergeable.Labels = gitlab.Labels{"dependencies", "frontend"}
+	return Scenario{
+		Projects:      []*gitlab.Project{p1, p2},
+		MergeRequests: []*gitlab.MergeRequest{draft, conflict, mergeable},
+		Diffs: map[int][]*gitlab.MergeRequestDiff{
+			draft.ID:     Diff("package.json", "@@ -1 +1 @@\n-\"react\": \"18.2.0\"\n+\"react\": \"18.3.0\"\n"),
+			conflict.ID:  Diff("package.json", "@@ -1 +1 @@\n-\"lodash\": \"4.17.20\"\n+\"lodash\": \"4.17.21\"\n"),
+			mergeable.ID: Diff("package.json", "@@ -1 +1 @@\n-\"vite\": \"5.0.0\"\n+\"vite\": \"5.1.0\"\n"),
+		},
+		Labels: map[int][]*gitlab.Label{
+			p1.ID: {Label("dependencies", "#428BCA")},
+			p2.ID: {Label("dependencies", "#428BCA"), Label("frontend", "#6699CC")},
+		},
+		Members: map[int][]*gitlab.ProjectMember{
+			p1.ID: {Member(11, "alice")},
+			p2.ID: {Member(11, "alice"), Member(12, "bob")},
+		},
+		TargetDiffs: map[int][]*gitlab.Diff{
+			p2.ID: {{OldPath: "package.json", NewPath: "package.json", Diff: "@@ -1 +1 @@\n-\"lodash\": \"4.17.20\"\n+\"lodash\": \"4.17.19\"\n"}},
+		},
+	}
+}
+
+// headSHA is the fake head commit of a merge request after the given number of pushes
+func headSHA(id int, pushes int) string {
+	return "head-" + itoa(id) + "-" + itoa(pushes)
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
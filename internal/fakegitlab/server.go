@@ -0,0 +1,1005 @@
+// Package fakegitlab is an in-process fake of the gitlab api endpoints used by gitlab-util. It allows to drive the
+// merge processing end-to-end against scenario fixtures without a real gitlab instance.
+package fakegitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake gitlab serving projects, merge requests, diffs, approvals and merges from memory
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	projects      []*gitlab.Project
+	mergeRequests []*gitlab.MergeRequest
+	diffs         map[int][]*gitlab.MergeRequestDiff
+	labels        map[int][]*gitlab.Label
+	members       map[int][]*gitlab.ProjectMember
+	bridges       map[int][]*gitlab.Bridge
+	statuses      map[int][]string
+	files         map[int]map[string]string
+	groupMembers  map[string][]*gitlab.GroupMember
+	rules         map[int][]*gitlab.MergeRequestApprovalRule
+	notes         map[int][]*gitlab.Note
+	targetDiffs   map[int][]*gitlab.Diff
+	jobs          map[int][]*gitlab.Job
+	commits       []gitlab.CreateCommitOptions
+	approved      map[int]string
+	pushes        map[int]int
+	failures      map[string]int
+	failureCodes  map[string]int
+	calls         []string
+	// approvalPassword is required to approve if set
+	approvalPassword string
+	// tokenExpiresAt is the expiry of the personal access token, revoked are the tokens replaced by a rotation
+	tokenExpiresAt time.Time
+	revoked        map[string]bool
+	rotations      int
+}
+
+// New starts a fake gitlab loaded with the scenario
+func New(scenario Scenario) *Server {
+	s := &Server{
+		diffs:        make(map[int][]*gitlab.MergeRequestDiff),
+		labels:       make(map[int][]*gitlab.Label),
+		members:      make(map[int][]*gitlab.ProjectMember),
+		bridges:      make(map[int][]*gitlab.Bridge),
+		statuses:     make(map[int][]string),
+		files:        make(map[int]map[string]string),
+		groupMembers: make(map[string][]*gitlab.GroupMember),
+		rules:        make(map[int][]*gitlab.MergeRequestApprovalRule),
+		notes:        make(map[int][]*gitlab.Note),
+		targetDiffs:  make(map[int][]*gitlab.Diff),
+		jobs:         make(map[int][]*gitlab.Job),
+		approved:     make(map[int]string),
+		pushes:       make(map[int]int),
+		failures:     make(map[string]int),
+		failureCodes: make(map[string]int),
+		revoked:      make(map[string]bool),
+	}
+	s.Load(scenario)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/version", s.getVersion)
+	mux.HandleFunc("GET /api/v4/personal_access_tokens/self", s.getToken)
+	mux.HandleFunc("POST /api/v4/personal_access_tokens/self/rotate", s.rotateToken)
+	mux.HandleFunc("GET /api/v4/users", s.listUsers)
+	mux.HandleFunc("GET /api/v4/groups/{gid}/members/all", s.listGroupMembers)
+	mux.HandleFunc("GET /api/v4/projects", s.listProjects)
+	mux.HandleFunc("GET /api/v4/projects/{pid}", s.getProject)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/labels", s.listLabels)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/labels/{label}", s.getLabel)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/labels", s.createLabel)
+	mux.HandleFunc("PUT /api/v4/projects/{pid}/labels", s.updateLabel)
+	mux.HandleFunc("DELETE /api/v4/projects/{pid}/labels/{label}", s.deleteLabel)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/members/all", s.listMembers)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/pipelines/{id}/bridges", s.listBridges)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/pipelines/{id}/jobs", s.listJobs)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/pipelines/{id}/retry", s.retryPipeline)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/repository/files/{file}/raw", s.getRawFile)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/repository/commits", s.createCommit)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/repository/compare", s.compare)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/merge_requests", s.createMergeRequest)
+	mux.HandleFunc("GET /api/v4/merge_requests", s.listMergeRequests)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests", s.listMergeRequests)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests/{iid}", s.getMergeRequest)
+	mux.HandleFunc("PUT /api/v4/projects/{pid}/merge_requests/{iid}", s.updateMergeRequest)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests/{iid}/diffs", s.listDiffs)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests/{iid}/approvals", s.getApprovals)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests/{iid}/approval_state", s.getApprovalState)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/merge_requests/{iid}/approve", s.approve)
+	mux.HandleFunc("GET /api/v4/projects/{pid}/merge_requests/{iid}/notes", s.listNotes)
+	mux.HandleFunc("POST /api/v4/projects/{pid}/merge_requests/{iid}/notes", s.createNote)
+	mux.HandleFunc("PUT /api/v4/projects/{pid}/merge_requests/{iid}/merge", s.merge)
+	mux.HandleFunc("PUT /api/v4/projects/{pid}/merge_requests/{iid}/rebase", s.rebase)
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// Client returns a go-gitlab client talking to the fake
+func (s *Server) Client() (*gitlab.Client, error) {
+	return gitlab.NewClient("fake-token", gitlab.WithBaseURL(s.URL+"/api/v4"))
+}
+
+// Load adds the projects, merge requests, diffs, labels, members, bridges and status sequences of the scenario
+func (s *Server) Load(scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = append(s.projects, scenario.Projects...)
+	s.mergeRequests = append(s.mergeRequests, scenario.MergeRequests...)
+	for id, d := range scenario.Diffs {
+		s.diffs[id] = d
+	}
+	for id, l := range scenario.Labels {
+		s.labels[id] = l
+	}
+	for id, members := range scenario.Members {
+		s.members[id] = members
+	}
+	for id, b := range scenario.Bridges {
+		s.bridges[id] = b
+	}
+	for id, seq := range scenario.Statuses {
+		s.statuses[id] = seq
+	}
+	for id, files := range scenario.Files {
+		s.files[id] = files
+	}
+	for path, members := range scenario.GroupMembers {
+		s.groupMembers[path] = members
+	}
+	for id, rules := range scenario.ApprovalRules {
+		s.rules[id] = rules
+	}
+	for id, diffs := range scenario.TargetDiffs {
+		s.targetDiffs[id] = diffs
+	}
+	for id, jobs := range scenario.Jobs {
+		s.jobs[id] = jobs
+	}
+}
+
+// Fail makes the next n calls of the endpoint (e.g. "POST approve") answer with the http status code 500
+func (s *Server) Fail(endpoint string, n int) {
+	s.FailWith(endpoint, n, http.StatusInternalServerError)
+}
+
+// FailWith makes the next n calls of the endpoint answer with the http status code, e.g. 403 for missing permissions
+func (s *Server) FailWith(endpoint string, n int, code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[endpoint] = n
+	s.failureCodes[endpoint] = code
+}
+
+// RequireApprovalPassword makes approvals without the password fail with 401, like instances that require
+// re-authentication to approve
+func (s *Server) RequireApprovalPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approvalPassword = password
+}
+
+// SetDiff replaces the diff of a merge request and moves its head to a new commit, e.g. to simulate a new push
+func (s *Server) SetDiff(id int, diff []*gitlab.MergeRequestDiff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.diffs[id] = diff
+	s.pushes[id]++
+	for _, mr := range s.mergeRequests {
+		if mr.ID == id {
+			mr.SHA = headSHA(id, s.pushes[id])
+		}
+	}
+}
+
+// MergeRequest returns a copy of the current state of the merge request
+func (s *Server) MergeRequest(id int) gitlab.MergeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, mr := range s.mergeRequests {
+		if mr.ID == id {
+			return *mr
+		}
+	}
+	return gitlab.MergeRequest{}
+}
+
+// FinishPipeline finishes the pending jobs of the pipeline with the status, the merge requests of the pipeline become
+// mergeable if it succeeded and otherwise need a passing pipeline
+func (s *Server) FinishPipeline(id int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs[id] {
+		if j.Status == "pending" {
+			j.Status = status
+		}
+	}
+	mergeStatus := "ci_must_pass"
+	if status == "success" {
+		mergeStatus = "mergeable"
+	}
+	s.setPipelineStatus(id, status, mergeStatus)
+}
+
+// SetTokenExpiry sets when the personal access token expires, tokens don't expire by default
+func (s *Server) SetTokenExpiry(expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenExpiresAt = expiresAt
+}
+
+// Notes returns the comments of the merge request
+func (s *Server) Notes(id int) []*gitlab.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*gitlab.Note(nil), s.notes[id]...)
+}
+
+// Commits returns the commits created through the api
+func (s *Server) Commits() []gitlab.CreateCommitOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]gitlab.CreateCommitOptions(nil), s.commits...)
+}
+
+// ApprovedAt returns the head commit the merge request was approved at, empty if it wasn't approved
+func (s *Server) ApprovedAt(id int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.approved[id]
+}
+
+// Calls returns the requests received so far as "METHOD path"
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+		revoked := s.revoked[r.Header.Get("PRIVATE-TOKEN")]
+		s.mu.Unlock()
+		if revoked {
+			http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) fail(w http.ResponseWriter, endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures[endpoint] <= 0 {
+		return false
+	}
+	s.failures[endpoint]--
+	code := s.failureCodes[endpoint]
+	http.Error(w, fmt.Sprintf(`{"message":"%d %s"}`, code, http.StatusText(code)), code)
+	return true
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	search := r.URL.Query().Get("search")
+	var projects []*gitlab.Project
+	for _, p := range s.projects {
+		if search == "" || p.Name == search || p.PathWithNamespace == search {
+			projects = append(projects, p)
+		}
+	}
+	writeJSON(w, projects)
+}
+
+func (s *Server) getToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, s.token())
+}
+
+// rotateToken revokes the token of the request and returns a new one, any token is accepted until it is revoked
+func (s *Server) rotateToken(w http.ResponseWriter, r *http.Request) {
+	var opt gitlab.RotatePersonalAccessTokenOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		http.Error(w, `{"message":"400 Bad request"}`, http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[r.Header.Get("PRIVATE-TOKEN")] = true
+	s.rotations++
+	s.tokenExpiresAt = time.Time{}
+	if opt.ExpiresAt != nil {
+		s.tokenExpiresAt = time.Time(*opt.ExpiresAt)
+	}
+	token := s.token()
+	token.Token = "rotated-" + itoa(s.rotations)
+	writeJSON(w, token)
+}
+
+// token is the personal access token the fake is accessed with
+func (s *Server) token() *gitlab.PersonalAccessToken {
+	token := &gitlab.PersonalAccessToken{ID: 1 + s.rotations, Name: "gitlab-util", Scopes: []string{"api"}, Active: true}
+	if !s.tokenExpiresAt.IsZero() {
+		token.ExpiresAt = gitlab.Ptr(gitlab.ISOTime(s.tokenExpiresAt))
+	}
+	return token
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, "version") {
+		return
+	}
+	writeJSON(w, gitlab.Version{Version: "17.0.0-fake", Revision: "fake"})
+}
+
+// listUsers lists the project members and the authors of the merge requests, filtered by username ignoring the case
+// or by search in usernames and names
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	seen := make(map[string]bool)
+	var users []*gitlab.User
+	add := func(id int, name string, username string) {
+		if username == "" || seen[username] {
+			return
+		}
+		seen[username] = true
+		if want := q.Get("username"); want != "" && !strings.EqualFold(want, username) {
+			return
+		}
+		search := strings.ToLower(q.Get("search"))
+		if search != "" && !strings.Contains(strings.ToLower(username), search) && !strings.Contains(strings.ToLower(name), search) {
+			return
+		}
+		users = append(users, &gitlab.User{ID: id, Username: username, Name: name, State: "active"})
+	}
+	for _, p := range s.projects {
+		for _, member := range s.members[p.ID] {
+			add(member.ID, member.Name, member.Username)
+		}
+	}
+	for _, mr := range s.mergeRequests {
+		if mr.Author != nil {
+			add(mr.Author.ID, mr.Author.Name, mr.Author.Username)
+		}
+	}
+	if perPage, err := strconv.Atoi(q.Get("per_page")); err == nil && perPage < len(users) {
+		users = users[:perPage]
+	}
+	writeJSON(w, users)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (s *Server) listLabels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, s.labels[p.ID])
+}
+
+func (s *Server) getLabel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	label, _ := s.label(r.PathValue("pid"), r.PathValue("label"))
+	if label == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, label)
+}
+
+// createLabel fails with a conflict if the label exists like gitlab does
+func (s *Server) createLabel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	var opt gitlab.CreateLabelOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil || opt.Name == nil {
+		http.Error(w, `{"message":"400 Bad Request"}`, http.StatusBadRequest)
+		return
+	}
+	if label, _ := s.label(r.PathValue("pid"), *opt.Name); label != nil {
+		http.Error(w, `{"message":"Label already exists"}`, http.StatusConflict)
+		return
+	}
+	label := &gitlab.Label{ID: len(s.labels[p.ID]) + 1, Name: *opt.Name}
+	if opt.Color != nil {
+		label.Color = *opt.Color
+	}
+	if opt.Description != nil {
+		label.Description = *opt.Description
+	}
+	s.labels[p.ID] = append(s.labels[p.ID], label)
+	writeJSON(w, label)
+}
+
+func (s *Server) updateLabel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var opt gitlab.UpdateLabelOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil || opt.Name == nil {
+		http.Error(w, `{"message":"400 Bad Request"}`, http.StatusBadRequest)
+		return
+	}
+	label, _ := s.label(r.PathValue("pid"), *opt.Name)
+	if label == nil {
+		notFound(w)
+		return
+	}
+	if opt.Description != nil {
+		label.Description = *opt.Description
+	}
+	writeJSON(w, label)
+}
+
+func (s *Server) deleteLabel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	label, p := s.label(r.PathValue("pid"), r.PathValue("label"))
+	if label == nil {
+		notFound(w)
+		return
+	}
+	s.labels[p.ID] = slices.DeleteFunc(s.labels[p.ID], func(l *gitlab.Label) bool {
+		return l == label
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listBridges(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, s.bridges[id])
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		notFound(w)
+		return
+	}
+	jobs := []*gitlab.Job{}
+	for _, j := range s.jobs[id] {
+		if r.URL.Query().Get("include_retried") == "true" || !slices.ContainsFunc(s.jobs[id], func(later *gitlab.Job) bool {
+			return later.Name == j.Name && later.ID > j.ID
+		}) {
+			jobs = append(jobs, j)
+		}
+	}
+	writeJSON(w, jobs)
+}
+
+// retryPipeline adds a pending attempt of each failed job and runs the pipeline again
+func (s *Server) retryPipeline(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, "POST retry") {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || s.jobs[id] == nil {
+		notFound(w)
+		return
+	}
+	nextID := 0
+	for _, jobs := range s.jobs {
+		for _, j := range jobs {
+			nextID = max(nextID, j.ID)
+		}
+	}
+	var retried []*gitlab.Job
+	for _, j := range s.jobs[id] {
+		latest := !slices.ContainsFunc(s.jobs[id], func(later *gitlab.Job) bool { return later.Name == j.Name && later.ID > j.ID })
+		if latest && j.Status == "failed" {
+			nextID++
+			retried = append(retried, Job(nextID, j.Name, "pending"))
+		}
+	}
+	s.jobs[id] = append(s.jobs[id], retried...)
+	s.setPipelineStatus(id, "running", "ci_still_running")
+	writeJSON(w, &gitlab.Pipeline{ID: id, Status: "running"})
+}
+
+// setPipelineStatus updates the head pipeline and the detailed merge status of the merge requests of the pipeline
+func (s *Server) setPipelineStatus(id int, status string, mergeStatus string) {
+	for _, mr := range s.mergeRequests {
+		if mr.HeadPipeline != nil && mr.HeadPipeline.ID == id {
+			mr.HeadPipeline.Status = status
+			mr.DetailedMergeStatus = mergeStatus
+		}
+	}
+}
+
+func (s *Server) listGroupMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members, ok := s.groupMembers[r.PathValue("gid")]
+	if !ok {
+		http.Error(w, `{"message":"404 Group Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, members)
+}
+
+func (s *Server) listMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, s.members[p.ID])
+}
+
+func (s *Server) listMergeRequests(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	var project *gitlab.Project
+	if pid := r.PathValue("pid"); pid != "" {
+		project = s.project(pid)
+		if project == nil {
+			notFound(w)
+			return
+		}
+	}
+	var mrs []*gitlab.MergeRequest
+	for _, mr := range s.mergeRequests {
+		if project != nil && mr.ProjectID != project.ID {
+			continue
+		}
+		if !matches(q, "state", mr.State) || !matches(q, "author_username", username(mr.Author)) {
+			continue
+		}
+		if reviewer := q.Get("reviewer_username"); reviewer != "" && !hasReviewer(mr, reviewer) {
+			continue
+		}
+		if reviewerID := q.Get("reviewer_id"); reviewerID != "" && !hasReviewerID(mr, reviewerID) {
+			continue
+		}
+		if assigneeID := q.Get("assignee_id"); assigneeID != "" && !hasAssigneeID(mr, assigneeID) {
+			continue
+		}
+		if milestone := q.Get("milestone"); milestone != "" && !hasMilestone(mr, milestone) {
+			continue
+		}
+		mrs = append(mrs, mr)
+	}
+	writeJSON(w, mrs)
+}
+
+func (s *Server) getMergeRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	// every read advances the merge request through its scripted status sequence
+	if seq := s.statuses[mr.ID]; len(seq) > 0 {
+		mr.DetailedMergeStatus = seq[0]
+		s.statuses[mr.ID] = seq[1:]
+	}
+	writeJSON(w, mr)
+}
+
+// updateMergeRequest supports closing and changing the reviewers to members of the project
+func (s *Server) updateMergeRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	var opt gitlab.UpdateMergeRequestOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		http.Error(w, `{"message":"400 Bad Request"}`, http.StatusBadRequest)
+		return
+	}
+	if opt.StateEvent != nil && *opt.StateEvent == "close" {
+		mr.State = "closed"
+		mr.DetailedMergeStatus = "not_open"
+	}
+	if opt.Title != nil {
+		mr.Title = *opt.Title
+	}
+	if opt.Description != nil {
+		mr.Description = *opt.Description
+	}
+	if opt.ReviewerIDs != nil {
+		mr.Reviewers = nil
+		for _, id := range *opt.ReviewerIDs {
+			for _, member := range s.members[mr.ProjectID] {
+				if member.ID == id {
+					mr.Reviewers = append(mr.Reviewers, &gitlab.BasicUser{ID: member.ID, Username: member.Username, Name: member.Name})
+				}
+			}
+		}
+	}
+	writeJSON(w, mr)
+}
+
+func (s *Server) listDiffs(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, "GET diffs") {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, s.diffs[mr.ID])
+}
+
+func (s *Server) approve(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, "POST approve") {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	var body struct {
+		SHA              string `json:"sha"`
+		ApprovalPassword string `json:"approval_password"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	if s.approvalPassword != "" && body.ApprovalPassword != s.approvalPassword {
+		http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if body.SHA != "" && body.SHA != mr.SHA {
+		http.Error(w, `{"message":"SHA does not match HEAD of source branch"}`, http.StatusConflict)
+		return
+	}
+	if mr.DetailedMergeStatus == "not_approved" {
+		mr.DetailedMergeStatus = "mergeable"
+	}
+	s.approved[mr.ID] = mr.SHA
+	writeJSON(w, s.approvals(mr))
+}
+
+func (s *Server) getApprovals(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, s.approvals(mr))
+}
+
+// approvals reports a single required approval, given by the token user once approved
+func (s *Server) approvals(mr *gitlab.MergeRequest) *gitlab.MergeRequestApprovals {
+	a := &gitlab.MergeRequestApprovals{ID: mr.ID, IID: mr.IID, ProjectID: mr.ProjectID, ApprovalsRequired: 1, ApprovalsLeft: 1}
+	if s.approved[mr.ID] != "" {
+		a.Approved = true
+		a.ApprovalsLeft = 0
+		a.ApprovedBy = []*gitlab.MergeRequestApproverUser{{User: &gitlab.BasicUser{Username: "fake-user"}}}
+	}
+	return a
+}
+
+// getApprovalState answers with the approval rules of the merge request, once approved they are all approved by the
+// token user
+func (s *Server) getApprovalState(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	state := &gitlab.MergeRequestApprovalState{}
+	for _, rule := range s.rules[mr.ID] {
+		rule := *rule
+		if s.approved[mr.ID] != "" {
+			rule.Approved = true
+			rule.ApprovedBy = append(rule.ApprovedBy, &gitlab.BasicUser{Username: "fake-user"})
+		}
+		state.Rules = append(state.Rules, &rule)
+	}
+	writeJSON(w, state)
+}
+
+func (s *Server) listNotes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, append([]*gitlab.Note{}, s.notes[mr.ID]...))
+}
+
+func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	var opt gitlab.CreateMergeRequestNoteOptions
+	_ = json.NewDecoder(r.Body).Decode(&opt)
+	if opt.Body == nil {
+		http.Error(w, `{"message":"400 Bad request - body is missing"}`, http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	note := &gitlab.Note{ID: len(s.notes[mr.ID]) + 1, Body: *opt.Body, CreatedAt: &now, NoteableID: mr.ID, NoteableIID: mr.IID, NoteableType: "MergeRequest"}
+	note.Author.Username = "fake-user"
+	s.notes[mr.ID] = append(s.notes[mr.ID], note)
+	writeJSON(w, note)
+}
+
+func (s *Server) merge(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, "PUT merge") {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	if mr.DetailedMergeStatus != "mergeable" {
+		http.Error(w, `{"message":"405 Method Not Allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	var opt gitlab.AcceptMergeRequestOptions
+	_ = json.NewDecoder(r.Body).Decode(&opt)
+	if opt.SHA != nil && *opt.SHA != mr.SHA {
+		http.Error(w, `{"message":"SHA does not match HEAD of source branch"}`, http.StatusConflict)
+		return
+	}
+	if opt.Squash != nil {
+		mr.Squash = *opt.Squash
+	}
+	now := time.Now()
+	mr.State = "merged"
+	mr.DetailedMergeStatus = "not_open"
+	mr.MergedAt = &now
+	writeJSON(w, mr)
+}
+
+// rebase finishes right away, a merge request waiting for a rebase becomes mergeable
+func (s *Server) rebase(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mr := s.mergeRequest(r)
+	if mr == nil {
+		notFound(w)
+		return
+	}
+	if mr.DetailedMergeStatus == "need_rebase" {
+		mr.DetailedMergeStatus = "mergeable"
+	}
+	writeJSONStatus(w, http.StatusAccepted, map[string]bool{"rebase_in_progress": true})
+}
+
+func (s *Server) getRawFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	content, ok := s.files[p.ID][r.PathValue("file")]
+	if !ok {
+		notFound(w)
+		return
+	}
+	_, _ = w.Write([]byte(content))
+}
+
+// compare answers with the changes on the default branch since any merge base, one commit per changed file
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	c := &gitlab.Compare{Commits: []*gitlab.Commit{}, Diffs: []*gitlab.Diff{}}
+	for i, d := range s.targetDiffs[p.ID] {
+		c.Commits = append(c.Commits, &gitlab.Commit{ID: "target-" + itoa(p.ID) + "-" + itoa(i), Title: "Change " + d.NewPath})
+		c.Diffs = append(c.Diffs, d)
+	}
+	writeJSON(w, c)
+}
+
+// createCommit records the commit, the files of the default branch stay unchanged
+func (s *Server) createCommit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	var opt gitlab.CreateCommitOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		http.Error(w, `{"message":"400 Bad Request"}`, http.StatusBadRequest)
+		return
+	}
+	s.commits = append(s.commits, opt)
+	writeJSONStatus(w, http.StatusCreated, &gitlab.Commit{ID: fmt.Sprintf("commit-%d", len(s.commits)), Title: *opt.CommitMessage})
+}
+
+func (s *Server) createMergeRequest(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		notFound(w)
+		return
+	}
+	var opt gitlab.CreateMergeRequestOptions
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		http.Error(w, `{"message":"400 Bad Request"}`, http.StatusBadRequest)
+		return
+	}
+	iid := 1
+	for _, mr := range s.mergeRequests {
+		if mr.ProjectID == p.ID && mr.IID >= iid {
+			iid = mr.IID + 1
+		}
+	}
+	mr := MergeRequest(1000+len(s.mergeRequests), p, iid, *opt.Title, "gitlab-util", "checking")
+	mr.SourceBranch = *opt.SourceBranch
+	mr.TargetBranch = *opt.TargetBranch
+	if opt.Description != nil {
+		mr.Description = *opt.Description
+	}
+	s.mergeRequests = append(s.mergeRequests, mr)
+	writeJSONStatus(w, http.StatusCreated, mr)
+}
+
+func (s *Server) project(pid string) *gitlab.Project {
+	for _, p := range s.projects {
+		if strconv.Itoa(p.ID) == pid || p.PathWithNamespace == pid {
+			return p
+		}
+	}
+	return nil
+}
+
+// label finds the label of the project by name or id
+func (s *Server) label(pid string, name string) (*gitlab.Label, *gitlab.Project) {
+	p := s.project(pid)
+	if p == nil {
+		return nil, nil
+	}
+	for _, l := range s.labels[p.ID] {
+		if l.Name == name || strconv.Itoa(l.ID) == name {
+			return l, p
+		}
+	}
+	return nil, p
+}
+
+func (s *Server) mergeRequest(r *http.Request) *gitlab.MergeRequest {
+	p := s.project(r.PathValue("pid"))
+	if p == nil {
+		return nil
+	}
+	iid, err := strconv.Atoi(r.PathValue("iid"))
+	if err != nil {
+		return nil
+	}
+	for _, mr := range s.mergeRequests {
+		if mr.ProjectID == p.ID && mr.IID == iid {
+			return mr
+		}
+	}
+	return nil
+}
+
+func matches(q url.Values, key string, value string) bool {
+	want := q.Get(key)
+	return want == "" || want == "all" || want == value
+}
+
+func username(u *gitlab.BasicUser) string {
+	if u == nil {
+		return ""
+	}
+	return u.Username
+}
+
+func hasReviewer(mr *gitlab.MergeRequest, reviewer string) bool {
+	for _, r := range mr.Reviewers {
+		if r.Username == reviewer {
+			return true
+		}
+	}
+	return false
+}
+
+func hasReviewerID(mr *gitlab.MergeRequest, id string) bool {
+	for _, r := range mr.Reviewers {
+		if itoa(r.ID) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAssigneeID matches the assignee id filter, None and Any select unassigned and assigned merge requests
+func hasAssigneeID(mr *gitlab.MergeRequest, id string) bool {
+	switch id {
+	case "None":
+		return len(mr.Assignees) == 0
+	case "Any":
+		return len(mr.Assignees) > 0
+	}
+	for _, a := range mr.Assignees {
+		if itoa(a.ID) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMilestone matches the milestone filter, None and Any select merge requests without and with a milestone
+func hasMilestone(mr *gitlab.MergeRequest, milestone string) bool {
+	switch milestone {
+	case "None":
+		return mr.Milestone == nil
+	case "Any":
+		return mr.Milestone != nil
+	}
+	return mr.Milestone != nil && mr.Milestone.Title == milestone
+}
+
+func notFound(w http.ResponseWriter) {
+	http.Error(w, `{"message":"404 Not found"}`, http.StatusNotFound)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	writeJSONStatus(w, http.StatusOK, v)
+}
+
+// writeJSONStatus answers with the status code, the content type has to be set before it
+func writeJSONStatus(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
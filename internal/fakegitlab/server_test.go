@@ -0,0 +1,41 @@
+package fakegitlab_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseContentType(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodPut, "/api/v4/projects/1/merge_requests/1/rebase", "", http.StatusAccepted},
+		{http.MethodPost, "/api/v4/projects/1/repository/commits", `{"branch":"renovate/ignore","commit_message":"Ignore","actions":[]}`, http.StatusCreated},
+		{http.MethodPost, "/api/v4/projects/1/merge_requests", `{"title":"Ignore","source_branch":"renovate/ignore","target_branch":"main"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			s := fakegitlab.New(fakegitlab.RenovateBump())
+			defer s.Close()
+			req, err := http.NewRequest(tt.method, s.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("PRIVATE-TOKEN", "fake-token")
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.status || resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("answered %d with content type %q, want %d with application/json", resp.StatusCode, resp.Header.Get("Content-Type"), tt.status)
+			}
+		})
+	}
+}
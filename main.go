@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gitu/gitlab-util/pkg/ggl"
 	"github.com/gitu/gitlab-util/pkg/glui"
 	"github.com/urfave/cli/v2"
 	"log"
 	"log/slog"
+	"maps"
 	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var (
@@ -14,6 +22,9 @@ var (
 	commit  = "none"
 )
 
+// managerOptions configure every MergeRequestManager opened by the commands, they are set up from the global flags
+var managerOptions []ggl.Option
+
 func main() {
 
 	cli.VersionPrinter = func(cCtx *cli.Context) {
@@ -36,9 +47,134 @@ func main() {
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
 			Name:    "gitlab-url",
-			Usage:   "gitlab url to connect to (e.g. https://gitlab.yourdomain.com/api/v4)  (can be set via GITLAB_URL env var if not used last logged in url is used)",
+			Usage:   "gitlab url to connect to (e.g. https://gitlab.yourdomain.com/api/v4)  (can be set via GITLAB_URL env var, in ci jobs CI_API_V4_URL is used, otherwise the instance with a stored token, asked for if there are several). GITLAB_TOKEN or CI_JOB_TOKEN are used instead of the stored token if set",
 			EnvVars: []string{"GITLAB_URL"},
 		},
+		&cli.StringFlag{
+			Name:    "db-path",
+			Usage:   "directory of the local cache database (default is one database per gitlab host in /data if it is writable, otherwise in the temp directory)",
+			EnvVars: []string{"GITLAB_UTIL_DB_PATH"},
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q"},
+			Usage:   "only log warnings and errors",
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Aliases: []string{"v"},
+			Usage:   "verbose logging (-v for debug, -vv to also include the source location)",
+		},
+		&cli.BoolFlag{
+			Name:    "log-json",
+			Usage:   "write logs as json (e.g. for journald or ELK)",
+			EnvVars: []string{"GITLAB_UTIL_LOG_JSON"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-color",
+			Usage:   "render the ui and diffs without colors, also set by the NO_COLOR env var",
+			EnvVars: []string{"GITLAB_UTIL_NO_COLOR"},
+		},
+		&cli.BoolFlag{
+			Name:    "ascii",
+			Usage:   "draw the ui with ascii characters only instead of box drawing characters, arrows and emojis (e.g. for screen readers)",
+			EnvVars: []string{"GITLAB_UTIL_ASCII"},
+		},
+		&cli.BoolFlag{
+			Name:    "project-membership",
+			Usage:   "only cache projects you are a member of (use --project-membership=false for all visible projects)",
+			Value:   true,
+			EnvVars: []string{"GITLAB_UTIL_PROJECT_MEMBERSHIP"},
+		},
+		&cli.BoolFlag{
+			Name:    "project-archived",
+			Usage:   "also cache archived projects",
+			EnvVars: []string{"GITLAB_UTIL_PROJECT_ARCHIVED"},
+		},
+		&cli.IntFlag{
+			Name:    "project-min-access-level",
+			Usage:   "only cache projects with at least this access level (10 guest, 20 reporter, 30 developer, 40 maintainer, 50 owner)",
+			EnvVars: []string{"GITLAB_UTIL_PROJECT_MIN_ACCESS_LEVEL"},
+		},
+		&cli.BoolFlag{
+			Name:    "project-owned",
+			Usage:   "only cache projects you own",
+			EnvVars: []string{"GITLAB_UTIL_PROJECT_OWNED"},
+		},
+		&cli.BoolFlag{
+			Name:    "require-signed-commits",
+			Usage:   "only auto-approve merge requests whose commits all have a verified gpg signature (or set \"requireSignedCommits\": true in ~/.gitlab-util/config.json)",
+			EnvVars: []string{"GITLAB_UTIL_REQUIRE_SIGNED_COMMITS"},
+		},
+		&cli.IntFlag{
+			Name:    "max-merges-per-hour",
+			Usage:   "merge at most this many merge requests per hour, the others wait for the next slot (0 is unlimited)",
+			EnvVars: []string{"GITLAB_UTIL_MAX_MERGES_PER_HOUR"},
+		},
+		&cli.IntFlag{
+			Name:    "max-merges-per-day",
+			Usage:   "merge at most this many merge requests per day (0 is unlimited)",
+			EnvVars: []string{"GITLAB_UTIL_MAX_MERGES_PER_DAY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "exclude-author",
+			Usage:   "never approve or merge merge requests of this author, they are still shown (can be repeated)",
+			EnvVars: []string{"GITLAB_UTIL_EXCLUDE_AUTHORS"},
+		},
+		&cli.StringFlag{
+			Name:    "source-branch-regex",
+			Usage:   "only consider merge requests whose source branch matches this regular expression (e.g. ^renovate/)",
+			EnvVars: []string{"GITLAB_UTIL_SOURCE_BRANCH_REGEX"},
+		},
+		&cli.StringFlag{
+			Name:    "flaky-jobs-regex",
+			Usage:   "retry a failed pipeline if only jobs whose name matches this regular expression failed (e.g. ^e2e-)",
+			EnvVars: []string{"GITLAB_UTIL_FLAKY_JOBS_REGEX"},
+		},
+		&cli.IntFlag{
+			Name:    "flaky-retries",
+			Usage:   "how often a pipeline with failed flaky jobs is retried before giving up",
+			Value:   2,
+			EnvVars: []string{"GITLAB_UTIL_FLAKY_RETRIES"},
+		},
+		&cli.StringFlag{
+			Name:    "min-age",
+			Usage:   "don't merge merge requests younger than this (e.g. 2h) to give humans a chance to object",
+			EnvVars: []string{"GITLAB_UTIL_MIN_AGE"},
+		},
+		&cli.StringFlag{
+			Name:    "max-age",
+			Usage:   "stop auto-merging merge requests older than this (e.g. 30d) and flag them as stale",
+			EnvVars: []string{"GITLAB_UTIL_MAX_AGE"},
+		},
+		&cli.BoolFlag{
+			Name:    "close-stale",
+			Usage:   "close merge requests older than --max-age instead of only flagging them",
+			EnvVars: []string{"GITLAB_UTIL_CLOSE_STALE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "status-action",
+			Usage:   "handle a detailed merge status with retry or abort (e.g. need_rebase=abort, can be repeated, overrides \"statusActions\" in ~/.gitlab-util/config.json)",
+			EnvVars: []string{"GITLAB_UTIL_STATUS_ACTIONS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "project-permission",
+			Usage:   "restrict a project path to both, approve, merge or none (e.g. group/infra=approve, can be repeated, overrides \"projectPermissions\" in ~/.gitlab-util/config.json)",
+			EnvVars: []string{"GITLAB_UTIL_PROJECT_PERMISSIONS"},
+		},
+		&cli.StringFlag{
+			Name:    "jira-key",
+			Usage:   "jira issue key added to the description of merge requests blocked by a missing jira association (e.g. DEPS-123, or set \"jiraKey\" in ~/.gitlab-util/config.json)",
+			EnvVars: []string{"GITLAB_UTIL_JIRA_KEY"},
+		},
+	}
+	app.UseShortOptionHandling = true
+	app.Before = func(c *cli.Context) error {
+		if err := setupManagerOptions(c); err != nil {
+			return err
+		}
+		setupRendering(c)
+		return setupLogging(c)
 	}
 
 	app.Commands = []*cli.Command{
@@ -60,9 +196,13 @@ func main() {
 					EnvVars:  []string{"GITLAB_URL"},
 					Required: true,
 				},
+				&cli.BoolFlag{
+					Name:  "encrypt",
+					Usage: "store the token encrypted with a passphrase (asked for or read from " + ggl.PassphraseEnv + ")",
+				},
 			},
 			Action: func(c *cli.Context) error {
-				return ggl.Login(c.String("token"), c.String("url"))
+				return ggl.Login(c.String("token"), c.String("url"), c.Bool("encrypt"), managerOptions...)
 			},
 		},
 		{
@@ -77,21 +217,275 @@ func main() {
 					Name:  "reviewer",
 					Usage: "reviewer of the merge requests to auto merge (e.g. your username)",
 				},
+				&cli.StringFlag{
+					Name:  "reviewer-group",
+					Usage: "also auto merge the merge requests with a member of this group (path or id) as reviewer, e.g. a code owner team",
+				},
+				&cli.StringFlag{
+					Name:  "assignee",
+					Usage: "assignee of the merge requests to auto merge (e.g. your username, None or Any)",
+				},
+				&cli.StringFlag{
+					Name:  "milestone",
+					Usage: "only auto merge merge requests of this milestone (title, None or Any)",
+				},
 				&cli.StringFlag{
 					Name:  "log-file",
 					Usage: "log file to write log into - optional",
 				},
+				&cli.BoolFlag{
+					Name:    "yolo",
+					Usage:   "approve, merge or close merge requests without asking for confirmation (or set \"yolo\": true in ~/.gitlab-util/config.json)",
+					EnvVars: []string{"GITLAB_UTIL_YOLO"},
+				},
+				&cli.BoolFlag{
+					Name:  "once",
+					Usage: "process all active merge targets a single time without ui and exit (exit code 0 all merged, 2 some held, 3 errors)",
+				},
+				&cli.IntFlag{
+					Name:    "rotate-token-days",
+					Usage:   "rotate the stored token at the start if it expires within this many days (0 disables)",
+					EnvVars: []string{"GITLAB_UTIL_ROTATE_TOKEN_DAYS"},
+				},
+				&cli.BoolFlag{
+					Name:  "read-only",
+					Usage: "only show the merge requests, diffs and targets, approving, merging and changing them is disabled",
+				},
+				&cli.BoolFlag{
+					Name:  "enable-all-matching",
+					Usage: "enable approve and merge for all mergeable merge requests and ones waiting for their pipeline without reviewing them, then continue with --once or the ui",
+				},
 			},
 			Action: func(c *cli.Context) error {
-				if c.String("author") == "" && c.String("reviewer") == "" {
+				if c.Bool("once") && c.Bool("read-only") {
+					return cli.Exit("--once processes targets and can't be combined with --read-only", 1)
+				}
+				if c.Bool("once") && hasMergeRequestFilter(c) && !c.Bool("enable-all-matching") {
+					return cli.Exit("--once only processes enabled targets, add --enable-all-matching to enable the merge requests of --author, --reviewer, --reviewer-group or --assignee", 1)
+				}
+				if c.Bool("enable-all-matching") && c.Bool("read-only") {
+					return cli.Exit("--enable-all-matching changes targets and can't be combined with --read-only", 1)
+				}
+				if err := rotateExpiringToken(c); err != nil {
+					return err
+				}
+				if c.Bool("enable-all-matching") {
+					if err := enableAllMatching(c); err != nil {
+						return err
+					}
+				}
+				if c.Bool("once") {
+					return autoMergeOnce(c.Context)
+				}
+				if !hasMergeRequestFilter(c) {
 					return cli.ShowCommandHelp(c, "")
 				}
-				return glui.AutoMerge(c.String("author"), c.String("reviewer"), c.String("log-file"))
+				return glui.AutoMerge(c.Context, glui.Options{
+					Author:        c.String("author"),
+					Reviewer:      c.String("reviewer"),
+					ReviewerGroup: c.String("reviewer-group"),
+					Assignee:      c.String("assignee"),
+					Milestone:     c.String("milestone"),
+					LogFile:       c.String("log-file"),
+					Yolo:          c.Bool("yolo"),
+					ReadOnly:      c.Bool("read-only"),
+					Manager:       managerOptions,
+				})
 			},
 		},
+		dashboardCommand(),
+		initCiCommand(),
+		mrCommand(),
+		pipelineCommand(),
+		ciCommand(),
+		searchCommand(),
+		projectCommand(),
+		groupCommand(),
+		targetsCommand(),
+		serveCommand(),
+		healthcheckCommand(),
+		reportCommand(),
+		tokenCommand(),
 	}
 
-	if err := app.Run(os.Args); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.RunContext(ctx, os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// setupRendering disables colors and non-ascii characters in the ui as requested by the global flags
+func setupRendering(c *cli.Context) {
+	// https://no-color.org: any non-empty value disables colors
+	if c.Bool("no-color") || os.Getenv("NO_COLOR") != "" {
+		glui.DisableColors()
+	}
+	if c.Bool("ascii") {
+		glui.UseASCII()
+	}
+}
+
+// setupLogging configures the default logger from the global logging flags
+func setupLogging(c *cli.Context) error {
+	opts := ggl.LogOptions{Level: slog.LevelInfo, JSON: c.Bool("log-json")}
+	switch {
+	case c.Bool("quiet"):
+		opts.Level = slog.LevelWarn
+	case c.Count("verbose") > 1:
+		opts.Level = slog.LevelDebug
+		opts.AddSource = true
+	case c.Count("verbose") == 1:
+		opts.Level = slog.LevelDebug
+	}
+	ggl.ConfigureLogging(opts, os.Stderr)
+	return nil
+}
+
+// setupManagerOptions configures the MergeRequestManager from the global flags
+func setupManagerOptions(c *cli.Context) error {
+	managerOptions = append(managerOptions, ggl.WithGitLabURL(c.String("gitlab-url")), ggl.WithDbPath(c.String("db-path")))
+	if stdinIsTerminal() {
+		managerOptions = append(managerOptions, ggl.WithPassphraseFunc(promptPassphrase), ggl.WithInstancePicker(pickInstance))
+	}
+	managerOptions = append(managerOptions, ggl.WithProjectFilter(ggl.ProjectFilter{
+		Membership:     c.Bool("project-membership"),
+		Archived:       c.Bool("project-archived"),
+		MinAccessLevel: c.Int("project-min-access-level"),
+		Owned:          c.Bool("project-owned"),
+	}))
+	config, err := ggl.LoadConfig()
+	if err != nil {
+		slog.Warn("error loading config", "err", err)
+	}
+	managerOptions = append(managerOptions, ggl.WithRequireSignedCommits(c.Bool("require-signed-commits") || config.RequireSignedCommits))
+	jiraKey := c.String("jira-key")
+	if jiraKey == "" {
+		jiraKey = config.JiraKey
+	}
+	statusActions, err := keyValues(c, "status-action", config.StatusActions)
+	if err != nil {
+		return err
+	}
+	projectPermissions, err := keyValues(c, "project-permission", config.ProjectPermissions)
+	if err != nil {
+		return err
+	}
+	managerOptions = append(managerOptions, ggl.WithJiraKey(jiraKey), ggl.WithStatusActions(statusActions))
+	approvalPassword := os.Getenv("GITLAB_UTIL_APPROVAL_PASSWORD")
+	if approvalPassword == "" {
+		approvalPassword = config.ApprovalPassword
+	}
+	managerOptions = append(managerOptions, ggl.WithApprovalPassword(approvalPassword), ggl.WithProjectPermissions(projectPermissions),
+		ggl.WithMergeLimits(c.Int("max-merges-per-hour"), c.Int("max-merges-per-day")))
+	ages := make([]time.Duration, 2)
+	for i, name := range []string{"min-age", "max-age"} {
+		if c.String(name) == "" {
+			continue
+		}
+		ages[i], err = ggl.ParseSince(c.String(name))
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("invalid --%s: %s", name, err), 1)
+		}
+	}
+	managerOptions = append(managerOptions, ggl.WithAgeLimits(ages[0], ages[1], c.Bool("close-stale")),
+		ggl.WithExcludedAuthors(c.StringSlice("exclude-author")))
+	if pattern := c.String("source-branch-regex"); pattern != "" {
+		sourceBranches, err := regexp.Compile(pattern)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("invalid --source-branch-regex: %s", err), 1)
+		}
+		managerOptions = append(managerOptions, ggl.WithSourceBranchPattern(sourceBranches))
+	}
+	if pattern := c.String("flaky-jobs-regex"); pattern != "" {
+		flakyJobs, err := regexp.Compile(pattern)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("invalid --flaky-jobs-regex: %s", err), 1)
+		}
+		managerOptions = append(managerOptions, ggl.WithFlakyJobs(flakyJobs, c.Int("flaky-retries")))
+	}
+	return nil
+}
+
+// keyValues adds the key=value pairs of the flag to the ones of the configuration file, the flag wins
+func keyValues(c *cli.Context, name string, configured map[string]string) (map[string]string, error) {
+	values := maps.Clone(configured)
+	for _, kv := range c.StringSlice(name) {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, cli.Exit(fmt.Sprintf("invalid --%s %q, expected key=value", name, kv), 1)
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// hasMergeRequestFilter reports whether the author, reviewer, reviewer group or assignee flag selects the merge
+// requests, the milestone only narrows them down
+func hasMergeRequestFilter(c *cli.Context) bool {
+	return c.String("author") != "" || c.String("reviewer") != "" || c.String("reviewer-group") != "" || c.String("assignee") != ""
+}
+
+// filterMergeRequests selects the merge requests fetched by the manager from the author, reviewer, reviewer group,
+// assignee and milestone flags, it fails if one of the users doesn't exist
+func filterMergeRequests(c *cli.Context, mrm *ggl.MergeRequestManager) error {
+	mrm.Author(c.String("author")).Reviewer(c.String("reviewer")).ReviewerGroup(c.String("reviewer-group")).
+		Assignee(c.String("assignee")).Milestone(c.String("milestone"))
+	if err := mrm.CheckFilterUsers(c.Context); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// enableAllMatching enables all green merge requests of the author and reviewer, the manager is closed again so
+// --once or the ui can open the database
+func enableAllMatching(c *cli.Context) error {
+	if !hasMergeRequestFilter(c) {
+		return cli.Exit("--enable-all-matching needs --author, --reviewer, --reviewer-group and/or --assignee", 1)
+	}
+	return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+		if err := filterMergeRequests(c, mrm); err != nil {
+			return err
+		}
+		err := mrm.FetchMergeRequests(c.Context)
+		if err != nil {
+			return err
+		}
+		enabled, err := mrm.EnableAllMatching(c.Context)
+		fmt.Println("enabled", enabled, "merge requests")
+		return err
+	})
+}
+
+// autoMergeOnce runs a single processing pass and maps the outcome to the exit code
+func autoMergeOnce(ctx context.Context) error {
+	mrm, err := ggl.NewDefaultMergeRequestManager(managerOptions...)
+	if err != nil {
+		return cli.Exit(err, 3)
+	}
+	defer mrm.Close()
+
+	access, err := mrm.TokenAccess(ctx)
+	if err != nil {
+		slog.Warn("error reading token scopes", "err", err)
+	}
+	if !access.CanWrite {
+		return cli.Exit("the token has no api scope and can't approve or merge", 3)
+	}
+
+	result, err := mrm.ProcessOnce(ctx)
+	if err != nil {
+		return cli.Exit(err, 3)
+	}
+	slog.Info("auto-merge once finished", "merged", result.Merged, "held", result.Held, "errors", result.Errors)
+	switch {
+	case result.Errors > 0:
+		return cli.Exit("", 3)
+	case result.Held > 0:
+		return cli.Exit("", 2)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/gitu/gitlab-util/pkg/glui"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// mrCommand holds the non interactive merge request subcommands
+func mrCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mr",
+		Usage: "merge request commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list the merge requests of a project",
+				Flags: []cli.Flag{
+					projectFlag(),
+					remoteFlag(),
+					&cli.StringFlag{
+						Name:  "state",
+						Usage: "state of the merge requests (opened, closed, locked, merged or all)",
+						Value: "opened",
+					},
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						project, err := localProject(c, mrm)
+						if err != nil {
+							return err
+						}
+						mrs, err := mrm.ListProjectMergeRequests(c.Context, project, c.String("state"))
+						if err != nil {
+							return err
+						}
+						return printOutput(c, mrs, func(w io.Writer) {
+							for _, mr := range mrs {
+								author := ""
+								if mr.Author != nil {
+									author = mr.Author.Username
+								}
+								_, _ = fmt.Fprintf(w, "!%d\t%s\t%s\t%s\n", mr.IID, mr.DetailedMergeStatus, author, mr.Title)
+							}
+						})
+					})
+				},
+			},
+			{
+				Name:      "approve",
+				Usage:     "approve a merge request",
+				ArgsUsage: "<project!iid|!iid>",
+				Flags: []cli.Flag{
+					remoteFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						mr, err := mrm.ApproveByReference(c.Context, c.Args().First(), c.String("remote"))
+						if err != nil {
+							return err
+						}
+						fmt.Println("approved", mr.WebURL)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "merge",
+				Usage:     "merge a merge request",
+				ArgsUsage: "<project!iid|!iid>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "approve",
+						Usage: "approve the merge request before merging",
+					},
+					remoteFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						if c.Bool("approve") {
+							_, err := mrm.ApproveByReference(c.Context, c.Args().First(), c.String("remote"))
+							if err != nil {
+								return err
+							}
+						}
+						mr, err := mrm.MergeByReference(c.Context, c.Args().First(), c.String("remote"))
+						if err != nil {
+							return err
+						}
+						fmt.Println(mr.State, mr.WebURL)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "print the unified diff of a merge request",
+				ArgsUsage: "<project!iid|!iid>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "color",
+						Usage: "colorize the diff",
+					},
+					&cli.StringFlag{
+						Name:    "pager",
+						Usage:   "pipe the diff into this command (e.g. delta or \"less -R\")",
+						EnvVars: []string{"GITLAB_UTIL_PAGER"},
+					},
+					remoteFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						mr, err := mrm.FetchByReference(c.Context, c.Args().First(), c.String("remote"))
+						if err != nil {
+							return err
+						}
+						diff, err := mrm.PullDiff(c.Context, mr.ID)
+						if err != nil {
+							return err
+						}
+						text := ggl.RenderDiffString(diff)
+						if c.Bool("color") {
+							text = glui.ColorizeDiff(text)
+						}
+						return page(text, c.String("pager"))
+					})
+				},
+			},
+			{
+				Name:  "create",
+				Usage: "create a merge request for the current branch of the local git repository",
+				Flags: []cli.Flag{
+					remoteFlag(),
+					&cli.StringFlag{
+						Name:  "target-branch",
+						Usage: "target branch of the merge request (defaults to the default branch of the project)",
+					},
+					&cli.StringFlag{
+						Name:    "title",
+						Aliases: []string{"t"},
+						Usage:   "title of the merge request (opens $EDITOR with the last commit message if not set)",
+					},
+					&cli.StringFlag{
+						Name:    "description",
+						Aliases: []string{"d"},
+						Usage:   "description of the merge request",
+					},
+					&cli.StringSliceFlag{
+						Name:    "label",
+						Aliases: []string{"l"},
+						Usage:   "label to add (can be repeated)",
+					},
+					&cli.StringSliceFlag{
+						Name:    "reviewer",
+						Aliases: []string{"r"},
+						Usage:   "username of a reviewer (can be repeated)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					title, description := c.String("title"), c.String("description")
+					if title == "" {
+						template, err := ggl.LastCommitMessage()
+						if err != nil {
+							return err
+						}
+						title, description, err = editMessage(template)
+						if err != nil {
+							return err
+						}
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						mr, err := mrm.CreateMergeRequestFromLocal(c.Context, ggl.CreateMergeRequestOptions{
+							Remote:       c.String("remote"),
+							TargetBranch: c.String("target-branch"),
+							Title:        title,
+							Description:  description,
+							Labels:       c.StringSlice("label"),
+							Reviewers:    c.StringSlice("reviewer"),
+						})
+						if err != nil {
+							return err
+						}
+						fmt.Println("created", mr.WebURL)
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "watch",
+				Usage: "print the merge requests of an author, reviewer or assignee as a periodically refreshing plain-text table",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "author",
+						Usage: "author of the merge requests (e.g. renovate-bot)",
+					},
+					&cli.StringFlag{
+						Name:  "reviewer",
+						Usage: "reviewer of the merge requests (e.g. your username)",
+					},
+					&cli.StringFlag{
+						Name:  "reviewer-group",
+						Usage: "also show the merge requests with a member of this group (path or id) as reviewer",
+					},
+					&cli.StringFlag{
+						Name:  "assignee",
+						Usage: "assignee of the merge requests (e.g. your username, None or Any)",
+					},
+					&cli.StringFlag{
+						Name:  "milestone",
+						Usage: "milestone of the merge requests (title, None or Any)",
+					},
+					&cli.DurationFlag{
+						Name:    "interval",
+						Aliases: []string{"n"},
+						Usage:   "time between refreshes",
+						Value:   time.Minute,
+					},
+					&cli.BoolFlag{
+						Name:  "color",
+						Usage: "colorize the output even if stdout is not a terminal",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if !hasMergeRequestFilter(c) {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						if err := filterMergeRequests(c, mrm); err != nil {
+							return err
+						}
+						return glui.Watch(c.Context, mrm, os.Stdout, glui.WatchOptions{
+							Interval: c.Duration("interval"),
+							Color:    c.Bool("color"),
+						})
+					})
+				},
+			},
+			{
+				Name:      "checkout",
+				Usage:     "fetch the head of a merge request into the local clone and check it out as mr/<iid>",
+				ArgsUsage: "<project!iid|!iid>",
+				Flags: []cli.Flag{
+					remoteFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						branch, err := mrm.CheckoutByReference(c.Context, c.Args().First(), c.String("remote"))
+						if err != nil {
+							return err
+						}
+						fmt.Println("checked out", branch)
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// projectFlag selects the project of project commands, it defaults to the project of the local git repository
+func projectFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "project",
+		Aliases: []string{"p"},
+		Usage:   "project path (defaults to the project the --remote of the local git repository points to)",
+	}
+}
+
+// remoteFlag is the git remote of the local repository used for the default project and !iid references
+func remoteFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "remote",
+		Usage: "git remote pointing to the gitlab project",
+		Value: "origin",
+	}
+}
+
+// localProject returns the --project flag or the project the --remote of the local git repository points to
+func localProject(c *cli.Context, mrm *ggl.MergeRequestManager) (string, error) {
+	project := c.String("project")
+	if project == "" {
+		project = mrm.LocalDefaultProject(c.String("remote"))
+	}
+	if project == "" {
+		return "", cli.Exit("no --project given and not inside a clone of a gitlab project", 1)
+	}
+	return project, nil
+}
+
+// page writes text to stdout, or to the stdin of the pager command if one is given
+func page(text string, pager string) error {
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		_, err := fmt.Print(text)
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// withMergeRequestManager opens the default MergeRequestManager for the duration of f
+func withMergeRequestManager(f func(mrm *ggl.MergeRequestManager) error) error {
+	mrm, err := ggl.NewDefaultMergeRequestManager(managerOptions...)
+	if err != nil {
+		return err
+	}
+	defer mrm.Close()
+	return f(mrm)
+}
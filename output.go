@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputFlag selects the output format of listing commands
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   "output format (table, csv or json)",
+		Value:   "table",
+	}
+}
+
+// printOutput writes v as json or, using writeTable, as aligned table or csv to stdout depending on the output flag.
+// writeTable writes one line per row with the cells separated by tabs.
+func printOutput(c *cli.Context, v interface{}, writeTable func(w io.Writer)) error {
+	switch c.String("output") {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		writeTable(w)
+		return w.Flush()
+	case "csv":
+		var table bytes.Buffer
+		writeTable(&table)
+		w := csv.NewWriter(os.Stdout)
+		for _, line := range strings.Split(table.String(), "\n") {
+			if line == "" {
+				continue
+			}
+			err := w.Write(strings.Split(line, "\t"))
+			if err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unknown output format %q", c.String("output"))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"strings"
+)
+
+// pipelineCommand holds the pipeline subcommands
+func pipelineCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "pipeline",
+		Usage: "pipeline commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list the latest pipelines of a project",
+				Flags: []cli.Flag{
+					projectFlag(),
+					remoteFlag(),
+					&cli.StringFlag{
+						Name:  "ref",
+						Usage: "only list the pipelines of this branch or tag",
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"n"},
+						Usage:   "number of pipelines to list",
+						Value:   20,
+					},
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						project, err := localProject(c, mrm)
+						if err != nil {
+							return err
+						}
+						pipelines, err := mrm.ListProjectPipelines(c.Context, project, c.String("ref"), c.Int("limit"))
+						if err != nil {
+							return err
+						}
+						return printOutput(c, pipelines, func(w io.Writer) {
+							for _, p := range pipelines {
+								_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", p.ID, p.Status, p.Ref, p.Source, p.WebURL)
+							}
+						})
+					})
+				},
+			},
+		},
+	}
+}
+
+// ciCommand holds the ci subcommands
+func ciCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ci",
+		Usage: "ci configuration commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "lint",
+				Usage:     "validate a ci configuration in the context of the project (includes, variables)",
+				ArgsUsage: "[file (defaults to .gitlab-ci.yml, - reads stdin)]",
+				Flags: []cli.Flag{
+					projectFlag(),
+					remoteFlag(),
+					&cli.StringFlag{
+						Name:  "ref",
+						Usage: "branch or tag to resolve includes at (defaults to the default branch)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					file := ".gitlab-ci.yml"
+					if c.NArg() > 0 {
+						file = c.Args().First()
+					}
+					var content []byte
+					var err error
+					if file == "-" {
+						content, err = io.ReadAll(os.Stdin)
+					} else {
+						content, err = os.ReadFile(file)
+					}
+					if err != nil {
+						return err
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						project, err := localProject(c, mrm)
+						if err != nil {
+							return err
+						}
+						result, err := mrm.LintCi(c.Context, project, string(content), c.String("ref"))
+						if err != nil {
+							return err
+						}
+						for _, w := range result.Warnings {
+							fmt.Println("warning:", w)
+						}
+						if !result.Valid {
+							return cli.Exit(file+" is invalid:\n"+strings.Join(result.Errors, "\n"), 1)
+						}
+						fmt.Println(file, "is valid")
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"time"
+)
+
+// ReasonStale is the abort reason for merge requests older than the max age
+const ReasonStale = "stale"
+
+// validateAgeLimits checks that a merge request can be old enough to merge without being stale
+func validateAgeLimits(minAge time.Duration, maxAge time.Duration) error {
+	if minAge < 0 || maxAge < 0 {
+		return errors.New("min and max age must not be negative")
+	}
+	if maxAge > 0 && minAge >= maxAge {
+		return errors.New("min age must be shorter than max age")
+	}
+	return nil
+}
+
+// AgeNote describes whether the open merge request is too young to merge or stale, it is empty otherwise
+func (m *MergeRequestManager) AgeNote(mr *gitlab.MergeRequest) string {
+	if mr.CreatedAt == nil || mr.State != "opened" {
+		return ""
+	}
+	age := m.clock.Now().Sub(*mr.CreatedAt)
+	switch {
+	case m.maxAge > 0 && age > m.maxAge:
+		return ReasonStale
+	case age < m.minAge:
+		return "too young"
+	}
+	return ""
+}
+
+// ageOutcome holds merge requests younger than the min age and stops stale ones, closing them if configured.
+// It returns false if the age doesn't keep the target from being processed.
+func (m *MergeRequestManager) ageOutcome(ctx context.Context, target mergeTarget, current *gitlab.MergeRequest) (MergeOutcome, bool) {
+	switch m.AgeNote(current) {
+	case ReasonStale:
+		if !m.closeStale {
+			return abortOutcome(ReasonStale + " (older than " + formatDelay(m.maxAge) + ")"), true
+		}
+		err := m.CloseMergeRequest(ctx, target.Id)
+		if err != nil {
+			m.logger.Error("error closing stale merge request", "target", target.Id, "err", err)
+			return errorOutcome("closing stale merge request", err), true
+		}
+		return abortOutcome(ReasonStale + ", closed"), true
+	case "too young":
+		mergeableAt := current.CreatedAt.Add(m.minAge)
+		return retryOutcome("too young, mergeable at "+mergeableAt.Local().Format("15:04"),
+			mergeableAt.Sub(m.clock.Now()).Round(time.Second)+time.Second), true
+	}
+	return MergeOutcome{}, false
+}
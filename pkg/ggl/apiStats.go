@@ -0,0 +1,63 @@
+package ggl
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// APIStats is a http.RoundTripper counting the gitlab api calls and tracking the rate limit reported by gitlab
+type APIStats struct {
+	next               http.RoundTripper
+	mu                 sync.Mutex
+	calls              []time.Time
+	rateLimit          int
+	rateLimitRemaining int
+}
+
+// NewAPIStats creates APIStats sending the requests through next
+func NewAPIStats(next http.RoundTripper) *APIStats {
+	return &APIStats{next: next, rateLimit: -1, rateLimitRemaining: -1}
+}
+
+// RoundTrip implements http.RoundTripper
+func (s *APIStats) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := s.next.RoundTrip(req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.recentCalls(time.Now()), time.Now())
+	if resp != nil {
+		if limit, err := strconv.Atoi(resp.Header.Get("RateLimit-Limit")); err == nil {
+			s.rateLimit = limit
+		}
+		if remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining")); err == nil {
+			s.rateLimitRemaining = remaining
+		}
+	}
+	return resp, err
+}
+
+// CallsLastMinute returns the number of api calls in the last minute
+func (s *APIStats) CallsLastMinute() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = s.recentCalls(time.Now())
+	return len(s.calls)
+}
+
+// RateLimit returns limit and remaining requests of the last response, -1 if gitlab did not report them
+func (s *APIStats) RateLimit() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rateLimit, s.rateLimitRemaining
+}
+
+func (s *APIStats) recentCalls(now time.Time) []time.Time {
+	i := 0
+	for i < len(s.calls) && now.Sub(s.calls[i]) > time.Minute {
+		i++
+	}
+	return s.calls[i:]
+}
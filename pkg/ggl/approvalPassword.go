@@ -0,0 +1,76 @@
+package ggl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+)
+
+// ReasonApprovalPassword is the reason of targets waiting for the approval password of instances that require
+// re-authentication to approve
+const ReasonApprovalPassword = "approval password required"
+
+// isUnauthorized reports whether gitlab answered the request with 401 Unauthorized
+func isUnauthorized(err error) bool {
+	var e *gitlab.ErrorResponse
+	return errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusUnauthorized
+}
+
+// approveOptions adds the approval password to the approve request if one is set
+func (m *MergeRequestManager) approveOptions() []gitlab.RequestOptionFunc {
+	password := m.approvalPassword.Load()
+	if password == nil || *password == "" {
+		return nil
+	}
+	return []gitlab.RequestOptionFunc{withApprovalPassword(*password)}
+}
+
+// approveAt approves the head commit of the merge request only, if there is one
+func approveAt(mr *gitlab.MergeRequest) *gitlab.ApproveMergeRequestOptions {
+	opts := &gitlab.ApproveMergeRequestOptions{}
+	if mr.SHA != "" {
+		opts.SHA = gitlab.Ptr(mr.SHA)
+	}
+	return opts
+}
+
+// SetApprovalPassword sets the password used to approve and retries the targets that are waiting for it
+func (m *MergeRequestManager) SetApprovalPassword(password string) error {
+	m.approvalPassword.Store(&password)
+	targets, err := loadAll[mergeTarget](m.db, targetPrefix)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.Active && t.Outcome.Reason == ReasonApprovalPassword {
+			err = errors.Join(err, m.RetryNow(t.Id))
+		}
+	}
+	return err
+}
+
+// withApprovalPassword adds approval_password to the json body of the request, go-gitlab has no field for it
+func withApprovalPassword(password string) gitlab.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		body := map[string]interface{}{}
+		data, err := req.BodyBytes()
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			err = json.Unmarshal(data, &body)
+			if err != nil {
+				return err
+			}
+		}
+		body["approval_password"] = password
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req.SetBody(data)
+	}
+}
@@ -0,0 +1,48 @@
+package ggl_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"testing"
+	"time"
+)
+
+// approvalPasswordScenario is a renovate merge request on an instance that requires the password to approve
+func approvalPasswordScenario() fakegitlab.Scenario {
+	scenario := fakegitlab.RenovateBump()
+	scenario.Statuses = nil
+	return scenario
+}
+
+func TestApprovalPasswordRequired(t *testing.T) {
+	scenario := approvalPasswordScenario()
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario, ggl.WithClock(clock))
+	h.Server.RequireApprovalPassword("secret")
+	enable(t, h.Manager, 101)
+
+	target := processOnce(t, h.Manager, 101)
+	if !target.Active || target.Outcome.Reason != ggl.ReasonApprovalPassword || target.Outcome.RetryAfter != time.Hour {
+		t.Fatalf("target is active %v with outcome %+v, want waiting an hour for the approval password", target.Active, target.Outcome)
+	}
+
+	// entering the password retries right away instead of waiting an hour
+	err := h.Manager.SetApprovalPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target = processOnce(t, h.Manager, 101)
+	if target.Outcome.State != ggl.OutcomeMerged {
+		t.Errorf("target is %s with the password, want merged", target.Info)
+	}
+}
+
+func TestApprovalPasswordOption(t *testing.T) {
+	h := newHarness(t, approvalPasswordScenario(), ggl.WithApprovalPassword("secret"))
+	h.Server.RequireApprovalPassword("secret")
+	enable(t, h.Manager, 101)
+
+	if target := processOnce(t, h.Manager, 101); target.Outcome.State != ggl.OutcomeMerged {
+		t.Errorf("target is %s, want merged", target.Info)
+	}
+}
@@ -0,0 +1,38 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+)
+
+// Approvals is the approval progress of a merge request
+type Approvals struct {
+	Approved int
+	Required int
+	Left     int
+}
+
+// String renders the progress like "approved 1/2"
+func (a Approvals) String() string {
+	switch {
+	case a.Required > 0:
+		return fmt.Sprintf("approved %d/%d", a.Approved, a.Required)
+	case a.Approved > 0:
+		return fmt.Sprintf("approved %d", a.Approved)
+	}
+	return ""
+}
+
+// fetchApprovals fetches and stores the approval progress of the merge request
+func (m *MergeRequestManager) fetchApprovals(ctx context.Context, mr *gitlab.MergeRequest) error {
+	config, _, err := m.gl.MergeRequestApprovals.GetConfiguration(mr.ProjectID, mr.IID, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return store(m.db, approvalsKey(mr.ID), Approvals{
+		Approved: len(config.ApprovedBy),
+		Required: config.ApprovalsRequired,
+		Left:     config.ApprovalsLeft,
+	})
+}
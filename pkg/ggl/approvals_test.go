@@ -0,0 +1,64 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"testing"
+)
+
+func TestApprovalProgress(t *testing.T) {
+	ctx := context.Background()
+	h := newHarness(t, fakegitlab.RenovateBump())
+
+	before := mergeRequestInfo(t, h.Manager, 101).Approvals
+	if want := (ggl.Approvals{Approved: 0, Required: 1, Left: 1}); before != want {
+		t.Errorf("approvals before approving are %+v, want %+v", before, want)
+	}
+	if before.String() != "approved 0/1" {
+		t.Errorf("progress is %q, want approved 0/1", before.String())
+	}
+
+	err := h.Manager.ApproveMergeRequest(ctx, 101, pullDiff(t, h.Manager, 101))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := mergeRequestInfo(t, h.Manager, 101).Approvals
+	if want := (ggl.Approvals{Approved: 1, Required: 1, Left: 0}); after != want {
+		t.Errorf("approvals after approving are %+v, want %+v", after, want)
+	}
+	if after.String() != "approved 1/1" {
+		t.Errorf("progress is %q, want approved 1/1", after.String())
+	}
+}
+
+func TestApprovalsString(t *testing.T) {
+	tests := []struct {
+		approvals ggl.Approvals
+		want      string
+	}{
+		{ggl.Approvals{Approved: 1, Required: 2, Left: 1}, "approved 1/2"},
+		{ggl.Approvals{Approved: 2}, "approved 2"},
+		{ggl.Approvals{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.approvals.String(); got != tt.want {
+			t.Errorf("%+v renders as %q, want %q", tt.approvals, got, tt.want)
+		}
+	}
+}
+
+func TestApproveAfterPush(t *testing.T) {
+	h := newHarness(t, fakegitlab.RenovateBump())
+	reviewed := pullDiff(t, h.Manager, 101)
+	// the push lands after the cached diff was compared
+	h.Server.SetDiff(101, fakegitlab.Diff("go.mod", "@@ -1 +1 @@\n-golang.org/x/net v0.35.0\n+golang.org/x/net v0.37.0\n"))
+
+	err := h.Manager.ApproveMergeRequest(context.Background(), 101, reviewed)
+	if err == nil || err.Error() != ggl.ReasonDiffChanged {
+		t.Errorf("approving after the push returned %v, want %s", err, ggl.ReasonDiffChanged)
+	}
+	if sha := h.Server.ApprovedAt(101); sha != "" {
+		t.Errorf("approved the pushed head %s", sha)
+	}
+}
@@ -0,0 +1,237 @@
+package ggl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"path"
+	"slices"
+	"strings"
+)
+
+// codeownersFiles are the locations of the CODEOWNERS file in the order gitlab looks for it
+var codeownersFiles = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// ApproverRule is an approval rule a merge request still needs approvals for and who can give them
+type ApproverRule struct {
+	Name string
+	// Left is the number of approvals the rule is missing, 0 for code owners gitlab doesn't require approvals from
+	Left int
+	// Approvers are the usernames of the eligible approvers, or the paths of groups owning files in CODEOWNERS
+	Approvers []string
+}
+
+// EligibleApprovers returns the approval rules the merge request still needs approvals for, with the users who can
+// approve them. If gitlab has no code owner rules for the merge request, e.g. without premium, the owners of the
+// changed files in the CODEOWNERS file of the target branch are added as they are the ones to ask.
+func (m *MergeRequestManager) EligibleApprovers(ctx context.Context, id int) ([]ApproverRule, error) {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := m.gl.MergeRequestApprovals.GetApprovalState(mr.ProjectID, mr.IID, gitlab.WithContext(ctx))
+	if err != nil && !errors.Is(err, gitlab.ErrNotFound) {
+		return nil, err
+	}
+	var rules []ApproverRule
+	codeOwnerRules := false
+	if state != nil {
+		for _, r := range state.Rules {
+			codeOwnerRules = codeOwnerRules || r.RuleType == "code_owner"
+			if r.Approved {
+				continue
+			}
+			rule := ApproverRule{Name: r.Name, Left: r.ApprovalsRequired - len(r.ApprovedBy)}
+			if r.RuleType == "code_owner" {
+				rule.Name = "code owners of " + r.Name
+			}
+			eligible := r.EligibleApprovers
+			if r.RuleType == "any_approver" && len(eligible) == 0 {
+				members, err := m.ProjectMembers(ctx, mr.ProjectID)
+				if err != nil {
+					return nil, err
+				}
+				for _, member := range members {
+					eligible = append(eligible, &gitlab.BasicUser{Username: member.Username})
+				}
+			}
+			for _, u := range eligible {
+				approved := slices.ContainsFunc(r.ApprovedBy, func(a *gitlab.BasicUser) bool { return a.Username == u.Username })
+				if !approved && (mr.Author == nil || u.Username != mr.Author.Username) {
+					rule.Approvers = append(rule.Approvers, u.Username)
+				}
+			}
+			rules = append(rules, rule)
+		}
+	}
+	if codeOwnerRules {
+		return rules, nil
+	}
+	owners, err := m.codeOwners(ctx, mr)
+	if err != nil {
+		m.logger.Warn("error reading the code owners", "mr", id, "err", err)
+	}
+	return append(rules, owners...), nil
+}
+
+// codeOwners returns the owners of the files changed by the merge request from the CODEOWNERS file of the target
+// branch, one rule per matching pattern
+func (m *MergeRequestManager) codeOwners(ctx context.Context, mr *gitlab.MergeRequest) ([]ApproverRule, error) {
+	var data []byte
+	for _, file := range codeownersFiles {
+		content, _, err := m.gl.RepositoryFiles.GetRawFile(mr.ProjectID, file, &gitlab.GetRawFileOptions{Ref: gitlab.Ptr(mr.TargetBranch)}, gitlab.WithContext(ctx))
+		if errors.Is(err, gitlab.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = content
+		break
+	}
+	if data == nil {
+		return nil, nil
+	}
+	diff, err := m.PullDiff(ctx, mr.ID)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseCodeowners(data)
+	var rules []ApproverRule
+	for _, d := range diff {
+		for _, file := range []string{d.OldPath, d.NewPath} {
+			for _, e := range matchingCodeowners(entries, file) {
+				name := "code owners of " + e.pattern
+				i := slices.IndexFunc(rules, func(r ApproverRule) bool { return r.Name == name })
+				if i < 0 {
+					rules = append(rules, ApproverRule{Name: name, Approvers: e.owners})
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+// codeownersEntry is a pattern of a CODEOWNERS file with its owners, the section groups the entries of which the
+// last matching one applies
+type codeownersEntry struct {
+	section string
+	pattern string
+	owners  []string
+}
+
+// parseCodeowners reads the entries of a CODEOWNERS file, entries without owners get the default owners of their
+// section. Owners given by email can't be mentioned and are left out.
+func parseCodeowners(data []byte) []codeownersEntry {
+	var entries []codeownersEntry
+	var section string
+	var defaults []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if header, ok := strings.CutPrefix(strings.TrimPrefix(line, "^"), "["); ok {
+			name, rest, _ := strings.Cut(header, "]")
+			section = name
+			// the number of required approvals may follow the section name, e.g. [Docs][2] @docs
+			if strings.HasPrefix(rest, "[") {
+				_, rest, _ = strings.Cut(rest, "]")
+			}
+			defaults = mentionableOwners(strings.Fields(rest))
+			continue
+		}
+		fields := strings.Fields(line)
+		owners := mentionableOwners(fields[1:])
+		if len(fields) == 1 {
+			owners = defaults
+		}
+		entries = append(entries, codeownersEntry{section: section, pattern: fields[0], owners: owners})
+	}
+	return entries
+}
+
+func mentionableOwners(fields []string) []string {
+	var owners []string
+	for _, f := range fields {
+		if owner, ok := strings.CutPrefix(f, "@"); ok {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// matchingCodeowners returns the entry owning the file per section, the last matching entry of a section wins
+func matchingCodeowners(entries []codeownersEntry, file string) []codeownersEntry {
+	var matches []codeownersEntry
+	for _, e := range entries {
+		if !codeownersMatch(e.pattern, file) {
+			continue
+		}
+		i := slices.IndexFunc(matches, func(match codeownersEntry) bool { return match.section == e.section })
+		if i < 0 {
+			matches = append(matches, e)
+		} else {
+			matches[i] = e
+		}
+	}
+	return slices.DeleteFunc(matches, func(e codeownersEntry) bool { return len(e.owners) == 0 })
+}
+
+// codeownersMatch reports whether the CODEOWNERS pattern matches the file like a gitignore pattern: patterns with a
+// leading or inner slash are relative to the repository root, others match in any directory, and a trailing slash
+// matches everything below the directory
+func codeownersMatch(pattern string, file string) bool {
+	dir := strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, "/**")
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	p := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "/"), "/")
+	parts := strings.Split(file, "/")
+	for start := range parts {
+		if anchored && start > 0 {
+			break
+		}
+		for end := start + 1; end <= len(parts); end++ {
+			if dir && end == len(parts) {
+				break
+			}
+			if ok, _ := path.Match(p, strings.Join(parts[start:end], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PingApprovers mentions the eligible approvers in a comment asking them to approve the merge request
+func (m *MergeRequestManager) PingApprovers(ctx context.Context, id int, rules []ApproverRule) error {
+	if m.gl.Notes == nil {
+		return errors.New("the client has no notes api")
+	}
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	var mentions, names []string
+	for _, r := range rules {
+		for _, a := range r.Approvers {
+			if !slices.Contains(mentions, "@"+a) {
+				mentions = append(mentions, "@"+a)
+			}
+		}
+		names = append(names, r.Name)
+	}
+	if len(mentions) == 0 {
+		return errors.New("no eligible approvers to ping")
+	}
+	body := fmt.Sprintf("%s this merge request is waiting for approval (%s), could you have a look?", strings.Join(mentions, " "), strings.Join(names, ", "))
+	_, _, err = m.gl.Notes.CreateMergeRequestNote(mr.ProjectID, mr.IID, &gitlab.CreateMergeRequestNoteOptions{Body: &body}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("pinged approvers", "mr", id, "approvers", mentions)
+	return nil
+}
@@ -0,0 +1,95 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"reflect"
+	"testing"
+)
+
+// codeowners makes the frontend team own package.json, the release section only has an owner by email which can't
+// be mentioned
+const codeowners = `# owners of the frontend
+[Frontend] @frontend-team
+package.json
+*.md @docs-team
+[Release][2]
+/package.json release@example.com
+`
+
+func approversScenario(codeOwnerRule bool) fakegitlab.Scenario {
+	scenario := fakegitlab.Mixed()
+	rules := []*gitlab.MergeRequestApprovalRule{
+		{
+			Name:              "Maintainers",
+			RuleType:          "regular",
+			ApprovalsRequired: 2,
+			EligibleApprovers: []*gitlab.BasicUser{{Username: "alice"}, {Username: "bob"}, {Username: "renovate-bot"}},
+			ApprovedBy:        []*gitlab.BasicUser{{Username: "bob"}},
+		},
+		{Name: "All Members", RuleType: "any_approver", ApprovalsRequired: 1},
+		{Name: "Security", RuleType: "regular", Approved: true},
+	}
+	if codeOwnerRule {
+		rules = append(rules, &gitlab.MergeRequestApprovalRule{
+			Name:              "package.json",
+			RuleType:          "code_owner",
+			ApprovalsRequired: 1,
+			EligibleApprovers: []*gitlab.BasicUser{{Username: "carol"}},
+		})
+	}
+	scenario.ApprovalRules = map[int][]*gitlab.MergeRequestApprovalRule{203: rules}
+	scenario.Files = map[int]map[string]string{2: {".gitlab/CODEOWNERS": codeowners}}
+	return scenario
+}
+
+func TestEligibleApprovers(t *testing.T) {
+	maintainers := ggl.ApproverRule{Name: "Maintainers", Left: 1, Approvers: []string{"alice"}}
+	members := ggl.ApproverRule{Name: "All Members", Left: 1, Approvers: []string{"alice", "bob"}}
+	tests := []struct {
+		name          string
+		codeOwnerRule bool
+		want          []ggl.ApproverRule
+	}{
+		{"codeowners file", false, []ggl.ApproverRule{maintainers, members, {Name: "code owners of package.json", Approvers: []string{"frontend-team"}}}},
+		{"code owner rule", true, []ggl.ApproverRule{maintainers, members, {Name: "code owners of package.json", Left: 1, Approvers: []string{"carol"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, approversScenario(tt.codeOwnerRule))
+			rules, err := h.Manager.EligibleApprovers(context.Background(), 203)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("rules are %+v, want %+v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestPingApprovers(t *testing.T) {
+	h := newHarness(t, approversScenario(false))
+	ctx := context.Background()
+	rules, err := h.Manager.EligibleApprovers(ctx, 203)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Manager.PingApprovers(ctx, 203, rules); err != nil {
+		t.Fatal(err)
+	}
+	notes := h.Server.Notes(203)
+	want := "@alice @bob @frontend-team this merge request is waiting for approval (Maintainers, All Members, code owners of package.json), could you have a look?"
+	if len(notes) != 1 || notes[0].Body != want {
+		t.Errorf("notes are %+v, want one %q", notes, want)
+	}
+
+	if err := h.Manager.PingApprovers(ctx, 202, nil); err == nil {
+		t.Error("pinged without approvers")
+	}
+	if notes := h.Server.Notes(202); len(notes) > 0 {
+		t.Errorf("notes without approvers are %+v", notes)
+	}
+}
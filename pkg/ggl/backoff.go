@@ -0,0 +1,78 @@
+package ggl
+
+import (
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// classes of errors that back off differently
+const (
+	ErrorClassNetwork      = "network"
+	ErrorClassUnauthorized = "unauthorized"
+	ErrorClassNotFound     = "not found"
+	ErrorClassNotAllowed   = "not allowed"
+	ErrorClassConflict     = "conflict"
+	ErrorClassClient       = "client"
+	ErrorClassServer       = "server"
+)
+
+// permanentErrorClasses don't go away by retrying, somebody has to look at the token or the merge request
+var permanentErrorClasses = []string{ErrorClassUnauthorized, ErrorClassNotFound}
+
+// errorBackoffs are the first delay and the cap per error class: network errors and conflicts usually pass quickly,
+// gitlab errors take longer and other client errors rarely go away on their own
+var errorBackoffs = map[string]struct{ base, max time.Duration }{
+	ErrorClassNetwork:    {30 * time.Second, 10 * time.Minute},
+	ErrorClassConflict:   {30 * time.Second, 10 * time.Minute},
+	ErrorClassNotAllowed: {1 * time.Minute, 30 * time.Minute},
+	ErrorClassServer:     {1 * time.Minute, 30 * time.Minute},
+	ErrorClassClient:     {5 * time.Minute, 1 * time.Hour},
+}
+
+// errorClass classifies err by its cause
+func errorClass(err error) string {
+	if errors.Is(err, gitlab.ErrNotFound) {
+		return ErrorClassNotFound
+	}
+	var e *gitlab.ErrorResponse
+	if errors.As(err, &e) && e.Response != nil {
+		switch code := e.Response.StatusCode; {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return ErrorClassUnauthorized
+		case code == http.StatusNotFound:
+			return ErrorClassNotFound
+		case code == http.StatusMethodNotAllowed:
+			return ErrorClassNotAllowed
+		case code == http.StatusConflict:
+			return ErrorClassConflict
+		case code < 500 && code != http.StatusTooManyRequests:
+			return ErrorClassClient
+		}
+		return ErrorClassServer
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorClassNetwork
+	}
+	return ErrorClassServer
+}
+
+// errorBackoff doubles the delay of the class for every consecutive error and adds up to 20% jitter, so that
+// targets failing together don't retry in lockstep
+func errorBackoff(class string, consecutive int) time.Duration {
+	b, ok := errorBackoffs[class]
+	if !ok {
+		b = errorBackoffs[ErrorClassServer]
+	}
+	d := b.base
+	for i := 1; i < consecutive && d < b.max; i++ {
+		d *= 2
+	}
+	d = min(d, b.max)
+	d += time.Duration(rand.Int64N(int64(d) / 5))
+	return d.Round(time.Second)
+}
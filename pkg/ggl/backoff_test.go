@@ -0,0 +1,49 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestErrorBackoffSchedulesNext(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	scenario.Statuses = nil
+	scenario.MergeRequests[0].DetailedMergeStatus = "mergeable"
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario, ggl.WithClock(clock))
+	enable(t, h.Manager, 101)
+
+	// client errors start at 5 minutes and double up to an hour
+	backoffs := []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 40 * time.Minute, time.Hour}
+	h.Server.FailWith("PUT merge", len(backoffs), http.StatusUnprocessableEntity)
+	for i, backoff := range backoffs {
+		attempt := clock.Now()
+		target := processOnce(t, h.Manager, 101)
+		if target.Outcome.State != ggl.OutcomeError || target.Outcome.ErrorClass != ggl.ErrorClassClient {
+			t.Fatalf("error %d: outcome %+v", i+1, target.Outcome)
+		}
+		delay := target.NextAttempt.Sub(attempt)
+		// up to 20% jitter rounded to seconds
+		if delay < backoff || delay > backoff*6/5+time.Second {
+			t.Errorf("error %d: next attempt in %s, want %s plus jitter", i+1, delay, backoff)
+		}
+
+		clock.Advance(delay - time.Second)
+		result, err := h.Manager.ProcessOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Held != 1 {
+			t.Errorf("error %d: processed %+v before the next attempt", i+1, result)
+		}
+		clock.Advance(time.Second)
+	}
+
+	if target := processOnce(t, h.Manager, 101); target.Outcome.State != ggl.OutcomeMerged {
+		t.Errorf("outcome after the errors is %+v", target.Outcome)
+	}
+}
@@ -0,0 +1,167 @@
+package ggl
+
+import (
+	"github.com/xanzy/go-gitlab"
+	"net/url"
+)
+
+// MergeRequestsService is the part of the gitlab merge requests api used by the MergeRequestManager
+type MergeRequestsService interface {
+	ListMergeRequests(opt *gitlab.ListMergeRequestsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.MergeRequest, *gitlab.Response, error)
+	ListProjectMergeRequests(pid interface{}, opt *gitlab.ListProjectMergeRequestsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.MergeRequest, *gitlab.Response, error)
+	GetMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.GetMergeRequestsOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+	ListMergeRequestDiffs(pid interface{}, mergeRequest int, opt *gitlab.ListMergeRequestDiffsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.MergeRequestDiff, *gitlab.Response, error)
+	CreateMergeRequest(pid interface{}, opt *gitlab.CreateMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+	AcceptMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.AcceptMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+	UpdateMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.UpdateMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+	RebaseMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.RebaseMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Response, error)
+	GetMergeRequestCommits(pid interface{}, mergeRequest int, opt *gitlab.GetMergeRequestCommitsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Commit, *gitlab.Response, error)
+}
+
+// MergeRequestApprovalsService is the part of the gitlab approvals api used by the MergeRequestManager
+type MergeRequestApprovalsService interface {
+	ApproveMergeRequest(pid interface{}, mr int, opt *gitlab.ApproveMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequestApprovals, *gitlab.Response, error)
+	GetConfiguration(pid interface{}, mr int, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequestApprovals, *gitlab.Response, error)
+	GetApprovalState(pid interface{}, mr int, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequestApprovalState, *gitlab.Response, error)
+}
+
+// ProjectsService is the part of the gitlab projects api used by the MergeRequestManager
+type ProjectsService interface {
+	ListProjects(opt *gitlab.ListProjectsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Project, *gitlab.Response, error)
+	GetProject(pid interface{}, opt *gitlab.GetProjectOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Project, *gitlab.Response, error)
+}
+
+// ProjectMembersService is the part of the gitlab project members api used by the MergeRequestManager
+type ProjectMembersService interface {
+	ListAllProjectMembers(pid interface{}, opt *gitlab.ListProjectMembersOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.ProjectMember, *gitlab.Response, error)
+}
+
+// RepositoryFilesService is the part of the gitlab repository files api used by the MergeRequestManager
+type RepositoryFilesService interface {
+	GetRawFile(pid interface{}, fileName string, opt *gitlab.GetRawFileOptions, options ...gitlab.RequestOptionFunc) ([]byte, *gitlab.Response, error)
+}
+
+// RepositoriesService is the part of the gitlab repositories api used by the MergeRequestManager
+type RepositoriesService interface {
+	Compare(pid interface{}, opt *gitlab.CompareOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Compare, *gitlab.Response, error)
+}
+
+// UsersService is the part of the gitlab users api used by the MergeRequestManager
+type UsersService interface {
+	ListUsers(opt *gitlab.ListUsersOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.User, *gitlab.Response, error)
+	CurrentUser(options ...gitlab.RequestOptionFunc) (*gitlab.User, *gitlab.Response, error)
+}
+
+// IssuesService is the part of the gitlab issues api used by the MergeRequestManager
+type IssuesService interface {
+	ListIssues(opt *gitlab.ListIssuesOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Issue, *gitlab.Response, error)
+}
+
+// GroupsService is the part of the gitlab groups api used by the MergeRequestManager
+type GroupsService interface {
+	ListGroups(opt *gitlab.ListGroupsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Group, *gitlab.Response, error)
+	ListAllGroupMembers(gid interface{}, opt *gitlab.ListGroupMembersOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.GroupMember, *gitlab.Response, error)
+}
+
+// LabelsService is the part of the gitlab labels api used by the MergeRequestManager
+type LabelsService interface {
+	ListLabels(pid interface{}, opt *gitlab.ListLabelsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Label, *gitlab.Response, error)
+	GetLabel(pid interface{}, labelID interface{}, options ...gitlab.RequestOptionFunc) (*gitlab.Label, *gitlab.Response, error)
+	CreateLabel(pid interface{}, opt *gitlab.CreateLabelOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Label, *gitlab.Response, error)
+	UpdateLabel(pid interface{}, opt *gitlab.UpdateLabelOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Label, *gitlab.Response, error)
+	DeleteLabel(pid interface{}, lid interface{}, opt *gitlab.DeleteLabelOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Response, error)
+}
+
+// JobsService is the part of the gitlab jobs api used by the MergeRequestManager
+type JobsService interface {
+	ListPipelineBridges(pid interface{}, pipelineID int, opts *gitlab.ListJobsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Bridge, *gitlab.Response, error)
+	ListPipelineJobs(pid interface{}, pipelineID int, opts *gitlab.ListJobsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Job, *gitlab.Response, error)
+}
+
+// PipelinesService is the part of the gitlab pipelines api used by the MergeRequestManager
+type PipelinesService interface {
+	RetryPipelineBuild(pid interface{}, pipeline int, options ...gitlab.RequestOptionFunc) (*gitlab.Pipeline, *gitlab.Response, error)
+	ListProjectPipelines(pid interface{}, opt *gitlab.ListProjectPipelinesOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.PipelineInfo, *gitlab.Response, error)
+}
+
+// ValidateService is the part of the gitlab ci lint api used by the MergeRequestManager
+type ValidateService interface {
+	ProjectNamespaceLint(pid interface{}, opt *gitlab.ProjectNamespaceLintOptions, options ...gitlab.RequestOptionFunc) (*gitlab.ProjectLintResult, *gitlab.Response, error)
+}
+
+// CommitsService is the part of the gitlab commits api used by the MergeRequestManager
+type CommitsService interface {
+	GetGPGSignature(pid interface{}, sha string, options ...gitlab.RequestOptionFunc) (*gitlab.GPGSignature, *gitlab.Response, error)
+	CreateCommit(pid interface{}, opt *gitlab.CreateCommitOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Commit, *gitlab.Response, error)
+}
+
+// NotesService is the part of the gitlab notes api used by the MergeRequestManager
+type NotesService interface {
+	ListMergeRequestNotes(pid interface{}, mergeRequest int, opt *gitlab.ListMergeRequestNotesOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Note, *gitlab.Response, error)
+	CreateMergeRequestNote(pid interface{}, mergeRequest int, opt *gitlab.CreateMergeRequestNoteOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Note, *gitlab.Response, error)
+}
+
+// SearchService is the part of the gitlab search api used by the MergeRequestManager
+type SearchService interface {
+	MergeRequests(query string, opt *gitlab.SearchOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.MergeRequest, *gitlab.Response, error)
+	Projects(query string, opt *gitlab.SearchOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Project, *gitlab.Response, error)
+}
+
+// PersonalAccessTokensService is the part of the gitlab personal access tokens api used by the MergeRequestManager
+type PersonalAccessTokensService interface {
+	GetSinglePersonalAccessToken(options ...gitlab.RequestOptionFunc) (*gitlab.PersonalAccessToken, *gitlab.Response, error)
+}
+
+// VersionService is the part of the gitlab version api used by the MergeRequestManager
+type VersionService interface {
+	GetVersion(options ...gitlab.RequestOptionFunc) (*gitlab.Version, *gitlab.Response, error)
+}
+
+// Client bundles the gitlab api services used by the MergeRequestManager.
+// Use WrapClient for a go-gitlab client or provide own implementations (e.g. fakes for tests).
+type Client struct {
+	BaseURL               *url.URL
+	MergeRequests         MergeRequestsService
+	MergeRequestApprovals MergeRequestApprovalsService
+	Projects              ProjectsService
+	ProjectMembers        ProjectMembersService
+	RepositoryFiles       RepositoryFilesService
+	Repositories          RepositoriesService
+	Users                 UsersService
+	Issues                IssuesService
+	Groups                GroupsService
+	Labels                LabelsService
+	Jobs                  JobsService
+	Pipelines             PipelinesService
+	Commits               CommitsService
+	Search                SearchService
+	Notes                 NotesService
+	PersonalAccessTokens  PersonalAccessTokensService
+	Version               VersionService
+	Validate              ValidateService
+}
+
+// WrapClient creates a Client backed by a go-gitlab client
+func WrapClient(gl *gitlab.Client) *Client {
+	return &Client{
+		BaseURL:               gl.BaseURL(),
+		MergeRequests:         gl.MergeRequests,
+		MergeRequestApprovals: gl.MergeRequestApprovals,
+		Projects:              gl.Projects,
+		ProjectMembers:        gl.ProjectMembers,
+		RepositoryFiles:       gl.RepositoryFiles,
+		Repositories:          gl.Repositories,
+		Users:                 gl.Users,
+		Issues:                gl.Issues,
+		Groups:                gl.Groups,
+		Labels:                gl.Labels,
+		Jobs:                  gl.Jobs,
+		Pipelines:             gl.Pipelines,
+		Commits:               gl.Commits,
+		Search:                gl.Search,
+		Notes:                 gl.Notes,
+		PersonalAccessTokens:  gl.PersonalAccessTokens,
+		Version:               gl.Version,
+		Validate:              gl.Validate,
+	}
+}
@@ -0,0 +1,67 @@
+package ggl
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config is the optional configuration stored in ~/.gitlab-util/config.json
+type Config struct {
+	// Yolo skips the confirmation before acting on a merge request in the ui
+	Yolo bool `json:"yolo,omitempty"`
+	// Columns are the columns of the auto merge table in display order, all columns are shown if empty
+	Columns []ColumnConfig `json:"columns,omitempty"`
+	// RequireSignedCommits only auto-approves merge requests whose commits all have a verified signature
+	RequireSignedCommits bool `json:"requireSignedCommits,omitempty"`
+	// JiraKey is added to merge requests blocked by a missing jira association
+	JiraKey string `json:"jiraKey,omitempty"`
+	// StatusActions override the handling of detailed merge statuses with "retry" or "abort"
+	StatusActions map[string]string `json:"statusActions,omitempty"`
+	// ApprovalPassword is sent along approvals on instances that require re-authentication to approve
+	ApprovalPassword string `json:"approvalPassword,omitempty"`
+	// ProjectPermissions restrict per project path what the tool does: "both", "approve", "merge" or "none"
+	ProjectPermissions map[string]string `json:"projectPermissions,omitempty"`
+}
+
+// ColumnConfig selects a column by its title and optionally overrides its width
+type ColumnConfig struct {
+	Title string `json:"title"`
+	Width int    `json:"width,omitempty"`
+}
+
+// ConfigEnv selects a different configuration file (e.g. one mounted into a container)
+const ConfigEnv = "GITLAB_UTIL_CONFIG"
+
+// ConfigPath returns the path of the configuration file, ConfigEnv overrides ~/.gitlab-util/config.json
+func ConfigPath() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".gitlab-util", "config.json"), nil
+}
+
+// LoadConfig reads the configuration file, the defaults are returned if it does not exist or there is no home
+// directory (e.g. in a ci job)
+func LoadConfig() (Config, error) {
+	var config Config
+	path, err := ConfigPath()
+	if err != nil {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
@@ -0,0 +1,64 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+)
+
+// Conflicts are the files changed by both the merge request and its target branch since the merge request branched
+// off, the candidates for the conflicts gitlab reports. The api doesn't expose the conflicts themselves.
+type Conflicts struct {
+	Files []string
+	// TargetCommits is the number of commits on the target branch since the merge base
+	TargetCommits int
+	// Generated is set if all files are lockfiles or other generated files, which a rebase regenerating them (e.g.
+	// by renovate) resolves
+	Generated bool
+}
+
+// ConflictDetails compares the changes of the merge request with the changes on its target branch since the merge
+// base and returns the files changed on both sides
+func (m *MergeRequestManager) ConflictDetails(ctx context.Context, id int) (Conflicts, error) {
+	if m.gl.Repositories == nil {
+		return Conflicts{}, errors.New("the client has no repositories api")
+	}
+	cached, err := m.GetMergeRequest(id)
+	if err != nil {
+		return Conflicts{}, err
+	}
+	mr, _, err := m.gl.MergeRequests.GetMergeRequest(cached.ProjectID, cached.IID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Conflicts{}, err
+	}
+	if mr.DiffRefs.BaseSha == "" {
+		return Conflicts{}, errors.New("gitlab didn't compute the merge base yet")
+	}
+	compare, _, err := m.gl.Repositories.Compare(mr.ProjectID, &gitlab.CompareOptions{
+		From: gitlab.Ptr(mr.DiffRefs.BaseSha),
+		To:   gitlab.Ptr(mr.TargetBranch),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Conflicts{}, err
+	}
+	var targetFiles []string
+	for _, d := range compare.Diffs {
+		targetFiles = append(targetFiles, d.OldPath, d.NewPath)
+	}
+	diff, err := m.PullDiff(ctx, id)
+	if err != nil {
+		return Conflicts{}, err
+	}
+	c := Conflicts{TargetCommits: len(compare.Commits)}
+	for _, d := range diff {
+		for _, file := range []string{d.OldPath, d.NewPath} {
+			if slices.Contains(targetFiles, file) && !slices.Contains(c.Files, file) {
+				c.Files = append(c.Files, file)
+			}
+		}
+	}
+	patterns := m.GeneratedPatterns(ctx, mr.ProjectID)
+	c.Generated = len(c.Files) > 0 && !slices.ContainsFunc(c.Files, func(file string) bool { return !IsGenerated(file, patterns) })
+	return c, nil
+}
@@ -0,0 +1,44 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"reflect"
+	"testing"
+)
+
+func TestConflictDetails(t *testing.T) {
+	lockfile := "@@ -1 +1 @@\n-lockfileVersion: 2\n+lockfileVersion: 3\n"
+	tests := []struct {
+		name     string
+		id       int
+		scenario func(s *fakegitlab.Scenario)
+		want     ggl.Conflicts
+	}{
+		{"changed on both sides", 202, func(s *fakegitlab.Scenario) {}, ggl.Conflicts{Files: []string{"package.json"}, TargetCommits: 1}},
+		{"target unchanged", 201, func(s *fakegitlab.Scenario) {}, ggl.Conflicts{}},
+		{"lockfile", 202, func(s *fakegitlab.Scenario) {
+			s.Diffs[202] = append(s.Diffs[202], fakegitlab.Diff("package-lock.json", lockfile)...)
+			s.TargetDiffs[2] = []*gitlab.Diff{{OldPath: "package-lock.json", NewPath: "package-lock.json", Diff: lockfile}}
+		}, ggl.Conflicts{Files: []string{"package-lock.json"}, TargetCommits: 1, Generated: true}},
+		{"generated by gitattributes", 202, func(s *fakegitlab.Scenario) {
+			s.Files = map[int]map[string]string{2: {".gitattributes": "package.json linguist-generated\n"}}
+		}, ggl.Conflicts{Files: []string{"package.json"}, TargetCommits: 1, Generated: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.Mixed()
+			tt.scenario(&scenario)
+			h := newHarness(t, scenario)
+			conflicts, err := h.Manager.ConflictDetails(context.Background(), tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(conflicts, tt.want) {
+				t.Errorf("conflicts are %+v, want %+v", conflicts, tt.want)
+			}
+		})
+	}
+}
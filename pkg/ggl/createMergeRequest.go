@@ -0,0 +1,87 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+)
+
+// CreateMergeRequestOptions describes a merge request to open for the current branch of the local repository
+type CreateMergeRequestOptions struct {
+	Remote       string
+	TargetBranch string
+	Title        string
+	Description  string
+	Labels       []string
+	Reviewers    []string
+}
+
+// CreateMergeRequestFromLocal pushes the current branch if needed and opens a merge request for it in the
+// project the remote points to
+func (m *MergeRequestManager) CreateMergeRequestFromLocal(ctx context.Context, opts CreateMergeRequestOptions) (*gitlab.MergeRequest, error) {
+	host, projectPath, err := LocalProject(opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if host != m.gl.BaseURL.Hostname() {
+		return nil, fmt.Errorf("remote %s points to %s but logged in to %s", opts.Remote, host, m.gl.BaseURL.Hostname())
+	}
+	branch, err := CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	project, _, err := m.gl.Projects.GetProject(projectPath, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	targetBranch := opts.TargetBranch
+	if targetBranch == "" {
+		targetBranch = project.DefaultBranch
+	}
+	if branch == targetBranch {
+		return nil, fmt.Errorf("current branch %s is the target branch", branch)
+	}
+	reviewerIDs, err := m.userIDs(ctx, opts.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = PushIfNeeded(opts.Remote, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	createOpts := &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr(opts.Title),
+		Description:  gitlab.Ptr(opts.Description),
+		SourceBranch: gitlab.Ptr(branch),
+		TargetBranch: gitlab.Ptr(targetBranch),
+	}
+	if len(opts.Labels) > 0 {
+		createOpts.Labels = gitlab.Ptr(gitlab.LabelOptions(opts.Labels))
+	}
+	if len(reviewerIDs) > 0 {
+		createOpts.ReviewerIDs = &reviewerIDs
+	}
+	mr, _, err := m.gl.MergeRequests.CreateMergeRequest(project.ID, createOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return mr, m.storeFetched(mrKey(mr.ID), mr)
+}
+
+// userIDs resolves usernames to user ids
+func (m *MergeRequestManager) userIDs(ctx context.Context, usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		users, _, err := m.gl.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,132 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"time"
+)
+
+// dashboardSetting is the setting the last fetched dashboard is stored in, so it can be shown before refreshing
+const dashboardSetting = "dashboard"
+
+// dashboardPageSize is the number of items fetched per dashboard section, the most recently updated first
+const dashboardPageSize = 50
+
+// Dashboard is the work of the current user across all projects
+type Dashboard struct {
+	Username        string
+	FetchedAt       time.Time
+	Issues          []DashboardItem
+	ReviewRequests  []DashboardItem
+	MergeRequests   []DashboardItem
+	FailedPipelines []DashboardItem
+}
+
+// DashboardItem is an issue, merge request or pipeline shown in the dashboard
+type DashboardItem struct {
+	Reference string
+	Title     string
+	WebURL    string
+	Info      string
+	UpdatedAt time.Time
+}
+
+// CachedDashboard returns the dashboard stored by the last FetchDashboard, it is empty if there was none
+func (m *MergeRequestManager) CachedDashboard() (Dashboard, error) {
+	var d Dashboard
+	err := m.LoadSetting(dashboardSetting, &d)
+	return d, err
+}
+
+// FetchDashboard fetches the open issues assigned to the current user, the merge requests awaiting their review,
+// their own open merge requests and the failed head pipelines of those and stores the result for CachedDashboard
+func (m *MergeRequestManager) FetchDashboard(ctx context.Context) (Dashboard, error) {
+	if m.gl.Issues == nil {
+		return Dashboard{}, errors.New("the client has no issues api")
+	}
+	user, _, err := m.gl.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return Dashboard{}, err
+	}
+	d := Dashboard{Username: user.Username, FetchedAt: m.clock.Now()}
+	listOptions := gitlab.ListOptions{Page: 1, PerPage: dashboardPageSize}
+
+	issues, _, err := m.gl.Issues.ListIssues(&gitlab.ListIssuesOptions{
+		ListOptions: listOptions,
+		State:       gitlab.Ptr("opened"),
+		Scope:       gitlab.Ptr("assigned_to_me"),
+		OrderBy:     gitlab.Ptr("updated_at"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Dashboard{}, err
+	}
+	for _, i := range issues {
+		item := DashboardItem{Reference: fullReference(i.References), Title: i.Title, WebURL: i.WebURL,
+			UpdatedAt: updatedAt(i.UpdatedAt)}
+		if i.Milestone != nil {
+			item.Info = i.Milestone.Title
+		}
+		d.Issues = append(d.Issues, item)
+	}
+
+	reviews, _, err := m.gl.MergeRequests.ListMergeRequests(&gitlab.ListMergeRequestsOptions{
+		ListOptions: listOptions,
+		State:       gitlab.Ptr("opened"),
+		Scope:       gitlab.Ptr("all"),
+		ReviewerID:  gitlab.ReviewerID(user.ID),
+		OrderBy:     gitlab.Ptr("updated_at"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Dashboard{}, err
+	}
+	for _, mr := range reviews {
+		d.ReviewRequests = append(d.ReviewRequests, mergeRequestItem(mr, mr.Author.Username))
+	}
+
+	own, _, err := m.gl.MergeRequests.ListMergeRequests(&gitlab.ListMergeRequestsOptions{
+		ListOptions: listOptions,
+		State:       gitlab.Ptr("opened"),
+		Scope:       gitlab.Ptr("created_by_me"),
+		OrderBy:     gitlab.Ptr("updated_at"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Dashboard{}, err
+	}
+	for _, mr := range own {
+		d.MergeRequests = append(d.MergeRequests, mergeRequestItem(mr, mr.DetailedMergeStatus))
+		// the list doesn't contain the head pipeline
+		full, _, err := m.gl.MergeRequests.GetMergeRequest(mr.ProjectID, mr.IID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			return Dashboard{}, err
+		}
+		if p := full.HeadPipeline; p != nil && p.Status == "failed" {
+			d.FailedPipelines = append(d.FailedPipelines, DashboardItem{Reference: fullReference(mr.References), Title: mr.Title,
+				WebURL: p.WebURL, Info: "pipeline " + p.Ref, UpdatedAt: updatedAt(p.UpdatedAt, mr.UpdatedAt)})
+		}
+	}
+
+	return d, m.StoreSetting(dashboardSetting, d)
+}
+
+func mergeRequestItem(mr *gitlab.MergeRequest, info string) DashboardItem {
+	return DashboardItem{Reference: fullReference(mr.References), Title: mr.Title, WebURL: mr.WebURL, Info: info,
+		UpdatedAt: updatedAt(mr.UpdatedAt)}
+}
+
+func fullReference(r *gitlab.IssueReferences) string {
+	if r == nil {
+		return ""
+	}
+	return r.Full
+}
+
+// updatedAt is the first of the timestamps that is set
+func updatedAt(ts ...*time.Time) time.Time {
+	for _, t := range ts {
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,127 @@
+package ggl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pidFile records the process holding the database lock
+const pidFile = "gitlab-util.pid"
+
+// dataVolume is the volume of the container image, the databases are kept there instead of the temp directory if it
+// is mounted writable
+const dataVolume = "/data"
+
+// DatabaseLockedError is returned when another process has the database open
+type DatabaseLockedError struct {
+	Path string
+	// PID is the process holding the lock, 0 if unknown
+	PID int
+	Err error
+}
+
+func (e *DatabaseLockedError) Error() string {
+	holder := "another process"
+	if e.PID != 0 {
+		holder = "another gitlab-util (pid " + strconv.Itoa(e.PID) + ")"
+	}
+	return fmt.Sprintf("database %s is in use by %s, stop it or use --db-path to open a different database", e.Path, holder)
+}
+
+func (e *DatabaseLockedError) Unwrap() error {
+	return e.Err
+}
+
+// GetDefaultDb opens the database set with WithDbPath or the one of the default gitlab instance, the lock has to be
+// closed after the database
+func GetDefaultDb(opts ...Option) (*pebble.DB, *pebble.Lock, error) {
+	inst := configure(opts).instance
+	if inst.dbPath != "" {
+		return OpenDb(inst.dbPath)
+	}
+	url, err := inst.defaultURL()
+	if err != nil {
+		return nil, nil, err
+	}
+	return GetDb(url)
+}
+
+// db opens the database set with WithDbPath or the one of the gitlab instance at url
+func (i instance) db(url string) (*pebble.DB, *pebble.Lock, error) {
+	if i.dbPath != "" {
+		return OpenDb(i.dbPath)
+	}
+	return GetDb(url)
+}
+
+// GetDb opens the database of the gitlab instance at url, every host has its own database so ids and timestamps of
+// different instances never mix. The databases are in /data if it is writable and in the temp directory otherwise.
+// The lock has to be closed after the database.
+func GetDb(urlStr string) (*pebble.DB, *pebble.Lock, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, nil, fmt.Errorf("no host in gitlab url %q", urlStr)
+	}
+	return OpenDb(path.Join(dbBaseDir(), "merge-request-manager", u.Hostname()))
+}
+
+// dbBaseDir is the data volume if it exists and can be written to, otherwise the temp directory
+func dbBaseDir() string {
+	info, err := os.Stat(dataVolume)
+	if err != nil || !info.IsDir() {
+		return os.TempDir()
+	}
+	f, err := os.CreateTemp(dataVolume, ".gitlab-util-*")
+	if err != nil {
+		return os.TempDir()
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return dataVolume
+}
+
+// OpenDb opens the database in dir, a DatabaseLockedError tells which process already has it open. The lock is held
+// until it is closed, which must not happen before the database is closed.
+func OpenDb(dir string) (*pebble.DB, *pebble.Lock, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, nil, err
+	}
+	lock, err := pebble.LockDirectory(dir, vfs.Default)
+	if err != nil {
+		return nil, nil, &DatabaseLockedError{Path: dir, PID: readPID(dir), Err: err}
+	}
+	db, err := pebble.Open(dir, &pebble.Options{Lock: lock})
+	if err != nil {
+		return nil, nil, errors.Join(err, lock.Close())
+	}
+	err = os.WriteFile(filepath.Join(dir, pidFile), []byte(strconv.Itoa(os.Getpid())), 0600)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("writing pid file: %w", err), db.Close(), lock.Close())
+	}
+	return db, lock, nil
+}
+
+// readPID reads the pid of the last process that opened the database in dir
+func readPID(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, pidFile))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,62 @@
+package ggl_test
+
+import (
+	"errors"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"os"
+	"testing"
+)
+
+func TestOpenDbLock(t *testing.T) {
+	dir := t.TempDir()
+	db, lock, err := ggl.OpenDb(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locked *ggl.DatabaseLockedError
+	if _, _, err := ggl.OpenDb(dir); !errors.As(err, &locked) || locked.PID != os.Getpid() {
+		t.Fatalf("opening the open database returned %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the database is released with its lock only
+	if _, _, err := ggl.OpenDb(dir); !errors.As(err, &locked) {
+		t.Fatalf("opening the closed database before releasing the lock returned %v", err)
+	}
+	if err := lock.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, lock, err = ggl.OpenDb(dir)
+	if err != nil {
+		t.Fatalf("opening the released database: %v", err)
+	}
+	_ = db.Close()
+	_ = lock.Close()
+}
+
+func TestCloseReleasesDbLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("CI_JOB_TOKEN", "")
+	s := fakegitlab.New(fakegitlab.RenovateBump())
+	t.Cleanup(s.Close)
+	if err := ggl.Login("stored-token", s.URL, false); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	m, err := ggl.NewDefaultMergeRequestManager(ggl.WithGitLabURL(s.URL), ggl.WithDbPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, lock, err := ggl.OpenDb(dir)
+	if err != nil {
+		t.Fatalf("opening the database of the closed manager: %v", err)
+	}
+	_ = db.Close()
+	_ = lock.Close()
+}
@@ -0,0 +1,20 @@
+package ggl
+
+import (
+	"regexp"
+	"strings"
+)
+
+// dependencyTitle matches the titles renovate gives its merge requests, e.g. "Update module golang.org/x/net to
+// v0.36.0", "Update golang Docker tag to v1.22" or "chore(deps): update dependency vite to v5.1.0 [SECURITY]"
+var dependencyTitle = regexp.MustCompile(`(?i)\bupdate (?:module |dependency |image |helm release |plugin )?(\S+)(?: docker tag| image)?(?: to (v?\d\S*))?`)
+
+// Dependency extracts the dependency and the version it is updated to from the title of a renovate merge request,
+// the version is empty if the title doesn't name one
+func Dependency(title string) (name string, version string, ok bool) {
+	match := dependencyTitle.FindStringSubmatch(title)
+	if match == nil || strings.EqualFold(match[1], "all") || strings.EqualFold(match[1], "dependencies") {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
@@ -0,0 +1,63 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/cockroachdb/pebble"
+	"github.com/xanzy/go-gitlab"
+	"strconv"
+)
+
+// PullDiff returns the diff of the merge request. It is served from the database as long as the head sha of the
+// stored merge request did not change, a new push invalidates the diffs stored for older heads.
+func (m *MergeRequestManager) PullDiff(ctx context.Context, id int) ([]*gitlab.MergeRequestDiff, error) {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if mr.SHA != "" {
+		diff, err := load[[]*gitlab.MergeRequestDiff](m.db, diffKey(id, mr.SHA))
+		if err == nil {
+			return diff, nil
+		}
+		if !errors.Is(err, pebble.ErrNotFound) {
+			m.logger.Warn("error loading cached diff", "mr", id, "err", err)
+		}
+	}
+	diff, _, err := m.gl.MergeRequests.ListMergeRequestDiffs(mr.ProjectID, mr.IID, &gitlab.ListMergeRequestDiffsOptions{
+		Unidiff: gitlab.Ptr(true),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if mr.SHA != "" {
+		err = m.deleteDiffs(id)
+		if err == nil {
+			err = store(m.db, diffKey(id, mr.SHA), diff)
+		}
+		if err != nil {
+			m.logger.Warn("error caching diff", "mr", id, "err", err)
+		}
+	}
+	return diff, nil
+}
+
+// IsDiffCached reports whether the diff of the merge request can be served without calling gitlab
+func (m *MergeRequestManager) IsDiffCached(id int) bool {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil || mr.SHA == "" {
+		return false
+	}
+	_, closer, err := m.db.Get([]byte(diffKey(id, mr.SHA)))
+	if err != nil {
+		return false
+	}
+	_ = closer.Close()
+	return true
+}
+
+// deleteDiffs deletes the diffs stored for all heads of the merge request
+func (m *MergeRequestManager) deleteDiffs(id int) error {
+	prefix := []byte(diffPrefix + strconv.Itoa(id) + "-")
+	return m.db.DeleteRange(prefix, keyUpperBound(prefix), pebble.Sync)
+}
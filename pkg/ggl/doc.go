@@ -0,0 +1,14 @@
+// Package ggl contains the gitlab access of gitlab-util: login and token storage, a local cache of merge requests
+// and projects and the auto-merge engine.
+//
+// The engine is embeddable in other programs:
+//
+//	mrm, err := ggl.NewMergeRequestManager(ggl.WithGitLab(client), ggl.WithDB(db), ggl.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	events := mrm.Author("renovate-bot").Start(ctx).Subscribe(ctx)
+//
+// Merge targets are enabled with ApproveAndMergeMergeRequest and processed in the background until they are merged
+// or aborted, every step is reported as Event to the subscribers.
+package ggl
@@ -0,0 +1,46 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// greenStatuses are the merge statuses of merge requests EnableAllMatching enables
+var greenStatuses = []string{"mergeable", "ci_still_running"}
+
+// EnableAllMatching enables approve and merge for every cached merge request that is mergeable or waits for its
+// pipeline, without reviewing the diffs. Drafts, merge requests with a hold marker or an active target, excluded
+// authors and observe-only projects are skipped. The targets are picked up by the processor or ProcessOnce, the number of enabled targets is returned.
+func (m *MergeRequestManager) EnableAllMatching(ctx context.Context) (int, error) {
+	mrs, err := m.GetMergeRequests()
+	if err != nil {
+		return 0, err
+	}
+	enabled := 0
+	var errs error
+	for _, mr := range mrs {
+		if mr.Target.Active || mr.Draft || HoldMarker(&mr.MergeRequest) != "" || !slices.Contains(greenStatuses, mr.DetailedMergeStatus) {
+			continue
+		}
+		if approve, merge := m.projectAllows(mr.ProjectID); (!approve && !merge) || m.Excluded(&mr.MergeRequest) {
+			continue
+		}
+		diff, err := m.PullDiff(ctx, mr.ID)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		target := m.newTarget(&mr.MergeRequest, diff, false)
+		err = store(m.db, targetKey(target.Id), target)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		enabled++
+		m.emit(Event{Type: EventEnabled, TargetID: target.Id})
+	}
+	m.wake()
+	m.logger.Info("enabled all matching merge requests", "enabled", enabled)
+	return enabled, errs
+}
@@ -0,0 +1,53 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/sync/errgroup"
+)
+
+// enrichWorkers is the number of concurrent requests used to enrich fetched merge requests
+const enrichWorkers = 8
+
+// enrichMergeRequests fetches the approvals of the merge requests and the projects and labels they reference with a
+// bounded pool of workers. All workers share the rate limiter of the gitlab client. Failures are only logged, so a
+// single broken project does not stop the refresh.
+func (m *MergeRequestManager) enrichMergeRequests(ctx context.Context, mrs []*gitlab.MergeRequest) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(enrichWorkers)
+
+	projects := make(map[int]bool)
+	labelProjects := make(map[int]bool)
+	for _, mr := range mrs {
+		projects[mr.ProjectID] = true
+		if len(mr.Labels) > 0 {
+			labelProjects[mr.ProjectID] = true
+		}
+	}
+
+	for pid := range projects {
+		g.Go(func() error {
+			if err := m.ensureProject(ctx, pid); err != nil {
+				m.logger.Warn("error fetching project", "project", pid, "err", err)
+			}
+			return ctx.Err()
+		})
+	}
+	for pid := range labelProjects {
+		g.Go(func() error {
+			if err := m.fetchLabels(ctx, pid); err != nil {
+				m.logger.Warn("error fetching labels", "project", pid, "err", err)
+			}
+			return ctx.Err()
+		})
+	}
+	for _, mr := range mrs {
+		g.Go(func() error {
+			if err := m.fetchApprovals(ctx, mr); err != nil {
+				m.logger.Warn("error fetching approvals", "mr", mr.ID, "err", err)
+			}
+			return ctx.Err()
+		})
+	}
+	return g.Wait()
+}
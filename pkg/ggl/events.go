@@ -0,0 +1,87 @@
+package ggl
+
+import (
+	"context"
+	"time"
+)
+
+// EventType is the kind of change reported to subscribers
+type EventType string
+
+const (
+	EventFetched     EventType = "fetched"
+	EventEnabled     EventType = "enabled"
+	EventApproved    EventType = "approved"
+	EventMerged      EventType = "merged"
+	EventAborted     EventType = "aborted"
+	EventDelegated   EventType = "delegated"
+	EventRescheduled EventType = "rescheduled"
+	EventCleared     EventType = "cleared"
+	EventUpdated     EventType = "updated"
+	EventProgress    EventType = "progress"
+	EventStalled     EventType = "stalled"
+	EventQuarantined EventType = "quarantined"
+)
+
+// Event describes a change of the cached merge requests or of a merge target
+type Event struct {
+	Type     EventType
+	TargetID int
+	Outcome  MergeOutcome
+	Time     time.Time
+	// Progress is set on EventProgress
+	Progress *Progress
+	// Info describes what happened on EventStalled
+	Info string
+}
+
+// Subscribe returns a channel receiving all events until ctx is done.
+// Events are dropped for subscribers that do not keep up, processing never blocks on them.
+func (m *MergeRequestManager) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, 64)
+	m.subscribersMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subscribersMu.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.subscribersMu.Lock()
+		delete(m.subscribers, ch)
+		close(ch)
+		m.subscribersMu.Unlock()
+	}()
+	return ch
+}
+
+func (m *MergeRequestManager) emit(e Event) {
+	e.Time = m.clock.Now()
+	m.subscribersMu.Lock()
+	defer m.subscribersMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// outcomeEvent maps the outcome of a processing step to the event reported to subscribers
+func outcomeEvent(targetID int, outcome MergeOutcome) Event {
+	e := Event{TargetID: targetID, Outcome: outcome}
+	switch outcome.State {
+	case OutcomeApproved:
+		e.Type = EventApproved
+	case OutcomeMerged:
+		e.Type = EventMerged
+	case OutcomeAborted:
+		e.Type = EventAborted
+	case OutcomeCleared:
+		e.Type = EventCleared
+	case OutcomeDelegated:
+		e.Type = EventDelegated
+	case OutcomeQuarantined:
+		e.Type = EventQuarantined
+	default:
+		e.Type = EventRescheduled
+	}
+	return e
+}
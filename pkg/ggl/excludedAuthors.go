@@ -0,0 +1,15 @@
+package ggl
+
+import (
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+)
+
+// errExcludedAuthor is returned when acting on merge requests of excluded authors
+var errExcludedAuthor = errors.New("author is excluded from auto-processing")
+
+// Excluded reports whether the merge request is authored by an excluded author, it is shown but never processed
+func (m *MergeRequestManager) Excluded(mr *gitlab.MergeRequest) bool {
+	return mr.Author != nil && slices.Contains(m.excludedAuthors, mr.Author.Username)
+}
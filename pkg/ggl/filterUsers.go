@@ -0,0 +1,72 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"strings"
+)
+
+// maxUserSuggestions is the number of similar usernames suggested for an unknown user
+const maxUserSuggestions = 3
+
+// CheckFilterUsers checks that the author, reviewer and assignee of the filter exist, a filter with an unknown user
+// never matches a merge request. The error suggests the usernames that were probably meant. If gitlab can't be
+// asked the check is skipped with a warning, so the cached merge requests can still be used offline.
+func (m *MergeRequestManager) CheckFilterUsers(ctx context.Context) error {
+	for _, f := range []struct {
+		flag     string
+		username *string
+	}{{"author", m.AuthorUsername}, {"reviewer", m.ReviewerUsername}, {"assignee", m.AssigneeUsername}} {
+		if f.username == nil {
+			continue
+		}
+		username := *f.username
+		if f.flag == "assignee" && (strings.EqualFold(username, "none") || strings.EqualFold(username, "any")) {
+			continue
+		}
+		found, suggestions, err := m.findUser(ctx, username)
+		if err != nil {
+			m.logger.Warn("error checking the user of the filter", "user", username, "err", err)
+			return nil
+		}
+		if found != "" {
+			if found != username {
+				m.logger.Warn("the "+f.flag+" differs in case from the username", f.flag, username, "username", found)
+			}
+			continue
+		}
+		if len(suggestions) == 0 {
+			return fmt.Errorf("%s %s not found on gitlab", f.flag, username)
+		}
+		return fmt.Errorf("%s %s not found on gitlab, did you mean %s?", f.flag, username, strings.Join(suggestions, ", "))
+	}
+	return nil
+}
+
+// findUser returns the username of the user, gitlab ignores the case of usernames. If there is no such user it
+// returns the usernames of similar users instead.
+func (m *MergeRequestManager) findUser(ctx context.Context, username string) (string, []string, error) {
+	users, _, err := m.gl.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Username, username) {
+			return u.Username, nil, nil
+		}
+	}
+	// the search also matches names and parts of usernames, e.g. without the @ prefix
+	similar, _, err := m.gl.Users.ListUsers(&gitlab.ListUsersOptions{
+		ListOptions: gitlab.ListOptions{PerPage: maxUserSuggestions},
+		Search:      gitlab.Ptr(strings.TrimPrefix(username, "@")),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	var suggestions []string
+	for _, u := range similar {
+		suggestions = append(suggestions, u.Username)
+	}
+	return "", suggestions, nil
+}
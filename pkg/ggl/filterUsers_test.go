@@ -0,0 +1,36 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"testing"
+)
+
+func TestCheckFilterUsers(t *testing.T) {
+	tests := []struct {
+		author   string
+		reviewer string
+		assignee string
+		err      string
+	}{
+		{author: "renovate-bot"},
+		{author: "Renovate-Bot", reviewer: "BOB"},
+		{author: "renovate-bot", assignee: "None"},
+		{reviewer: "@bob", err: "reviewer @bob not found on gitlab, did you mean bob?"},
+		{author: "renovate", err: "author renovate not found on gitlab, did you mean renovate-bot?"},
+		{author: "renovate-bot", assignee: "carol", err: "assignee carol not found on gitlab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.author+"-"+tt.reviewer+"-"+tt.assignee, func(t *testing.T) {
+			h := newHarness(t, fakegitlab.Mixed())
+			m := h.Manager.Author(tt.author).Reviewer(tt.reviewer).Assignee(tt.assignee)
+			err := m.CheckFilterUsers(context.Background())
+			if tt.err == "" && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Errorf("error is %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
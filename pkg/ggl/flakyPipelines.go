@@ -0,0 +1,78 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"strings"
+	"time"
+)
+
+// flakyPipelineOutcome retries the failed head pipeline of the merge request if only jobs known to be flaky failed.
+// The retries are counted from the retried jobs of the pipeline, a new push starts over. Once the flaky jobs failed
+// on every retry the target gives up.
+func (m *MergeRequestManager) flakyPipelineOutcome(ctx context.Context, target mergeTarget, mr *gitlab.MergeRequest) (MergeOutcome, bool) {
+	if m.flakyJobs == nil || mr.HeadPipeline == nil || mr.HeadPipeline.Status != "failed" || m.gl.Pipelines == nil ||
+		m.gl.Jobs == nil {
+		return MergeOutcome{}, false
+	}
+	pipeline := mr.HeadPipeline
+	jobs, err := m.pipelineJobs(ctx, pipeline.ProjectID, pipeline.ID)
+	if err != nil {
+		m.logger.Error("error listing pipeline jobs", "target", target.Id, "err", err)
+		return errorOutcome("listing pipeline jobs", err), true
+	}
+	// the latest attempt of a job decides, the earlier ones count the retries
+	latest := make(map[string]*gitlab.Job)
+	attempts := make(map[string]int)
+	for _, j := range jobs {
+		attempts[j.Name]++
+		if l, ok := latest[j.Name]; !ok || j.ID > l.ID {
+			latest[j.Name] = j
+		}
+	}
+	var failed []string
+	retries := 0
+	for _, j := range jobs {
+		if latest[j.Name] != j || j.Status != "failed" || j.AllowFailure {
+			continue
+		}
+		if !m.flakyJobs.MatchString(j.Name) {
+			// a real failure, the merge request waits for a fix
+			return MergeOutcome{}, false
+		}
+		failed = append(failed, j.Name)
+		retries = max(retries, attempts[j.Name]-1)
+	}
+	if len(failed) == 0 {
+		return MergeOutcome{}, false
+	}
+	if retries >= m.flakyRetries {
+		m.logger.Info("flaky jobs failed on every retry", "target", target.Id, "jobs", failed, "retries", retries)
+		return abortOutcome(fmt.Sprintf("pipeline failed, flaky jobs %s failed %d retries", strings.Join(failed, ", "), retries)), true
+	}
+	_, _, err = m.gl.Pipelines.RetryPipelineBuild(pipeline.ProjectID, pipeline.ID, gitlab.WithContext(ctx))
+	if err != nil {
+		m.logger.Error("error retrying pipeline", "target", target.Id, "pipeline", pipeline.ID, "err", err)
+		return errorOutcome("retrying pipeline", err), true
+	}
+	m.logger.Info("retried pipeline with flaky jobs", "target", target.Id, "pipeline", pipeline.ID, "jobs", failed)
+	return retryOutcome(fmt.Sprintf("retried pipeline %d/%d, flaky jobs %s failed", retries+1, m.flakyRetries, strings.Join(failed, ", ")), 1*time.Minute), true
+}
+
+// pipelineJobs lists all jobs of the pipeline including the retried ones
+func (m *MergeRequestManager) pipelineJobs(ctx context.Context, projectID int, pipelineID int) ([]*gitlab.Job, error) {
+	opt := &gitlab.ListJobsOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}, IncludeRetried: gitlab.Ptr(true)}
+	var jobs []*gitlab.Job
+	for {
+		page, resp, err := m.gl.Jobs.ListPipelineJobs(projectID, pipelineID, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, page...)
+		if resp == nil || resp.NextPage == 0 {
+			return jobs, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,104 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+// flakyScenario has merge request 101 with a failed pipeline 500, e2e is the flaky job
+func flakyScenario(jobs ...*gitlab.Job) fakegitlab.Scenario {
+	p := fakegitlab.Project(1, "service")
+	mr := fakegitlab.MergeRequest(101, p, 7, "Update module golang.org/x/net to v0.36.0", "renovate-bot", "ci_must_pass")
+	mr.HeadPipeline = &gitlab.Pipeline{ID: 500, ProjectID: p.ID, Status: "failed"}
+	return fakegitlab.Scenario{
+		Projects:      []*gitlab.Project{p},
+		MergeRequests: []*gitlab.MergeRequest{mr},
+		Diffs:         map[int][]*gitlab.MergeRequestDiff{mr.ID: fakegitlab.Diff("go.mod", "@@ -1 +1 @@\n-golang.org/x/net v0.35.0\n+golang.org/x/net v0.36.0\n")},
+		Jobs:          map[int][]*gitlab.Job{500: jobs},
+	}
+}
+
+func TestFlakyPipelineRetried(t *testing.T) {
+	const retry = "POST /api/v4/projects/1/pipelines/500/retry"
+	tests := []struct {
+		name    string
+		jobs    []*gitlab.Job
+		rerun   string
+		info    []string
+		retries int
+	}{
+		{"flaky job passes on retry", []*gitlab.Job{fakegitlab.Job(1, "build", "success"), fakegitlab.Job(2, "e2e", "failed")}, "success", []string{
+			"retried pipeline 1/2, flaky jobs e2e failed - will check again in 1 minute",
+			"merged",
+		}, 1},
+		{"flaky job fails every retry", []*gitlab.Job{fakegitlab.Job(1, "build", "success"), fakegitlab.Job(2, "e2e", "failed")}, "failed", []string{
+			"retried pipeline 1/2, flaky jobs e2e failed - will check again in 1 minute",
+			"retried pipeline 2/2, flaky jobs e2e failed - will check again in 1 minute",
+			"aborted - pipeline failed, flaky jobs e2e failed 2 retries",
+		}, 2},
+		{"real failure", []*gitlab.Job{fakegitlab.Job(1, "build", "failed"), fakegitlab.Job(2, "e2e", "failed")}, "", []string{
+			"status ci_must_pass - will check again in 1 minute",
+		}, 0},
+		{"allowed failure", []*gitlab.Job{fakegitlab.Job(1, "build", "success"), {ID: 2, Name: "lint", Status: "failed", AllowFailure: true}}, "", []string{
+			"status ci_must_pass - will check again in 1 minute",
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := flakyScenario(tt.jobs...)
+			clock := fakegitlab.NewClock(time.Now())
+			h := newHarness(t, scenario, ggl.WithFlakyJobs(regexp.MustCompile(`^e2e$`), 2), ggl.WithClock(clock))
+			enable(t, h.Manager, 101)
+
+			var info []string
+			for range tt.info {
+				target := processOnce(t, h.Manager, 101)
+				info = append(info, target.Info)
+				if !target.Active {
+					break
+				}
+				if tt.rerun != "" {
+					h.Server.FinishPipeline(500, tt.rerun)
+				}
+				clock.Advance(time.Minute)
+			}
+			if !slices.Equal(info, tt.info) {
+				t.Errorf("info is %q, want %q", info, tt.info)
+			}
+			retries := 0
+			for _, call := range h.Server.Calls() {
+				if call == retry {
+					retries++
+				}
+			}
+			if retries != tt.retries {
+				t.Errorf("retried the pipeline %d times, want %d", retries, tt.retries)
+			}
+		})
+	}
+}
+
+func TestFlakyPipelineWithoutJobsApi(t *testing.T) {
+	h := newHarness(t, flakyScenario(fakegitlab.Job(1, "e2e", "failed")))
+	gl, err := h.Server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ggl.WrapClient(gl)
+	client.Jobs = nil
+	m := secondInstance(t, h, ggl.WithClient(client),
+		ggl.WithFlakyJobs(regexp.MustCompile(`^e2e$`), 2), ggl.WithClock(fakegitlab.NewClock(time.Now())))
+	if err := m.Author("renovate-bot").FetchMergeRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	enable(t, m, 101)
+	if target := processOnce(t, m, 101); target.Info != "status ci_must_pass - will check again in 1 minute" {
+		t.Errorf("info without jobs api is %q", target.Info)
+	}
+}
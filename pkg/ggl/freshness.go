@@ -0,0 +1,46 @@
+package ggl
+
+import (
+	"github.com/cockroachdb/pebble"
+	"time"
+)
+
+// storeFetched stores a merge request or project record that was just fetched from gitlab together with the time
+func (m *MergeRequestManager) storeFetched(key string, v any) error {
+	b := m.db.NewBatch()
+	defer b.Close()
+	err := storeFetchedBatch(b, key, v, m.clock.Now())
+	if err != nil {
+		return err
+	}
+	return b.Commit(pebble.Sync)
+}
+
+// storeFetchedBatch adds the record and the time it was fetched to the batch
+func storeFetchedBatch(b *pebble.Batch, key string, v any, fetchedAt time.Time) error {
+	err := storeBatch(b, key, v)
+	if err != nil {
+		return err
+	}
+	return storeBatch(b, fetchedKey(key), fetchedAt)
+}
+
+// deleteFetchedBatch adds the deletion of the record and the time it was fetched to the batch
+func deleteFetchedBatch(b *pebble.Batch, key []byte) error {
+	err := b.Delete(key, nil)
+	if err != nil {
+		return err
+	}
+	return b.Delete([]byte(fetchedKey(string(key))), nil)
+}
+
+// fetchedAt is when the record under key was last fetched, zero for records cached before the times were kept
+func (m *MergeRequestManager) fetchedAt(key string) time.Time {
+	t, _ := load[time.Time](m.db, fetchedKey(key))
+	return t
+}
+
+// ProjectFetchedAt is when the cached project was last fetched from gitlab, zero if unknown
+func (m *MergeRequestManager) ProjectFetchedAt(id int) time.Time {
+	return m.fetchedAt(projectKey(id))
+}
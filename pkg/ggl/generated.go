@@ -0,0 +1,90 @@
+package ggl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generatedFiles are lockfiles and other generated files that are usually not reviewed line by line
+var generatedFiles = []string{
+	"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "go.sum", "Cargo.lock", "poetry.lock",
+	"Pipfile.lock", "Gemfile.lock", "composer.lock", "gradle.lockfile", "flake.lock", "*.min.js", "*.pb.go", "zz_generated*",
+}
+
+// GeneratedPatterns returns the file patterns considered generated for the project: the common lockfiles plus the
+// patterns marked linguist-generated or -diff in the .gitattributes of the default branch, which is cached for an hour
+func (m *MergeRequestManager) GeneratedPatterns(ctx context.Context, projectID int) []string {
+	patterns, err := m.gitattributesPatterns(ctx, projectID)
+	if err != nil {
+		m.logger.Debug("no .gitattributes patterns", "project", projectID, "err", err)
+	}
+	return append(slices.Clone(generatedFiles), patterns...)
+}
+
+func (m *MergeRequestManager) gitattributesPatterns(ctx context.Context, projectID int) ([]string, error) {
+	timestampId := "last-fetch-gitattributes-" + strconv.Itoa(projectID)
+	lastFetch, err := m.GetTimeStamp(timestampId)
+	if err != nil {
+		return nil, err
+	}
+	if m.clock.Now().Sub(lastFetch) < 60*time.Minute {
+		return load[[]string](m.db, gitattributesKey(projectID))
+	}
+	var patterns []string
+	data, resp, err := m.gl.RepositoryFiles.GetRawFile(projectID, ".gitattributes", &gitlab.GetRawFileOptions{}, gitlab.WithContext(ctx))
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return nil, err
+	}
+	if err == nil {
+		patterns = parseGeneratedAttributes(data)
+	}
+	err = store(m.db, gitattributesKey(projectID), patterns)
+	if err != nil {
+		return nil, err
+	}
+	return patterns, m.setTimeStamp(timestampId, m.clock.Now())
+}
+
+// parseGeneratedAttributes returns the patterns of the .gitattributes lines marking files as generated or not diffable
+func parseGeneratedAttributes(data []byte) []string {
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "linguist-generated" || attr == "linguist-generated=true" || attr == "-diff" || attr == "binary" {
+				patterns = append(patterns, strings.TrimPrefix(fields[0], "/"))
+				break
+			}
+		}
+	}
+	return patterns
+}
+
+// IsGenerated reports whether the file matches one of the patterns, patterns without a slash match the file name
+// in any directory
+func IsGenerated(file string, patterns []string) bool {
+	for _, p := range patterns {
+		name := file
+		if !strings.Contains(p, "/") {
+			name = path.Base(file)
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if strings.HasSuffix(p, "/**") && strings.HasPrefix(file, strings.TrimSuffix(p, "**")) {
+			return true
+		}
+	}
+	return false
+}
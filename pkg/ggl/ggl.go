@@ -1,15 +1,43 @@
 package ggl
 
 import (
+	"errors"
+	"fmt"
 	"github.com/xanzy/go-gitlab"
+	"io/fs"
 	"log/slog"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// Login to gitlab and store the token
-func Login(token string, url string) error {
+// instance selects the gitlab instance and database of the default client and database, and unlocks the tokens
+// stored by Login. It is set up with WithGitLabURL, WithDbPath, WithPassphraseFunc and WithInstancePicker.
+type instance struct {
+	// url overrides the last logged-in url, dbPath the per host database directory
+	url    string
+	dbPath string
+	// passphrase asks for the passphrase of encrypted tokens if PassphraseEnv isn't set, nil fails instead
+	passphrase func() ([]byte, error)
+	// pickInstance selects one of several instances with a stored token, nil fails listing them instead
+	pickInstance func(instances []string) (string, error)
+}
+
+// configure applies the options to a manager that only provides the instance and api stats to the functions
+// working without a MergeRequestManager, like Login and the token functions
+func configure(opts []Option) *MergeRequestManager {
+	m := &MergeRequestManager{apiStats: NewAPIStats(http.DefaultTransport)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Login to gitlab and store the token, encrypted with the passphrase if encrypt is set. The passphrase is read from
+// PassphraseEnv or asked for with the function set by WithPassphraseFunc.
+func Login(token string, url string, encrypt bool, opts ...Option) error {
 	git, err := gitlab.NewClient(token, gitlab.WithBaseURL(url))
 	if err != nil {
 		return err
@@ -23,61 +51,131 @@ func Login(token string, url string) error {
 	}
 
 	slog.Info("successfull login", "url", url, "project_approx", r.ItemsPerPage*(r.TotalPages))
-	err = storeToken(token, url)
+	inst := configure(opts).instance
+	if encrypt {
+		err = storeEncryptedToken(token, url, inst.readPassphrase)
+	} else {
+		err = storeToken(token, url, inst.readPassphrase)
+	}
+	if err != nil {
+		return err
+	}
+	err = storeInstanceURL(url)
 	if err != nil {
 		return err
 	}
 	return storeLastLoggedInDomain(url)
 }
 
-func GetClient(url string) (*gitlab.Client, error) {
-	token, err := readToken(url)
+// GetClient creates a client for url with the token from GITLAB_TOKEN, CI_JOB_TOKEN or the one stored by Login
+func GetClient(url string, opts ...Option) (*gitlab.Client, error) {
+	m := configure(opts)
+	return m.instance.client(url, m.apiStats)
+}
+
+// GetDefaultClient creates a client for the default instance, see DefaultURL
+func GetDefaultClient(opts ...Option) (*gitlab.Client, error) {
+	m := configure(opts)
+	url, err := m.instance.defaultURL()
 	if err != nil {
 		return nil, err
 	}
 
-	return gitlab.NewClient(token, gitlab.WithBaseURL(url))
+	return m.instance.client(url, m.apiStats)
 }
 
-func GetDefaultClient() (*gitlab.Client, error) {
-	url, err := readLastLoggedInDomain()
+// DefaultURL is the url set with WithGitLabURL, the api url of the gitlab ci job or the instance with a stored token.
+// If there are tokens for several instances the one chosen by the WithInstancePicker function is used, the last
+// logged-in one is used if there are none.
+func DefaultURL(opts ...Option) (string, error) {
+	return configure(opts).instance.defaultURL()
+}
+
+func (i instance) defaultURL() (string, error) {
+	if i.url != "" {
+		return i.url, nil
+	}
+	if url := os.Getenv("CI_API_V4_URL"); url != "" {
+		return url, nil
+	}
+	instances, err := Instances()
+	if err != nil {
+		return "", err
+	}
+	switch len(instances) {
+	case 0:
+		return readLastLoggedInDomain()
+	case 1:
+		return instances[0], nil
+	}
+	if i.pickInstance == nil {
+		return "", fmt.Errorf("tokens for several gitlab instances are stored, select one with --gitlab-url: %s",
+			strings.Join(instances, ", "))
+	}
+	return i.pickInstance(instances)
+}
+
+// client creates a client for url counting its calls in stats
+func (i instance) client(url string, transport http.RoundTripper) (*gitlab.Client, error) {
+	options := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(url), gitlab.WithHTTPClient(&http.Client{Transport: transport})}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		return gitlab.NewClient(token, options...)
+	}
+	if token := os.Getenv("CI_JOB_TOKEN"); token != "" {
+		return gitlab.NewJobClient(token, options...)
+	}
+
+	token, err := i.readToken(url)
 	if err != nil {
 		return nil, err
 	}
 
-	return GetClient(url)
+	return gitlab.NewClient(token, options...)
 }
 
-func readToken(url string) (string, error) {
+func (i instance) readToken(url string) (string, error) {
 	if url == "" {
 		url, err := readLastLoggedInDomain()
 		if err != nil {
 			return "", err
 		}
-		return readTokenForUrl(url)
+		return readTokenForUrl(url, i.readPassphrase)
 	}
-	return readTokenForUrl(url)
+	return readTokenForUrl(url, i.readPassphrase)
 }
 
-// storeToken stores the token in a file in the user's home directory per domain
-func storeToken(token string, urlStr string) error {
+// tokenDir is the directory in the user's home directory holding the token of the domain
+func tokenDir(urlStr string) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".gitlab-util", u.Hostname()), nil
+}
+
+// storeToken stores the token in a file in the user's home directory per domain, a token that is already stored
+// encrypted stays encrypted
+func storeToken(token string, urlStr string, passphrase func() ([]byte, error)) error {
+	dir, err := tokenDir(urlStr)
 	if err != nil {
 		return err
 	}
+	if hasEncryptedToken(dir) {
+		return storeEncryptedToken(token, urlStr, passphrase)
+	}
 
-	tokenDir := filepath.Join(homeDir, ".gitlab-util", u.Hostname())
-	err = os.MkdirAll(tokenDir, 0700)
+	err = os.MkdirAll(dir, 0700)
 	if err != nil {
 		return err
 	}
 
-	tokenFile := filepath.Join(tokenDir, "token")
+	tokenFile := filepath.Join(dir, "token")
 	err = os.WriteFile(tokenFile, []byte(token), 0600)
 	if err != nil {
 		return err
@@ -86,19 +184,41 @@ func storeToken(token string, urlStr string) error {
 	return nil
 }
 
-// readTokenForUrl reads the token from a file in the user's home directory per domain
-func readTokenForUrl(urlStr string) (string, error) {
-	homeDir, err := os.UserHomeDir()
+// storeEncryptedToken stores the token encrypted with the passphrase and removes a plaintext token of the domain
+func storeEncryptedToken(token string, urlStr string, passphrase func() ([]byte, error)) error {
+	dir, err := tokenDir(urlStr)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	u, err := url.Parse(urlStr)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	err = writeEncryptedToken(dir, token, passphrase)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, "token"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// readTokenForUrl reads the token from a file in the user's home directory per domain
+func readTokenForUrl(urlStr string, passphrase func() ([]byte, error)) (string, error) {
+	dir, err := tokenDir(urlStr)
 	if err != nil {
 		return "", err
 	}
+	if hasEncryptedToken(dir) {
+		return readEncryptedToken(dir, passphrase)
+	}
 
-	tokenFile := filepath.Join(homeDir, ".gitlab-util", u.Hostname(), "token")
+	tokenFile := filepath.Join(dir, "token")
 	tokenBytes, err := os.ReadFile(tokenFile)
 	if err != nil {
 		return "", err
@@ -0,0 +1,98 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"testing"
+	"time"
+)
+
+// newHarness starts a fake gitlab with the scenario and fetches the merge requests of renovate-bot
+func newHarness(t *testing.T, scenario fakegitlab.Scenario, opts ...ggl.Option) *fakegitlab.Harness {
+	t.Helper()
+	h, err := fakegitlab.NewHarness(scenario, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = h.Close()
+	})
+	h.Manager.Author("renovate-bot")
+	if err := h.Manager.FetchMergeRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// mergeRequestInfo returns the cached merge request with the id
+func mergeRequestInfo(t *testing.T, m *ggl.MergeRequestManager, id int) ggl.MergeRequestInfo {
+	t.Helper()
+	mrs, err := m.GetMergeRequests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mr := range mrs {
+		if mr.ID == id {
+			return mr
+		}
+	}
+	t.Fatalf("merge request %d is not cached", id)
+	return ggl.MergeRequestInfo{}
+}
+
+// pullDiff returns the current diff of the merge request
+func pullDiff(t *testing.T, m *ggl.MergeRequestManager, id int) []*gitlab.MergeRequestDiff {
+	t.Helper()
+	diff, err := m.PullDiff(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diff
+}
+
+// enable approves and merges the merge request once it is mergeable, like confirming it in the ui
+func enable(t *testing.T, m *ggl.MergeRequestManager, id int) {
+	t.Helper()
+	if err := m.ApproveAndMergeMergeRequest(context.Background(), id, pullDiff(t, m, id)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// processOnce processes the due targets once and returns the merge target of the merge request afterwards
+func processOnce(t *testing.T, m *ggl.MergeRequestManager, id int) ggl.TargetStatus {
+	t.Helper()
+	if _, err := m.ProcessOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	target, err := m.Target(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+// settle waits until the loops started by Start wait for the clock again, which are the given number of timers and
+// tickers
+func settle(t *testing.T, clock *fakegitlab.Clock, waiters int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() != waiters {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d waiters, want %d", clock.Waiters(), waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// start runs the background processing of the manager until the test ends or the returned func is called
+func start(t *testing.T, m *ggl.MergeRequestManager) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		m.Wait()
+	})
+	m.Start(ctx)
+	return cancel
+}
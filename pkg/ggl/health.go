@@ -0,0 +1,179 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cockroachdb/pebble"
+	"github.com/xanzy/go-gitlab"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeatInterval is how often the idle processor reports that it is alive
+const heartbeatInterval = 30 * time.Second
+
+// restartDelay is the pause before a panicked or stalled loop is started again
+const restartDelay = 5 * time.Second
+
+// stallTimeout is how long the processor or enqueuer may go without heartbeat before they count as stalled
+const stallTimeout = 5 * time.Minute
+
+// stopTimeout is how long a stalled loop gets to return after its context got cancelled before it is restarted anyway
+const stopTimeout = 30 * time.Second
+
+// heartbeat is the time a background loop last reported to be alive
+type heartbeat struct {
+	nanos atomic.Int64
+}
+
+func (h *heartbeat) beat(t time.Time) {
+	h.nanos.Store(t.UnixNano())
+}
+
+func (h *heartbeat) last() time.Time {
+	n := h.nanos.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// Health is the state of the manager reported by the health endpoints of the daemon
+type Health struct {
+	ProcessorHeartbeat time.Time `json:"processorHeartbeat"`
+	EnqueuerHeartbeat  time.Time `json:"enqueuerHeartbeat"`
+	// QueueDepth is the number of targets waiting for the processor
+	QueueDepth int `json:"queueDepth"`
+	// Leader holds the lease with a leader lock, Standby is set while it is another instance
+	Leader  string `json:"leader,omitempty"`
+	Standby bool   `json:"standby,omitempty"`
+	// Problems are the reasons the manager is not live or ready, empty if it is
+	Problems []string `json:"problems,omitempty"`
+}
+
+// OK reports whether no problems were found
+func (h Health) OK() bool {
+	return len(h.Problems) == 0
+}
+
+// Liveness checks that the processor and enqueuer started by Start are running and had a heartbeat lately
+func (m *MergeRequestManager) Liveness() Health {
+	h := Health{
+		ProcessorHeartbeat: m.processorBeat.last(),
+		EnqueuerHeartbeat:  m.enqueuerBeat.last(),
+		QueueDepth:         m.QueueDepth(),
+		Leader:             m.Leader(),
+		Standby:            !m.Leading(),
+	}
+	now := m.clock.Now()
+	for _, loop := range []struct {
+		name string
+		beat time.Time
+	}{{"processor", h.ProcessorHeartbeat}, {"enqueuer", h.EnqueuerHeartbeat}} {
+		switch {
+		case loop.beat.IsZero():
+			h.Problems = append(h.Problems, loop.name+" not started")
+		case now.Sub(loop.beat) > stallTimeout:
+			h.Problems = append(h.Problems, fmt.Sprintf("%s stalled, last heartbeat %s ago", loop.name, now.Sub(loop.beat).Round(time.Second)))
+		}
+	}
+	return h
+}
+
+// Readiness checks the liveness and additionally that the database is readable and gitlab is reachable
+func (m *MergeRequestManager) Readiness(ctx context.Context) Health {
+	h := m.Liveness()
+	_, closer, err := m.db.Get([]byte("ts-" + m.mergeRequestsTimestampId()))
+	if err == nil {
+		err = closer.Close()
+	}
+	if err != nil && !errors.Is(err, pebble.ErrNotFound) {
+		h.Problems = append(h.Problems, "database: "+err.Error())
+	}
+	if m.gl.Version != nil {
+		_, _, err = m.gl.Version.GetVersion(gitlab.WithContext(ctx))
+		if err != nil {
+			h.Problems = append(h.Problems, "gitlab: "+err.Error())
+		}
+	}
+	return h
+}
+
+// supervise runs the loop until ctx is done and restarts it if it panics or has no heartbeat for stallTimeout. A
+// stalled loop gets its context cancelled, which aborts the api call or channel operation it is blocked in, and is
+// restarted once it returned or after stopTimeout. A loop that is still stuck keeps its targets claimed and queued,
+// so the restarted one doesn't process them at the same time. The heartbeats are stored in the database, so they are
+// available after a crash.
+func (m *MergeRequestManager) supervise(ctx context.Context, name string, beat *heartbeat, loop func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		beat.beat(m.clock.Now())
+		loopCtx, cancel := context.WithCancel(ctx)
+		done := make(chan string, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					m.logger.Error(name+" panicked", "panic", r, "stack", string(debug.Stack()))
+					done <- fmt.Sprintf("panicked: %v", r)
+					return
+				}
+				done <- "stopped"
+			}()
+			loop(loopCtx)
+		}()
+		problem, running := m.watch(ctx, name, beat, done)
+		cancel()
+		if running && !m.stopped(done) {
+			m.logger.Warn(name+" did not stop", "timeout", stopTimeout)
+		}
+		if problem == "" {
+			return
+		}
+		m.logger.Warn("restarting "+name, "problem", problem)
+		m.emit(Event{Type: EventStalled, Info: fmt.Sprintf("%s %s, restarting it", name, problem)})
+		if !m.sleep(ctx, restartDelay) {
+			return
+		}
+	}
+}
+
+// watch waits until the loop ends or stalls and returns the problem, empty if it stopped because ctx is done. The
+// loop is still running if it stalled.
+func (m *MergeRequestManager) watch(ctx context.Context, name string, beat *heartbeat, done <-chan string) (string, bool) {
+	ticker := m.clock.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case problem := <-done:
+			if ctx.Err() != nil {
+				return "", false
+			}
+			return problem, false
+		case <-ctx.Done():
+			// the loop finishes its current step first
+			<-done
+			return "", false
+		case <-ticker.C():
+		}
+		last := beat.last()
+		if err := m.setTimeStamp("heartbeat-"+name, last); err != nil {
+			m.logger.Error("error storing heartbeat", "loop", name, "err", err)
+		}
+		if since := m.clock.Now().Sub(last); since > stallTimeout {
+			return fmt.Sprintf("stalled, no heartbeat for %s", since.Round(time.Second)), true
+		}
+	}
+}
+
+// stopped waits up to stopTimeout for the cancelled loop to return
+func (m *MergeRequestManager) stopped(done <-chan string) bool {
+	timer := m.clock.NewTimer(stopTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C():
+		return false
+	}
+}
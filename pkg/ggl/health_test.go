@@ -0,0 +1,129 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	h := newHarness(t, scenario, ggl.WithClock(fakegitlab.NewClock(time.Now())))
+	m := h.Manager
+
+	if problems := m.Liveness().Problems; !slices.Equal(problems, []string{"processor not started", "enqueuer not started"}) {
+		t.Errorf("problems before start are %q", problems)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		m.Wait()
+	})
+	m.Start(ctx)
+	deadline := time.Now().Add(time.Second)
+	for !m.Liveness().OK() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if health := m.Readiness(ctx); !health.OK() || health.Standby {
+		t.Fatalf("not ready after start: %+v", health)
+	}
+
+	// a revoked token, server errors are retried by the client
+	h.Server.FailWith("version", 1, http.StatusUnauthorized)
+	health := m.Readiness(ctx)
+	if len(health.Problems) != 1 || !strings.HasPrefix(health.Problems[0], "gitlab: ") {
+		t.Errorf("problems with gitlab failing are %q", health.Problems)
+	}
+	if !m.Liveness().OK() {
+		t.Error("not live with gitlab failing")
+	}
+	if health := m.Readiness(ctx); !health.OK() {
+		t.Errorf("not ready once gitlab answers again: %q", health.Problems)
+	}
+}
+
+// stuckMerge blocks the first merge until released, ignoring the cancellation of its context like a wedged call
+type stuckMerge struct {
+	ggl.MergeRequestsService
+	release  chan struct{}
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	overlaps atomic.Int32
+}
+
+func (s *stuckMerge) AcceptMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.AcceptMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	if s.inFlight.Add(1) > 1 {
+		s.overlaps.Add(1)
+	}
+	defer s.inFlight.Add(-1)
+	if s.calls.Add(1) == 1 {
+		<-s.release
+	}
+	return s.MergeRequestsService.AcceptMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+func TestRestartDoesNotProcessTwice(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	scenario.Statuses = nil
+	scenario.MergeRequests[0].DetailedMergeStatus = "mergeable"
+	h := newHarness(t, scenario)
+	s := h.Server
+	clock := fakegitlab.NewClock(time.Now())
+	gl, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ggl.WrapClient(gl)
+	stuck := &stuckMerge{MergeRequestsService: client.MergeRequests, release: make(chan struct{})}
+	client.MergeRequests = stuck
+	m := secondInstance(t, h, ggl.WithClient(client), ggl.WithClock(clock))
+	if err := m.Author("renovate-bot").FetchMergeRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	enable(t, m, 101)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := m.Subscribe(ctx)
+	start(t, m)
+	defer cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for stuck.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// the processor stalls in the merge, is cancelled and restarted after the stop timeout
+	restarted := false
+	for range 60 {
+		clock.Advance(30 * time.Second)
+		time.Sleep(time.Millisecond)
+		for len(events) > 0 {
+			restarted = restarted || (<-events).Type == ggl.EventStalled
+		}
+	}
+	if !restarted {
+		t.Fatal("the stuck processor wasn't restarted")
+	}
+	if calls := stuck.calls.Load(); calls != 1 {
+		t.Errorf("merged %d times while the stuck merge was running", calls)
+	}
+
+	close(stuck.release)
+	deadline = time.Now().Add(5 * time.Second)
+	for s.MergeRequest(101).State != "merged" && time.Now().Before(deadline) {
+		clock.Advance(30 * time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if state := s.MergeRequest(101).State; state != "merged" {
+		t.Errorf("state after the stuck merge returned is %s", state)
+	}
+	if overlaps := stuck.overlaps.Load(); overlaps > 0 {
+		t.Errorf("merged %d times concurrently", overlaps)
+	}
+}
@@ -0,0 +1,39 @@
+package ggl
+
+import (
+	"github.com/xanzy/go-gitlab"
+	"strings"
+)
+
+// holdMarkers are the conventional title prefixes and labels of merge requests that must not be merged even though
+// they are not drafts, compared after normalizeMarker
+var holdMarkers = []string{"do not merge", "dont merge", "on hold", "hold", "wip"}
+
+// HoldMarker returns the title prefix or label that marks the merge request as not to be merged, empty if there is
+// none. "DO NOT MERGE" is also found anywhere in the title.
+func HoldMarker(mr *gitlab.MergeRequest) string {
+	for _, label := range mr.Labels {
+		for _, marker := range holdMarkers {
+			if normalizeMarker(label) == marker {
+				return label
+			}
+		}
+	}
+	title := normalizeMarker(mr.Title)
+	for _, marker := range holdMarkers {
+		rest, ok := strings.CutPrefix(strings.TrimLeft(title, "[( "), marker)
+		if ok && (rest == "" || strings.ContainsAny(rest[:1], " :])")) {
+			return marker
+		}
+	}
+	if strings.Contains(title, "do not merge") || strings.Contains(title, "dont merge") {
+		return "do not merge"
+	}
+	return ""
+}
+
+// normalizeMarker lowercases s and treats dashes and underscores as spaces, e.g. DO_NOT_MERGE, do-not-merge and
+// Don't merge are all matched
+func normalizeMarker(s string) string {
+	return strings.NewReplacer("-", " ", "_", " ", "'", "").Replace(strings.ToLower(s))
+}
@@ -0,0 +1,62 @@
+package ggl
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// instanceURLFile keeps the url used at login next to the token of the domain
+const instanceURLFile = "url"
+
+// Instances lists the urls of the gitlab instances with a stored token, sorted by host
+func Instances() ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(homeDir, ".gitlab-util"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lastLogin, _ := readLastLoggedInDomain()
+	var instances []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(homeDir, ".gitlab-util", e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "token")); err != nil && !hasEncryptedToken(dir) {
+			continue
+		}
+		instances = append(instances, instanceURL(dir, e.Name(), lastLogin))
+	}
+	return instances, nil
+}
+
+// instanceURL is the url stored at login, tokens stored by older versions fall back to the last logged-in url of
+// the same host or https
+func instanceURL(dir string, host string, lastLogin string) string {
+	if data, err := os.ReadFile(filepath.Join(dir, instanceURLFile)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if u, err := url.Parse(lastLogin); err == nil && u.Hostname() == host {
+		return lastLogin
+	}
+	return "https://" + host
+}
+
+// storeInstanceURL keeps the url next to the token of its domain
+func storeInstanceURL(urlStr string) error {
+	dir, err := tokenDir(urlStr)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, instanceURLFile), []byte(urlStr), 0600)
+}
@@ -0,0 +1,26 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"strings"
+)
+
+// mentionsJiraKey reports whether the title or description of the merge request already contains the key
+func mentionsJiraKey(mr *gitlab.MergeRequest, key string) bool {
+	return strings.Contains(mr.Title, key) || strings.Contains(mr.Description, key)
+}
+
+// addJiraKey appends the jira key to the description of the merge request, so that gitlab associates it with the
+// issue
+func (m *MergeRequestManager) addJiraKey(ctx context.Context, mr *gitlab.MergeRequest) error {
+	description := strings.TrimRight(mr.Description, "\n") + "\n\nJira: " + m.jiraKey
+	updated, _, err := m.gl.MergeRequests.UpdateMergeRequest(mr.ProjectID, mr.IID, &gitlab.UpdateMergeRequestOptions{
+		Description: gitlab.Ptr(strings.TrimLeft(description, "\n")),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("added jira key", "mr", mr.ID, "key", m.jiraKey)
+	return m.storeFetched(mrKey(updated.ID), updated)
+}
@@ -0,0 +1,38 @@
+package ggl_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJiraKeyAddedOnce(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	scenario.MergeRequests[0].DetailedMergeStatus = "jira_association_missing"
+	scenario.MergeRequests[0].Description = "This MR contains the following updates."
+	scenario.Statuses = nil
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario, ggl.WithJiraKey("DEPS-123"), ggl.WithClock(clock))
+	enable(t, h.Manager, 101)
+
+	target := processOnce(t, h.Manager, 101)
+	if !strings.HasPrefix(target.Info, "added jira key DEPS-123") {
+		t.Errorf("target is %q, want the jira key added", target.Info)
+	}
+	want := "This MR contains the following updates.\n\nJira: DEPS-123"
+	if description := h.Server.MergeRequest(101).Description; description != want {
+		t.Errorf("description is %q, want %q", description, want)
+	}
+
+	// gitlab takes a while to pick up the association, the key is not added again meanwhile
+	clock.Advance(time.Minute)
+	target = processOnce(t, h.Manager, 101)
+	if !strings.HasPrefix(target.Info, "status jira_association_missing") {
+		t.Errorf("target is %q, want waiting for the association", target.Info)
+	}
+	if description := h.Server.MergeRequest(101).Description; description != want {
+		t.Errorf("description is %q after the second check, want %q", description, want)
+	}
+}
@@ -0,0 +1,56 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+)
+
+// Label is a merge request label with the colors configured in gitlab
+type Label struct {
+	Name      string
+	Color     string
+	TextColor string
+}
+
+// fetchLabels fetches and stores the labels of the project
+func (m *MergeRequestManager) fetchLabels(ctx context.Context, projectID int) error {
+	opt := &gitlab.ListLabelsOptions{
+		ListOptions:           gitlab.ListOptions{PerPage: 100, Page: 1},
+		IncludeAncestorGroups: gitlab.Ptr(true),
+	}
+	var labels []Label
+	for {
+		page, resp, err := m.gl.Labels.ListLabels(projectID, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		for _, l := range page {
+			labels = append(labels, Label{Name: l.Name, Color: l.Color, TextColor: l.TextColor})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return store(m.db, labelsKey(projectID), labels)
+}
+
+// mergeRequestLabels resolves the label names of the merge request to the stored project labels,
+// labels without known colors are returned by name only
+func (m *MergeRequestManager) mergeRequestLabels(mr gitlab.MergeRequest) []Label {
+	if len(mr.Labels) == 0 {
+		return nil
+	}
+	known, _ := load[[]Label](m.db, labelsKey(mr.ProjectID))
+	labels := make([]Label, len(mr.Labels))
+	for i, name := range mr.Labels {
+		labels[i] = Label{Name: name}
+		for _, l := range known {
+			if l.Name == name {
+				labels[i] = l
+				break
+			}
+		}
+	}
+	return labels
+}
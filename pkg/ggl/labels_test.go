@@ -0,0 +1,28 @@
+package ggl_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"slices"
+	"testing"
+)
+
+func TestMergeRequestLabelColors(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	// a label of the merge request that the project doesn't list, e.g. one deleted since
+	scenario.MergeRequests[1].Labels = append(scenario.MergeRequests[1].Labels, "removed")
+	h := newHarness(t, scenario)
+
+	got := mergeRequestInfo(t, h.Manager, 202).LabelDetails
+	want := []ggl.Label{
+		{Name: "dependencies", Color: "#428BCA", TextColor: "#FFFFFF"},
+		{Name: "frontend", Color: "#6699CC", TextColor: "#FFFFFF"},
+		{Name: "removed"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("labels are %+v, want %+v", got, want)
+	}
+	if got := mergeRequestInfo(t, h.Manager, 201).LabelDetails; !slices.Equal(got, want[:1]) {
+		t.Errorf("labels of the other project are %+v, want %+v", got, want[:1])
+	}
+}
@@ -0,0 +1,203 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// leaderLabel is the project label holding the lease of the instance that approves and merges. Creating a label that
+// exists fails, so only one of the instances racing for a missing lease gets it. An expired lease is taken over by
+// writing the label description and reading it back after leaseSettle, the instance whose write came last leads.
+const leaderLabel = "gitlab-util-leader"
+
+// leaseSettle is how long an instance taking over the lease waits before reading it back, longer than an api round
+// trip so that the writes of the instances that saw the same expired lease have landed
+const leaseSettle = 10 * time.Second
+
+// ReasonStandingBy is the reason of targets that were not approved or merged because the lease got lost while they
+// were processed
+const ReasonStandingBy = "standing by, another instance approves and merges"
+
+// leaderLease is how long a lease is valid, the leader renews it three times per lease
+const leaderLease = 2 * time.Minute
+
+// leaderLock is the lease of the instances using the same leader lock project
+type leaderLock struct {
+	project string
+	holder  string
+	// until is the end of the own lease in unix nanos, 0 while standing by
+	until atomic.Int64
+	// current is the holder of the lease as last seen
+	current atomic.Pointer[string]
+}
+
+// leaseDescription is the description of the leader label
+func leaseDescription(holder string, until time.Time) string {
+	return "gitlab-util leader " + holder + " until " + until.UTC().Format(time.RFC3339)
+}
+
+// parseLease reads the holder and the end of the lease from the description of the leader label, a description that
+// isn't a lease is treated as expired
+func parseLease(description string) (string, time.Time) {
+	lease, ok := strings.CutPrefix(description, "gitlab-util leader ")
+	if !ok {
+		return "", time.Time{}
+	}
+	holder, until, ok := strings.Cut(lease, " until ")
+	if !ok {
+		return "", time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return holder, t
+}
+
+// Leading reports whether this instance approves and merges, always true without leader lock
+func (m *MergeRequestManager) Leading() bool {
+	if m.leader == nil {
+		return true
+	}
+	return m.clock.Now().UnixNano() < m.leader.until.Load()
+}
+
+// Leader is the holder of the lease as last seen, empty without leader lock
+func (m *MergeRequestManager) Leader() string {
+	if m.leader == nil {
+		return ""
+	}
+	if current := m.leader.current.Load(); current != nil {
+		return *current
+	}
+	return ""
+}
+
+// leaderElection takes or renews the lease until ctx is done and releases it then, so a standby instance takes over
+// right away
+func (m *MergeRequestManager) leaderElection(ctx context.Context) {
+	m.logger.Debug("starting leader election", "project", m.leader.project, "holder", m.leader.holder)
+	var leading *bool
+	for {
+		err := m.renewLease(ctx)
+		if err != nil && ctx.Err() == nil {
+			m.logger.Error("error renewing leader lease", "err", err)
+		}
+		if leads := m.Leading(); leading == nil || *leading != leads {
+			if leads {
+				m.logger.Info("leading, approving and merging", "holder", m.leader.holder)
+			} else {
+				m.logger.Warn("standing by, another instance approves and merges", "leader", m.Leader())
+			}
+			leading = &leads
+		}
+		if !m.sleep(ctx, leaderLease/3) {
+			break
+		}
+	}
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.releaseLease(releaseCtx); err != nil {
+		m.logger.Error("error releasing leader lease", "err", err)
+	}
+}
+
+// renewLease extends the own lease or takes an expired one. On errors the own lease stays valid until it expires.
+func (m *MergeRequestManager) renewLease(ctx context.Context) error {
+	l := m.leader
+	now := m.clock.Now()
+	label, resp, err := m.gl.Labels.GetLabel(l.project, leaderLabel, gitlab.WithContext(ctx))
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return m.createLease(ctx, now)
+	}
+	if err != nil {
+		return err
+	}
+	holder, until := parseLease(label.Description)
+	if holder != l.holder && now.Before(until) {
+		l.until.Store(0)
+		l.current.Store(&holder)
+		return nil
+	}
+	if holder != l.holder {
+		m.logger.Info("taking over expired leader lease", "leader", holder, "expired", until)
+	}
+	return m.writeLease(ctx, now, holder != l.holder || !now.Before(until))
+}
+
+// writeLease writes the own lease into the description of the leader label and reads it back. Labels can't be
+// updated conditionally, so an instance taking over the lease stands by for leaseSettle before reading it back: of
+// the instances that saw the lease expired only the one whose write came last reads back its own lease and leads.
+func (m *MergeRequestManager) writeLease(ctx context.Context, now time.Time, takeover bool) error {
+	l := m.leader
+	description := leaseDescription(l.holder, now.Add(leaderLease))
+	_, _, err := m.gl.Labels.UpdateLabel(l.project, &gitlab.UpdateLabelOptions{Name: gitlab.Ptr(leaderLabel), Description: &description}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if takeover {
+		l.until.Store(0)
+		if !m.sleep(ctx, leaseSettle) {
+			return ctx.Err()
+		}
+	}
+	label, _, err := m.gl.Labels.GetLabel(l.project, leaderLabel, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	holder, _ := parseLease(label.Description)
+	l.current.Store(&holder)
+	if holder != l.holder {
+		m.logger.Info("lost the race for the leader lease", "leader", holder)
+		l.until.Store(0)
+		return nil
+	}
+	l.until.Store(now.Add(leaderLease).UnixNano())
+	return nil
+}
+
+// createLease creates the leader label, if another instance was faster this one stands by
+func (m *MergeRequestManager) createLease(ctx context.Context, now time.Time) error {
+	l := m.leader
+	description := leaseDescription(l.holder, now.Add(leaderLease))
+	_, resp, err := m.gl.Labels.CreateLabel(l.project, &gitlab.CreateLabelOptions{
+		Name:        gitlab.Ptr(leaderLabel),
+		Color:       gitlab.Ptr("#808080"),
+		Description: &description,
+	}, gitlab.WithContext(ctx))
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		l.until.Store(0)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	l.until.Store(now.Add(leaderLease).UnixNano())
+	l.current.Store(&l.holder)
+	return nil
+}
+
+// releaseLease deletes the leader label if this instance holds the lease
+func (m *MergeRequestManager) releaseLease(ctx context.Context) error {
+	l := m.leader
+	if l.until.Swap(0) == 0 {
+		return nil
+	}
+	label, _, err := m.gl.Labels.GetLabel(l.project, leaderLabel, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if holder, _ := parseLease(label.Description); holder != l.holder {
+		return nil
+	}
+	resp, err := m.gl.Labels.DeleteLabel(l.project, leaderLabel, nil, gitlab.WithContext(ctx))
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return err
+	}
+	m.logger.Info("released leader lease", "holder", l.holder)
+	return nil
+}
@@ -0,0 +1,157 @@
+package ggl_test
+
+import (
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// instanceWaiters are the timers and tickers of an idle instance with leader lock: the processor, enqueuer and
+// leader election wait for a timer, the supervisors of the processor and enqueuer for a ticker
+const instanceWaiters = 5
+
+// secondInstance creates another instance against the fake gitlab of the harness with its own database
+func secondInstance(t *testing.T, h *fakegitlab.Harness, opts ...ggl.Option) *ggl.MergeRequestManager {
+	t.Helper()
+	gl, err := h.Server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	m, err := ggl.NewMergeRequestManager(append([]ggl.Option{ggl.WithGitLab(gl), ggl.WithDB(db)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLeaderLease(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	expired := fakegitlab.Label("gitlab-util-leader", "#808080")
+	expired.Description = "gitlab-util leader crashed until " + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	scenario.Labels = map[int][]*gitlab.Label{1: {expired}}
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario, ggl.WithLeaderLock("group/service", "a"), ggl.WithClock(clock))
+	a := h.Manager
+	b := secondInstance(t, h, ggl.WithLeaderLock("group/service", "b"), ggl.WithClock(clock))
+
+	stopA := start(t, a)
+	settle(t, clock, instanceWaiters)
+	if a.Leading() {
+		t.Fatal("a leads before reading back the lease it took over")
+	}
+	clock.Advance(10 * time.Second)
+	settle(t, clock, instanceWaiters)
+	if !a.Leading() || a.Leader() != "a" {
+		t.Fatalf("a didn't take over the expired lease of %q", a.Leader())
+	}
+	if slices.ContainsFunc(h.Server.Calls(), func(call string) bool { return strings.HasPrefix(call, "DELETE ") }) {
+		t.Error("deleted the expired lease instead of taking it over")
+	}
+
+	start(t, b)
+	settle(t, clock, 2*instanceWaiters)
+	for i := range 10 {
+		if !a.Leading() || b.Leading() || b.Leader() != "a" {
+			t.Fatalf("renewal %d: a leading %t, b leading %t and sees %q as leader", i, a.Leading(), b.Leading(), b.Leader())
+		}
+		clock.Advance(40 * time.Second)
+		settle(t, clock, 2*instanceWaiters)
+	}
+
+	stopA()
+	a.Wait()
+	clock.Advance(40 * time.Second)
+	settle(t, clock, instanceWaiters)
+	if !b.Leading() || b.Leader() != "b" {
+		t.Errorf("b didn't take over the released lease, leader is %q", b.Leader())
+	}
+}
+
+func TestStandbyDoesNotApprove(t *testing.T) {
+	h := newHarness(t, fakegitlab.RenovateBump(), ggl.WithLeaderLock("group/service", "b"))
+	enable(t, h.Manager, 101)
+
+	target := processOnce(t, h.Manager, 101)
+	if target.Info != ggl.ReasonStandingBy+" - will check again in 1 minute" {
+		t.Errorf("info of a standby instance is %q", target.Info)
+	}
+	if slices.Contains(h.Server.Calls(), "POST /api/v4/projects/1/merge_requests/1/approve") {
+		t.Error("approved without holding the lease")
+	}
+}
+
+// heldLease holds the first lease write of an instance until the test lets it through
+type heldLease struct {
+	ggl.LabelsService
+	writing chan struct{}
+	write   chan struct{}
+	once    sync.Once
+}
+
+func (l *heldLease) UpdateLabel(pid interface{}, opt *gitlab.UpdateLabelOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Label, *gitlab.Response, error) {
+	l.once.Do(func() {
+		close(l.writing)
+		<-l.write
+	})
+	return l.LabelsService.UpdateLabel(pid, opt, options...)
+}
+
+// heldInstance creates an instance with leader lock whose first lease write is held
+func heldInstance(t *testing.T, h *fakegitlab.Harness, clock *fakegitlab.Clock, holder string) (*ggl.MergeRequestManager, *heldLease) {
+	t.Helper()
+	gl, err := h.Server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ggl.WrapClient(gl)
+	lease := &heldLease{LabelsService: client.Labels, writing: make(chan struct{}), write: make(chan struct{})}
+	client.Labels = lease
+	return secondInstance(t, h, ggl.WithClient(client), ggl.WithLeaderLock("group/service", holder), ggl.WithClock(clock)), lease
+}
+
+func TestLeaderLeaseRace(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	expired := fakegitlab.Label("gitlab-util-leader", "#808080")
+	expired.Description = "gitlab-util leader crashed until " + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	scenario.Labels = map[int][]*gitlab.Label{1: {expired}}
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario)
+	a, leaseA := heldInstance(t, h, clock, "a")
+	b, leaseB := heldInstance(t, h, clock, "b")
+
+	// both see the expired lease before either writes
+	start(t, a)
+	start(t, b)
+	<-leaseA.writing
+	<-leaseB.writing
+	// a writes first and waits for the clock, b writes after a could have read its own lease back
+	close(leaseA.write)
+	settle(t, clock, 2*instanceWaiters-1)
+	close(leaseB.write)
+	settle(t, clock, 2*instanceWaiters)
+	if a.Leading() && b.Leading() {
+		t.Fatal("both instances lead after writing the lease")
+	}
+
+	for i := range 10 {
+		clock.Advance(10 * time.Second)
+		settle(t, clock, 2*instanceWaiters)
+		if a.Leading() == b.Leading() || a.Leader() != "b" || b.Leader() != "b" {
+			t.Fatalf("step %d: a leading %t, b leading %t, they see %q and %q as leader", i, a.Leading(), b.Leading(), a.Leader(), b.Leader())
+		}
+	}
+}
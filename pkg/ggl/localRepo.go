@@ -0,0 +1,108 @@
+package ggl
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// git runs a git command in the current directory and returns its trimmed output
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CurrentBranch returns the branch checked out in the local repository
+func CurrentBranch() (string, error) {
+	return git("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// LastCommitMessage returns the message of the HEAD commit
+func LastCommitMessage() (string, error) {
+	return git("log", "-1", "--format=%B")
+}
+
+// LocalProject returns host and project path of the given remote of the local repository
+func LocalProject(remote string) (string, string, error) {
+	remoteURL, err := git("remote", "get-url", remote)
+	if err != nil {
+		return "", "", err
+	}
+	return ParseRemoteURL(remoteURL)
+}
+
+// ParseRemoteURL extracts host and project path from a git remote url in https, ssh or scp-like syntax
+func ParseRemoteURL(remoteURL string) (string, string, error) {
+	var host, projectPath string
+	if !strings.Contains(remoteURL, "://") {
+		// scp-like syntax: git@host:group/project.git
+		userHost, p, found := strings.Cut(remoteURL, ":")
+		if !found {
+			return "", "", fmt.Errorf("unsupported remote url %q", remoteURL)
+		}
+		host = userHost[strings.LastIndex(userHost, "@")+1:]
+		projectPath = p
+	} else {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", err
+		}
+		host = u.Hostname()
+		projectPath = u.Path
+	}
+	projectPath = strings.TrimSuffix(strings.Trim(projectPath, "/"), ".git")
+	if host == "" || projectPath == "" {
+		return "", "", fmt.Errorf("unsupported remote url %q", remoteURL)
+	}
+	return host, projectPath, nil
+}
+
+// PushIfNeeded pushes the branch to the remote if it has no upstream yet or has unpushed commits
+func PushIfNeeded(remote string, branch string) error {
+	_, err := git("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		_, err = git("push", "--set-upstream", remote, branch)
+		return err
+	}
+	ahead, err := git("rev-list", "--count", "@{u}..HEAD")
+	if err != nil {
+		return err
+	}
+	if ahead != "0" {
+		_, err = git("push", remote, branch)
+	}
+	return err
+}
+
+// CheckoutMergeRequestHead fetches the head of merge request iid from the remote into the local branch mr/<iid>
+// and checks it out. Returns the name of the local branch.
+func CheckoutMergeRequestHead(remote string, iid int) (string, error) {
+	branch := fmt.Sprintf("mr/%d", iid)
+	ref := fmt.Sprintf("refs/merge-requests/%d/head", iid)
+	current, err := CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	if current == branch {
+		_, err = git("fetch", remote, ref)
+		if err != nil {
+			return "", err
+		}
+		_, err = git("merge", "--ff-only", "FETCH_HEAD")
+		return branch, err
+	}
+	_, err = git("fetch", remote, "+"+ref+":"+branch)
+	if err != nil {
+		return "", err
+	}
+	_, err = git("checkout", branch)
+	return branch, err
+}
@@ -0,0 +1,34 @@
+package ggl
+
+import (
+	"io"
+	"log/slog"
+)
+
+// LogOptions configures level and format of the default logger
+type LogOptions struct {
+	Level     slog.Level
+	JSON      bool
+	AddSource bool
+}
+
+var logOptions LogOptions
+
+// ConfigureLogging sets up the default slog logger with the given options writing to w.
+// Output of the standard log package is routed through the same handler.
+func ConfigureLogging(opts LogOptions, w io.Writer) {
+	logOptions = opts
+	SetLogOutput(w)
+}
+
+// SetLogOutput redirects the default logger to w keeping the configured level and format
+func SetLogOutput(w io.Writer) {
+	handlerOptions := &slog.HandlerOptions{Level: logOptions.Level, AddSource: logOptions.AddSource}
+	var handler slog.Handler
+	if logOptions.JSON {
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOptions)
+	}
+	slog.SetDefault(slog.New(handler))
+}
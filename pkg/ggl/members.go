@@ -0,0 +1,83 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/cockroachdb/pebble"
+	"github.com/xanzy/go-gitlab"
+	"strconv"
+	"time"
+)
+
+// Member is a user with access to a project
+type Member struct {
+	ID       int
+	Username string
+	Name     string
+}
+
+// ProjectMembers returns the members of the project who can review (developer or above) from the cache,
+// they are fetched if the cache is older than an hour
+func (m *MergeRequestManager) ProjectMembers(ctx context.Context, projectID int) ([]Member, error) {
+	timestampId := "last-fetch-members-" + strconv.Itoa(projectID)
+	lastFetch, err := m.GetTimeStamp(timestampId)
+	if err != nil {
+		return nil, err
+	}
+	if m.clock.Now().Sub(lastFetch) < 60*time.Minute {
+		members, err := load[[]Member](m.db, membersKey(projectID))
+		if err == nil {
+			return members, nil
+		}
+		if !errors.Is(err, pebble.ErrNotFound) {
+			return nil, err
+		}
+	}
+	members, err := m.fetchMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return members, m.setTimeStamp(timestampId, m.clock.Now())
+}
+
+func (m *MergeRequestManager) fetchMembers(ctx context.Context, projectID int) ([]Member, error) {
+	opt := &gitlab.ListProjectMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}}
+	var members []Member
+	for {
+		page, resp, err := m.gl.ProjectMembers.ListAllProjectMembers(projectID, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, pm := range page {
+			if pm.State == "active" && pm.AccessLevel >= gitlab.DeveloperPermissions {
+				members = append(members, Member{ID: pm.ID, Username: pm.Username, Name: pm.Name})
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return members, store(m.db, membersKey(projectID), members)
+}
+
+// SetReviewers replaces the reviewers of the merge request
+func (m *MergeRequestManager) SetReviewers(ctx context.Context, id int, userIDs []int) error {
+	old, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	mr, _, err := m.gl.MergeRequests.UpdateMergeRequest(old.ProjectID, old.IID, &gitlab.UpdateMergeRequestOptions{
+		ReviewerIDs: &userIDs,
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("updated reviewers", "mr", id, "reviewers", userIDs)
+	err = m.storeFetched(mrKey(mr.ID), mr)
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventUpdated, TargetID: id})
+	return nil
+}
@@ -0,0 +1,48 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+	"testing"
+)
+
+func TestProjectMembersCanReview(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	guest := fakegitlab.Member(13, "carol")
+	guest.AccessLevel = gitlab.GuestPermissions
+	blocked := fakegitlab.Member(14, "dave")
+	blocked.State = "blocked"
+	scenario.Members[2] = append(scenario.Members[2], guest, blocked)
+	h := newHarness(t, scenario)
+
+	members, err := h.Manager.ProjectMembers(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ggl.Member{{ID: 11, Username: "alice", Name: "alice"}, {ID: 12, Username: "bob", Name: "bob"}}
+	if !slices.Equal(members, want) {
+		t.Errorf("members are %+v, want the active developers %+v", members, want)
+	}
+}
+
+func TestSetReviewers(t *testing.T) {
+	h := newHarness(t, fakegitlab.Mixed())
+
+	err := h.Manager.SetReviewers(context.Background(), 203, []int{12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewers := h.Server.MergeRequest(203).Reviewers; len(reviewers) != 1 || reviewers[0].Username != "bob" {
+		t.Errorf("reviewers on gitlab are %v, want bob", reviewers)
+	}
+	mr, err := h.Manager.GetMergeRequest(203)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mr.Reviewers) != 1 || mr.Reviewers[0].Username != "bob" {
+		t.Errorf("cached reviewers are %v, want bob", mr.Reviewers)
+	}
+}
@@ -0,0 +1,37 @@
+package ggl
+
+import (
+	"github.com/xanzy/go-gitlab"
+)
+
+// acceptOptions builds the merge options matching the merge method and squash setting of the project. The head sha
+// is always passed, so that gitlab refuses the merge if something was pushed after the merge request was read.
+func (m *MergeRequestManager) acceptOptions(mr *gitlab.MergeRequest) *gitlab.AcceptMergeRequestOptions {
+	opts := &gitlab.AcceptMergeRequestOptions{}
+	if mr.SHA != "" {
+		opts.SHA = gitlab.Ptr(mr.SHA)
+	}
+	p, err := m.GetProject(mr.ProjectID)
+	if err != nil {
+		return opts
+	}
+	switch p.SquashOption {
+	case gitlab.SquashOptionAlways:
+		opts.Squash = gitlab.Ptr(true)
+	case gitlab.SquashOptionNever:
+		opts.Squash = gitlab.Ptr(false)
+	default:
+		opts.Squash = gitlab.Ptr(mr.Squash)
+	}
+	return opts
+}
+
+// rebasesBeforeMerge reports whether the project only allows fast-forward or semi-linear merges, which need the
+// merge request to be rebased onto the target branch first
+func (m *MergeRequestManager) rebasesBeforeMerge(projectID int) bool {
+	p, err := m.GetProject(projectID)
+	if err != nil {
+		return false
+	}
+	return p.MergeMethod == gitlab.FastForwardMerge || p.MergeMethod == gitlab.RebaseMerge
+}
@@ -0,0 +1,98 @@
+package ggl
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// OutcomeState is the state a merge target is in after a processing step
+type OutcomeState string
+
+const (
+	OutcomeRescheduled OutcomeState = "rescheduled"
+	OutcomeApproved    OutcomeState = "approved"
+	OutcomeMerged      OutcomeState = "merged"
+	OutcomeAborted     OutcomeState = "aborted"
+	OutcomeError       OutcomeState = "error"
+	OutcomeCleared     OutcomeState = "cleared"
+	OutcomeDelegated   OutcomeState = "delegated"
+	OutcomeInactive    OutcomeState = "inactive"
+	OutcomeUnknown     OutcomeState = "unknown"
+	// OutcomeQuarantined targets panicked while being processed, they are kept until enabled again
+	OutcomeQuarantined OutcomeState = "quarantined"
+)
+
+// ReasonDiffChanged is the abort reason for merge requests whose diff changed after it was reviewed
+const ReasonDiffChanged = "diff changed"
+
+// MergeOutcome is the machine-readable result of processing a merge target
+type MergeOutcome struct {
+	State      OutcomeState
+	Reason     string
+	RetryAfter time.Duration
+	Error      string `json:",omitempty"`
+	// ErrorClass decides how errors back off, see errorClass
+	ErrorClass string `json:",omitempty"`
+}
+
+func retryOutcome(reason string, retryAfter time.Duration) MergeOutcome {
+	return MergeOutcome{State: OutcomeRescheduled, Reason: reason, RetryAfter: retryAfter}
+}
+
+// errorOutcome is the outcome of a failed action, processMerge sets the delay from the class and earlier errors
+func errorOutcome(action string, err error) MergeOutcome {
+	return MergeOutcome{State: OutcomeError, Reason: action, Error: err.Error(), ErrorClass: errorClass(err)}
+}
+
+func abortOutcome(reason string) MergeOutcome {
+	return MergeOutcome{State: OutcomeAborted, Reason: reason}
+}
+
+// Finished reports whether processing of the target stops with this outcome
+func (o MergeOutcome) Finished() bool {
+	return o.State == OutcomeMerged || o.State == OutcomeAborted || o.State == OutcomeCleared || o.State == OutcomeDelegated ||
+		o.State == OutcomeQuarantined || o.NeedsHuman()
+}
+
+// NeedsHuman reports whether the outcome is an error that retrying won't fix (e.g. the merge request was deleted or
+// the token lost access)
+func (o MergeOutcome) NeedsHuman() bool {
+	return o.State == OutcomeError && slices.Contains(permanentErrorClasses, o.ErrorClass)
+}
+
+// Info renders the outcome as human-readable text
+func (o MergeOutcome) Info() string {
+	switch o.State {
+	case OutcomeRescheduled:
+		return fmt.Sprintf("%s - will check again in %s", o.Reason, formatDelay(o.RetryAfter))
+	case OutcomeApproved:
+		return "approved - will try to merge"
+	case OutcomeMerged:
+		return "merged"
+	case OutcomeAborted:
+		return "aborted - " + o.Reason
+	case OutcomeDelegated:
+		return "delegated to GitLab auto-merge"
+	case OutcomeError:
+		if o.NeedsHuman() {
+			return fmt.Sprintf("error %s (%s): %s - needs human", o.Reason, o.ErrorClass, o.Error)
+		}
+		return fmt.Sprintf("error %s (%s): %s - will retry in %s", o.Reason, o.ErrorClass, o.Error, formatDelay(o.RetryAfter))
+	case OutcomeQuarantined:
+		return "quarantined - processing panicked: " + o.Reason + " - enable it again to retry"
+	case OutcomeUnknown:
+		return fmt.Sprintf("unknown status %s - will check again in %s", o.Reason, formatDelay(o.RetryAfter))
+	}
+	return string(o.State)
+}
+
+func formatDelay(d time.Duration) string {
+	switch {
+	case d == time.Minute:
+		return "1 minute"
+	case d > time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
@@ -0,0 +1,91 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+)
+
+// CloseMergeRequest closes the merge request, e.g. a stale update that is superseded
+func (m *MergeRequestManager) CloseMergeRequest(ctx context.Context, id int) error {
+	old, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	mr, _, err := m.gl.MergeRequests.UpdateMergeRequest(old.ProjectID, old.IID, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("close"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("closed merge request", "mr", id)
+	err = m.storeFetched(mrKey(mr.ID), mr)
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventUpdated, TargetID: id})
+	return nil
+}
+
+// RebaseMergeRequest asks gitlab to rebase the source branch of the merge request onto the target branch,
+// the rebase runs asynchronously in gitlab
+func (m *MergeRequestManager) RebaseMergeRequest(ctx context.Context, id int) error {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	_, err = m.gl.MergeRequests.RebaseMergeRequest(mr.ProjectID, mr.IID, &gitlab.RebaseMergeRequestOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("rebase requested", "mr", id)
+	m.emit(Event{Type: EventUpdated, TargetID: id})
+	return nil
+}
+
+// defaultTemplate is the merge request description template gitlab preselects for new merge requests
+const defaultTemplate = ".gitlab/merge_request_templates/Default.md"
+
+// MergeRequestText returns the current title and description of the merge request for editing, an empty description
+// is filled with the default merge request template of the project
+func (m *MergeRequestManager) MergeRequestText(ctx context.Context, id int) (string, string, error) {
+	cached, err := m.GetMergeRequest(id)
+	if err != nil {
+		return "", "", err
+	}
+	current, _, err := m.gl.MergeRequests.GetMergeRequest(cached.ProjectID, cached.IID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", "", err
+	}
+	if current.Description != "" {
+		return current.Title, current.Description, nil
+	}
+	template, _, err := m.gl.RepositoryFiles.GetRawFile(current.ProjectID, defaultTemplate, &gitlab.GetRawFileOptions{Ref: gitlab.Ptr(current.TargetBranch)}, gitlab.WithContext(ctx))
+	if err != nil && !errors.Is(err, gitlab.ErrNotFound) {
+		return "", "", err
+	}
+	return current.Title, string(template), nil
+}
+
+// EditMergeRequest replaces the title and description of the merge request, e.g. to add the issue key required by a
+// jira association check
+func (m *MergeRequestManager) EditMergeRequest(ctx context.Context, id int, title string, description string) error {
+	old, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	mr, _, err := m.gl.MergeRequests.UpdateMergeRequest(old.ProjectID, old.IID, &gitlab.UpdateMergeRequestOptions{
+		Title:       gitlab.Ptr(title),
+		Description: gitlab.Ptr(description),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("edited merge request", "mr", id)
+	err = m.storeFetched(mrKey(mr.ID), mr)
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventUpdated, TargetID: id})
+	return nil
+}
@@ -0,0 +1,44 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"slices"
+	"testing"
+)
+
+func TestCloseMergeRequest(t *testing.T) {
+	h := newHarness(t, fakegitlab.Mixed())
+
+	err := h.Manager.CloseMergeRequest(context.Background(), 202)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := h.Server.MergeRequest(202).State; state != "closed" {
+		t.Errorf("merge request on gitlab is %s, want closed", state)
+	}
+	mr, err := h.Manager.GetMergeRequest(202)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.State != "closed" {
+		t.Errorf("cached merge request is %s, want closed", mr.State)
+	}
+}
+
+func TestRebaseMergeRequest(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	scenario.MergeRequests[2].DetailedMergeStatus = "need_rebase"
+	h := newHarness(t, scenario)
+
+	err := h.Manager.RebaseMergeRequest(context.Background(), 203)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(h.Server.Calls(), "PUT /api/v4/projects/2/merge_requests/4/rebase") {
+		t.Errorf("no rebase requested, calls: %v", h.Server.Calls())
+	}
+	if status := h.Server.MergeRequest(203).DetailedMergeStatus; status != "mergeable" {
+		t.Errorf("merge request is %s after the rebase, want mergeable", status)
+	}
+}
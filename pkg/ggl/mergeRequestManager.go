@@ -2,32 +2,144 @@ package ggl
 
 import (
 	"cmp"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/cockroachdb/pebble"
 	"github.com/xanzy/go-gitlab"
-	"log"
-	"os"
-	"path"
-	"reflect"
+	"log/slog"
+	"net/http"
+	"regexp"
 	"slices"
-	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // MergeRequestManager is a struct that manages the merge requests
 type MergeRequestManager struct {
-	db               *pebble.DB
-	gl               *gitlab.Client
-	processQueue     chan mergeTarget
+	db            *pebble.DB
+	gl            *Client
+	logger        *slog.Logger
+	clock         Clock
+	projectFilter ProjectFilter
+	// requireSignedCommits only approves merge requests whose commits all have verified signatures
+	requireSignedCommits bool
+	// jiraKey is added to merge requests blocked by a missing jira association
+	jiraKey string
+	// statusActions override the handling of detailed merge statuses
+	statusActions map[string]string
+	// approvalPassword is sent along approvals on instances that require re-authentication
+	approvalPassword atomic.Pointer[string]
+	// projectPermissions restrict approving and merging per project path
+	projectPermissions map[string]string
+	// maxMergesPerHour and maxMergesPerDay throttle merges, 0 is unlimited
+	maxMergesPerHour int
+	maxMergesPerDay  int
+	// excludedAuthors are the usernames whose merge requests are never processed
+	excludedAuthors []string
+	// sourceBranches restricts the merge requests to ones from matching source branches, nil allows all
+	sourceBranches *regexp.Regexp
+	// minAge holds merge requests until they are that old, older than maxAge they are stale and closed if closeStale
+	minAge           time.Duration
+	maxAge           time.Duration
+	closeStale       bool
+	processQueue     chan int
+	wakeEnqueuer     chan struct{}
+	subscribers      map[chan Event]struct{}
+	subscribersMu    sync.Mutex
 	AuthorUsername   *string
 	ReviewerUsername *string
+	AssigneeUsername *string
+	MilestoneTitle   *string
+	// running tracks the processor and enqueuer started by Start, they report to be alive with their heartbeats
+	running       sync.WaitGroup
+	processorBeat heartbeat
+	enqueuerBeat  heartbeat
+	// pending are the ids of the targets queued or being processed, inFlight the ones being processed
+	pending  map[int]struct{}
+	inFlight map[int]struct{}
+	queueMu  sync.Mutex
+	// leader is the lease shared with other instances, nil if this instance always approves and merges
+	leader *leaderLock
+	// ReviewerGroupPath selects the merge requests with a member of the group as reviewer, besides ReviewerUsername
+	ReviewerGroupPath *string
+	// flakyJobs matches the names of jobs whose failed pipelines are retried up to flakyRetries times, nil disables it
+	flakyJobs    *regexp.Regexp
+	flakyRetries int
+	// instance selects the gitlab instance and database of NewDefaultMergeRequestManager, apiStats counts the calls of
+	// its client
+	instance instance
+	apiStats *APIStats
+	// token replaces the token of the client of NewDefaultMergeRequestManager after RotateExpiringToken
+	token *rotatedToken
+	// dbLock is the directory lock of the database opened by NewDefaultMergeRequestManager, released after the
+	// database is closed
+	dbLock *pebble.Lock
 }
 
-// NewMergeRequestManager creates a new MergeRequestManager
-func NewMergeRequestManager(db *pebble.DB, gl *gitlab.Client) *MergeRequestManager {
-	return &MergeRequestManager{db: db, gl: gl, processQueue: make(chan mergeTarget)}
+// NewMergeRequestManager creates a new MergeRequestManager, a database and a gitlab client are required
+func NewMergeRequestManager(opts ...Option) (*MergeRequestManager, error) {
+	m := &MergeRequestManager{
+		logger:        slog.Default(),
+		clock:         realClock{},
+		projectFilter: DefaultProjectFilter,
+		processQueue:  make(chan int, processQueueSize),
+		pending:       make(map[int]struct{}),
+		inFlight:      make(map[int]struct{}),
+		wakeEnqueuer:  make(chan struct{}, 1),
+		subscribers:   make(map[chan Event]struct{}),
+		apiStats:      NewAPIStats(http.DefaultTransport),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.db == nil {
+		return nil, errors.New("database must be set")
+	}
+	if m.gl == nil {
+		return nil, errors.New("gitlab client must be set")
+	}
+	if err := validateStatusActions(m.statusActions); err != nil {
+		return nil, err
+	}
+	if err := validateProjectPermissions(m.projectPermissions); err != nil {
+		return nil, err
+	}
+	if err := validateAgeLimits(m.minAge, m.maxAge); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewDefaultMergeRequestManager creates a MergeRequestManager using the default client and database of the instance
+// selected by WithGitLabURL and WithDbPath, opts can configure everything else
+func NewDefaultMergeRequestManager(opts ...Option) (*MergeRequestManager, error) {
+	c := configure(opts)
+	url, err := c.instance.defaultURL()
+	if err != nil {
+		return nil, err
+	}
+	token := &rotatedToken{next: c.apiStats}
+	gl, err := c.instance.client(url, token)
+	if err != nil {
+		return nil, err
+	}
+	db, lock, err := c.instance.db(url)
+	if err != nil {
+		return nil, err
+	}
+	withToken := func(m *MergeRequestManager) {
+		m.token = token
+		m.dbLock = lock
+	}
+	return NewMergeRequestManager(append([]Option{WithGitLab(gl), WithDB(db), WithAPIStats(c.apiStats), withToken}, opts...)...)
+}
+
+// APIStats returns the calls and rate limit of the client of NewDefaultMergeRequestManager
+func (m *MergeRequestManager) APIStats() *APIStats {
+	return m.apiStats
 }
 
 func (m *MergeRequestManager) GetTimeStamp(timestampId string) (time.Time, error) {
@@ -53,37 +165,72 @@ func (m *MergeRequestManager) setTimeStamp(timestampId string, t time.Time) erro
 // GetOrFetchMergeRequests gets all the merge requests from the database or fetches them from the gitlab api
 // if the last fetch was more than 1 minutes ago or if there are no merge requests in the database blocks until
 // the merge requests are fetched
-func (m *MergeRequestManager) GetOrFetchMergeRequests(force bool) ([]MergeRequestInfo, error) {
-	err := m.FetchProjectsIfNotOutdated()
-	if err != nil {
-		log.Println("Error fetching projects", err)
-		return nil, err
-	}
-	timestampId := fmt.Sprintf("last-fetch-mr-%s-%s", m.AuthorUsername, m.ReviewerUsername)
+func (m *MergeRequestManager) GetOrFetchMergeRequests(ctx context.Context, force bool) ([]MergeRequestInfo, error) {
+	timestampId := m.mergeRequestsTimestampId()
 	lastFetch, err := m.GetTimeStamp(timestampId)
 	if err != nil {
-		log.Println("Error getting timestamp", err)
+		m.logger.Error("error getting timestamp", "err", err)
 		return nil, err
 	}
-	if time.Since(lastFetch) > 1*time.Minute || force {
-		err = m.FetchMergeRequests()
+	if m.clock.Now().Sub(lastFetch) > 1*time.Minute || force || !m.cachedForFilter() {
+		err = m.FetchMergeRequests(ctx)
 		if err != nil {
-			log.Println("Error fetching merge requests", err)
+			m.logger.Error("error fetching merge requests", "err", err)
 			return nil, err
 		}
-		err = m.setTimeStamp(timestampId, time.Now())
+		err = m.setTimeStamp(timestampId, m.clock.Now())
 		if err != nil {
-			log.Println("Error setting timestamp", err)
+			m.logger.Error("error setting timestamp", "err", err)
 			return nil, err
 		}
 	}
 	return m.GetMergeRequests()
 }
 
+func (m *MergeRequestManager) mergeRequestsTimestampId() string {
+	id := fmt.Sprintf("last-fetch-mr-%s-%s", deref(m.AuthorUsername), deref(m.ReviewerUsername))
+	if m.AssigneeUsername != nil || m.MilestoneTitle != nil {
+		id += fmt.Sprintf("-%s-%s", deref(m.AssigneeUsername), deref(m.MilestoneTitle))
+	}
+	if m.ReviewerGroupPath != nil {
+		id += "-group-" + *m.ReviewerGroupPath
+	}
+	return id
+}
+
+// cachedFilterSetting names the filter the cached merge requests were fetched with
+const cachedFilterSetting = "cached-mr-filter"
+
+// cachedForFilter reports whether the cached merge requests were fetched with the current filter. Merge requests
+// cached under another filter are out of scope until a fetch replaces them, without filter the whole cache is used.
+func (m *MergeRequestManager) cachedForFilter() bool {
+	if m.AuthorUsername == nil && m.ReviewerUsername == nil && m.AssigneeUsername == nil && m.MilestoneTitle == nil &&
+		m.ReviewerGroupPath == nil {
+		return true
+	}
+	var cached string
+	err := m.LoadSetting(cachedFilterSetting, &cached)
+	if err != nil {
+		m.logger.Warn("error loading the filter of the cached merge requests", "err", err)
+		return false
+	}
+	// the timestamp id contains the filter
+	return cached == m.mergeRequestsTimestampId()
+}
+
+// LastSync returns when the merge requests were last fetched successfully
+func (m *MergeRequestManager) LastSync() (time.Time, error) {
+	return m.GetTimeStamp(m.mergeRequestsTimestampId())
+}
+
 // FetchMergeRequests fetches the merge requests from the gitlab api
-func (m *MergeRequestManager) FetchMergeRequests() error {
-	if m.AuthorUsername == nil && m.ReviewerUsername == nil {
-		return errors.New("author and/or reviewer username must be set")
+func (m *MergeRequestManager) FetchMergeRequests(ctx context.Context) error {
+	if m.AuthorUsername == nil && m.ReviewerUsername == nil && m.AssigneeUsername == nil && m.ReviewerGroupPath == nil {
+		return errors.New("author, reviewer, reviewer group and/or assignee must be set")
+	}
+	assigneeID, err := m.assigneeID(ctx)
+	if err != nil {
+		return err
 	}
 	opt := &gitlab.ListMergeRequestsOptions{
 		ListOptions: gitlab.ListOptions{
@@ -93,36 +240,58 @@ func (m *MergeRequestManager) FetchMergeRequests() error {
 		},
 		AuthorUsername:   m.AuthorUsername,
 		ReviewerUsername: m.ReviewerUsername,
+		AssigneeID:       assigneeID,
+		Milestone:        m.MilestoneTitle,
 		State:            gitlab.Ptr("opened"),
 		Scope:            gitlab.Ptr("all"),
 		Sort:             gitlab.Ptr("created_at"),
 	}
+	opts, err := m.reviewerGroupOptions(ctx, opt)
+	if err != nil {
+		return err
+	}
 	mrIds := make(map[string]bool)
+	var fetched []*gitlab.MergeRequest
+	fetchedAt := m.clock.Now()
+	batch := m.db.NewBatch()
+	defer batch.Close()
 
-	for {
-		mrs, resp, err := m.gl.MergeRequests.ListMergeRequests(opt)
-		if err != nil {
-			return err
-		}
-
-		// Store the merge requests in the database
-		for _, mr := range mrs {
-			key := mrKey(mr.ID)
-			mrIds[key] = true
-			err = m.store(mrKey(mr.ID), mr)
+	for _, opt := range opts {
+		for {
+			mrs, resp, err := m.gl.MergeRequests.ListMergeRequests(opt, gitlab.WithContext(ctx))
 			if err != nil {
 				return err
 			}
-		}
 
-		if resp.NextPage == 0 {
-			break
+			// Store the merge requests in the database, the lists of the reviewers of a group overlap
+			mrs = slices.DeleteFunc(mrs, func(mr *gitlab.MergeRequest) bool {
+				return !m.allowedSourceBranch(mr) || mrIds[mrKey(mr.ID)]
+			})
+			for _, mr := range mrs {
+				key := mrKey(mr.ID)
+				mrIds[key] = true
+				err = storeFetchedBatch(batch, key, mr, fetchedAt)
+				if err != nil {
+					return err
+				}
+			}
+			fetched = append(fetched, mrs...)
+			m.reportProgress(Progress{What: "merge requests", Page: opt.Page, Pages: resp.TotalPages, Items: len(fetched)})
+
+			if resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
 		}
-		opt.Page = resp.NextPage
+	}
+
+	err = m.enrichMergeRequests(ctx, fetched)
+	if err != nil {
+		return err
 	}
 
 	// Delete merge requests that are no longer in the list
-	iter, err := m.db.NewIter(prefixIterOptions([]byte("mr-")))
+	iter, err := m.db.NewIter(prefixIterOptions([]byte(mrPrefix)))
 	if err != nil {
 		return err
 	}
@@ -131,165 +300,181 @@ func (m *MergeRequestManager) FetchMergeRequests() error {
 	for iter.First(); iter.Valid(); iter.Next() {
 		item := iter.Key()
 		if !mrIds[string(item)] {
-			err := m.db.Delete(item, pebble.Sync)
+			err := deleteFetchedBatch(batch, item)
+			if err != nil {
+				return err
+			}
+			id := strings.TrimPrefix(string(item), mrPrefix)
+			err = batch.Delete([]byte(approvalsPrefix+id), nil)
+			if err != nil {
+				return err
+			}
+			err = batch.DeleteRange([]byte(diffPrefix+id+"-"), keyUpperBound([]byte(diffPrefix+id+"-")), nil)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return nil
-}
-
-func keyUpperBound(b []byte) []byte {
-	end := make([]byte, len(b))
-	copy(end, b)
-	for i := len(end) - 1; i >= 0; i-- {
-		end[i] = end[i] + 1
-		if end[i] != 0 {
-			return end[:i+1]
-		}
+	err = storeBatch(batch, settingPrefix+cachedFilterSetting, m.mergeRequestsTimestampId())
+	if err != nil {
+		return err
 	}
-	return nil // no upper-bound
-}
-
-func prefixIterOptions(prefix []byte) *pebble.IterOptions {
-	return &pebble.IterOptions{
-		LowerBound: prefix,
-		UpperBound: keyUpperBound(prefix),
+	err = batch.Commit(pebble.Sync)
+	if err != nil {
+		return err
 	}
+	m.emit(Event{Type: EventFetched})
+	return nil
 }
 
-func (m *MergeRequestManager) FetchMergeRequest(id int) error {
+func (m *MergeRequestManager) FetchMergeRequest(ctx context.Context, id int) error {
 	old, err := m.GetMergeRequest(id)
 	if err != nil {
 		return err
 	}
-	mr, _, err := m.gl.MergeRequests.GetMergeRequest(old.ProjectID, old.IID, &gitlab.GetMergeRequestsOptions{})
+	mr, _, err := m.gl.MergeRequests.GetMergeRequest(old.ProjectID, old.IID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
 		return err
 	}
-	err = m.store(mrKey(mr.ID), mr)
+	err = m.storeFetched(mrKey(mr.ID), mr)
 	return err
 }
 
 // GetMergeRequest gets a merge request by id
 func (m *MergeRequestManager) GetMergeRequest(id int) (*gitlab.MergeRequest, error) {
-	var mr gitlab.MergeRequest
-	err := m.load(mrKey(id), &mr)
+	mr, err := load[gitlab.MergeRequest](m.db, mrKey(id))
 	return &mr, err
 }
 
 type MergeRequestInfo struct {
 	gitlab.MergeRequest
-	Target mergeTarget
+	Target    mergeTarget
+	Approvals Approvals
+	// LabelDetails are the labels of the merge request with their colors
+	LabelDetails []Label
+	// CanMerge is false if the access level of the token user in the project is too low to merge
+	CanMerge bool
+	// FetchedAt is when the merge request was last fetched from gitlab, zero if unknown
+	FetchedAt time.Time
 }
 
+// GetMergeRequests returns the cached merge requests, none if they were cached under another filter
 func (m *MergeRequestManager) GetMergeRequests() ([]MergeRequestInfo, error) {
-	var mrs []gitlab.MergeRequest
-	err := m.loadPrefix("mr-", &mrs)
+	if !m.cachedForFilter() {
+		return nil, nil
+	}
+	mrs, err := loadAll[gitlab.MergeRequest](m.db, mrPrefix)
 	slices.SortFunc(mrs, func(a, b gitlab.MergeRequest) int {
 		return b.UpdatedAt.Compare(*a.UpdatedAt)
 	})
 	mri := make([]MergeRequestInfo, len(mrs))
 	for i, mr := range mrs {
-		target := mergeTarget{}
-		_ = m.load("merge-target-"+strconv.Itoa(mr.ID), &target)
-		mri[i] = MergeRequestInfo{MergeRequest: mr, Target: target}
+		target, _ := load[mergeTarget](m.db, targetKey(mr.ID))
+		approvals, _ := load[Approvals](m.db, approvalsKey(mr.ID))
+		mri[i] = MergeRequestInfo{MergeRequest: mr, Target: target, Approvals: approvals, LabelDetails: m.mergeRequestLabels(mr), CanMerge: true,
+			FetchedAt: m.fetchedAt(mrKey(mr.ID))}
+		if p, err := m.GetProject(mr.ProjectID); err == nil {
+			mri[i].CanMerge = mr.User.CanMerge || canMerge(p)
+		}
 	}
 	return mri, err
 }
 
-func mrKey(id int) string {
-	return "mr-" + strconv.Itoa(id)
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
-func (m *MergeRequestManager) FetchProjects() error {
+// FetchProjects fetches the projects matching the project filter into the cache and removes all others.
+// Projects are listed with keyset pagination, which does not slow down on late pages like offset pagination,
+// instances not supporting it answer with offset pagination links that are followed the same way.
+func (m *MergeRequestManager) FetchProjects(ctx context.Context) error {
 	opts := gitlab.ListProjectsOptions{
 		ListOptions: gitlab.ListOptions{
-			PerPage: 20,
-			Page:    1,
+			PerPage:    100,
+			Pagination: "keyset",
 		},
+		OrderBy:    gitlab.Ptr("id"),
+		Sort:       gitlab.Ptr("asc"),
+		Membership: gitlab.Ptr(m.projectFilter.Membership),
+		Owned:      gitlab.Ptr(m.projectFilter.Owned),
+	}
+	if !m.projectFilter.Archived {
+		opts.Archived = gitlab.Ptr(false)
+	}
+	if m.projectFilter.MinAccessLevel > 0 {
+		opts.MinAccessLevel = gitlab.Ptr(gitlab.AccessLevelValue(m.projectFilter.MinAccessLevel))
 	}
+	projectIds := make(map[string]bool)
+	fetchedAt := m.clock.Now()
+	page := 1
+	batch := m.db.NewBatch()
+	defer batch.Close()
+	requestOpts := []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)}
 	for {
-		projects, resp, err := m.gl.Projects.ListProjects(&opts)
+		projects, resp, err := m.gl.Projects.ListProjects(&opts, requestOpts...)
 		if err != nil {
 			return err
 		}
 
 		// Store the projects in the database
 		for _, project := range projects {
-			err := m.store("project-"+strconv.Itoa(project.ID), project)
+			projectIds[projectKey(project.ID)] = true
+			err := storeFetchedBatch(batch, projectKey(project.ID), project, fetchedAt)
 			if err != nil {
 				return err
 			}
 		}
-		if resp.NextPage == 0 {
+		m.reportProgress(Progress{What: "projects", Page: page, Items: len(projectIds)})
+		if resp.NextLink == "" {
 			break
 		}
-		opts.Page = resp.NextPage
+		page++
+		requestOpts = []gitlab.RequestOptionFunc{gitlab.WithContext(ctx), gitlab.WithKeysetPaginationParameters(resp.NextLink)}
 	}
 
-	return nil
-}
-func (m *MergeRequestManager) store(key string, v interface{}) error {
-	data, err := json.Marshal(v)
-	if err != nil {
-		return err
-	}
-	return m.db.Set([]byte(key), data, pebble.Sync)
-}
-
-func (m *MergeRequestManager) loadPrefix(prefix string, v interface{}) error {
-	// Ensure v is a pointer to a slice
-	sliceValue := reflect.ValueOf(v)
-	if sliceValue.Kind() != reflect.Ptr || sliceValue.Elem().Kind() != reflect.Slice {
-		return errors.New("v must be a pointer to a slice")
-	}
-
-	sliceElemType := sliceValue.Elem().Type().Elem()
-
-	iter, err := m.db.NewIter(prefixIterOptions([]byte(prefix)))
+	// Delete projects that no longer match the filter
+	iter, err := m.db.NewIter(prefixIterOptions([]byte(projectPrefix)))
 	if err != nil {
 		return err
 	}
 	defer iter.Close()
-
 	for iter.First(); iter.Valid(); iter.Next() {
-		item, err := iter.ValueAndErr()
-		if err != nil {
-			return err
-		}
-		elem := reflect.New(sliceElemType).Interface()
-		err = json.Unmarshal(item, elem)
-		if err != nil {
-			return err
+		if !projectIds[string(iter.Key())] {
+			err := deleteFetchedBatch(batch, iter.Key())
+			if err != nil {
+				return err
+			}
 		}
-		sliceValue.Elem().Set(reflect.Append(sliceValue.Elem(), reflect.ValueOf(elem).Elem()))
 	}
-	return nil
+	return batch.Commit(pebble.Sync)
 }
 
-func (m *MergeRequestManager) load(key string, v interface{}) error {
-	data, closer, err := m.db.Get([]byte(key))
-	if err != nil {
+// ensureProject fetches the project into the cache if it is not cached yet
+func (m *MergeRequestManager) ensureProject(ctx context.Context, id int) error {
+	_, closer, err := m.db.Get([]byte(projectKey(id)))
+	if err == nil {
+		return closer.Close()
+	}
+	if !errors.Is(err, pebble.ErrNotFound) {
 		return err
 	}
-	err = json.Unmarshal(data, v)
+	project, _, err := m.gl.Projects.GetProject(id, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
 		return err
 	}
-	return closer.Close()
+	return m.storeFetched(projectKey(project.ID), project)
 }
 
 func (m *MergeRequestManager) GetProject(id int) (*gitlab.Project, error) {
-	var project gitlab.Project
-	err := m.load("project-"+strconv.Itoa(id), &project)
+	project, err := load[gitlab.Project](m.db, projectKey(id))
 	return &project, err
 }
 
 func (m *MergeRequestManager) GetProjects() ([]gitlab.Project, error) {
-	var projects []gitlab.Project
-	err := m.loadPrefix("project-", &projects)
+	projects, err := loadAll[gitlab.Project](m.db, projectPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -299,30 +484,18 @@ func (m *MergeRequestManager) GetProjects() ([]gitlab.Project, error) {
 	return projects, err
 }
 
-func (m *MergeRequestManager) PullDiff(id int) ([]*gitlab.MergeRequestDiff, error) {
-	mr, err := m.GetMergeRequest(id)
-	if err != nil {
-		return nil, err
-	}
-	diff, _, err := m.gl.MergeRequests.ListMergeRequestDiffs(mr.ProjectID, mr.IID, &gitlab.ListMergeRequestDiffsOptions{
-		Unidiff: gitlab.Ptr(true),
-	})
-	if err != nil {
-		return nil, err
-	}
-	return diff, err
-}
-
-func (m *MergeRequestManager) FetchProjectsIfNotOutdated() error {
+func (m *MergeRequestManager) FetchProjectsIfNotOutdated(ctx context.Context) error {
 
-	lastFetch, err := m.GetTimeStamp("last-fetch-projects")
+	// the filter is part of the timestamp, so that the projects are fetched again when it changes
+	timestampId := fmt.Sprintf("last-fetch-projects-%+v", m.projectFilter)
+	lastFetch, err := m.GetTimeStamp(timestampId)
 
-	if time.Since(lastFetch) > 60*time.Minute {
-		err = m.FetchProjects()
+	if m.clock.Now().Sub(lastFetch) > 60*time.Minute {
+		err = m.FetchProjects(ctx)
 		if err != nil {
 			return err
 		}
-		err = m.setTimeStamp("last-fetch-projects", time.Now())
+		err = m.setTimeStamp(timestampId, m.clock.Now())
 		if err != nil {
 			return err
 		}
@@ -330,24 +503,98 @@ func (m *MergeRequestManager) FetchProjectsIfNotOutdated() error {
 	return nil
 }
 
-func (m *MergeRequestManager) ApproveAndMergeMergeRequest(id int, diff []*gitlab.MergeRequestDiff) interface{} {
-	diffText := RenderDiffString(diff)
+// ApproveAndMergeMergeRequest approves and merges the merge request once it becomes mergeable, as long as its diff is
+// the reviewed one
+func (m *MergeRequestManager) ApproveAndMergeMergeRequest(ctx context.Context, id int, diff []*gitlab.MergeRequestDiff) error {
+	return m.enable(ctx, id, diff, false)
+}
+
+// MergeMergeRequest merges the merge request once it becomes mergeable without approving it,
+// approvals have to come from elsewhere
+func (m *MergeRequestManager) MergeMergeRequest(ctx context.Context, id int, diff []*gitlab.MergeRequestDiff) error {
+	return m.enable(ctx, id, diff, true)
+}
 
+// ApproveMergeRequest approves the merge request if its diff is still the reviewed one, merging is left to others
+func (m *MergeRequestManager) ApproveMergeRequest(ctx context.Context, id int, diff []*gitlab.MergeRequestDiff) error {
 	mr, err := m.GetMergeRequest(id)
 	if err != nil {
 		return err
 	}
+	current, err := m.PullDiff(ctx, id)
+	if err != nil {
+		return err
+	}
+	if RenderDiffString(current) != RenderDiffString(diff) {
+		return errors.New("diff changed since it was reviewed")
+	}
+	if m.Excluded(mr) {
+		return errExcludedAuthor
+	}
+	if approve, _ := m.projectAllows(mr.ProjectID); !approve {
+		return errors.New("approving is disabled for the project")
+	}
+	// a push after the diff was compared must not be approved, gitlab refuses the approval if the head moved
+	_, _, err = m.gl.MergeRequestApprovals.ApproveMergeRequest(mr.ProjectID, mr.IID, approveAt(mr),
+		append(m.approveOptions(), gitlab.WithContext(ctx))...)
+	if isUnauthorized(err) {
+		return errors.New(ReasonApprovalPassword)
+	}
+	if errorClass(err) == ErrorClassConflict {
+		return errors.New(ReasonDiffChanged)
+	}
+	if err != nil {
+		return err
+	}
+	m.logger.Info("approved merge request", "mr", id)
+	err = m.fetchApprovals(ctx, mr)
+	if err != nil {
+		m.logger.Warn("error fetching approvals", "mr", id, "err", err)
+	}
+	m.emit(Event{Type: EventApproved, TargetID: id})
+	return nil
+}
+
+// enable creates an active merge target for the merge request with the reviewed diff and queues it
+func (m *MergeRequestManager) enable(ctx context.Context, id int, diff []*gitlab.MergeRequestDiff, skipApproval bool) error {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	if approve, merge := m.projectAllows(mr.ProjectID); !approve && !merge {
+		return errObserveOnly
+	}
+	if m.Excluded(mr) {
+		return errExcludedAuthor
+	}
 
+	target := m.newTarget(mr, diff, skipApproval)
+	err = m.process(ctx, target)
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventEnabled, TargetID: target.Id})
+	return nil
+}
+
+// newTarget creates an active merge target for the merge request with the reviewed diff, the history of an earlier
+// target is kept
+func (m *MergeRequestManager) newTarget(mr *gitlab.MergeRequest, diff []*gitlab.MergeRequestDiff, skipApproval bool) mergeTarget {
 	target := mergeTarget{
-		Id:        mr.ID,
-		ProjectID: mr.ProjectID,
-		MergeID:   mr.IID,
-		DiffHash:  diffText,
-		Next:      time.Now(),
-		Info:      "enabled",
-		Active:    true,
+		Id:           mr.ID,
+		ProjectID:    mr.ProjectID,
+		MergeID:      mr.IID,
+		DiffHash:     RenderDiffString(diff),
+		Next:         m.clock.Now(),
+		Info:         "enabled",
+		Active:       true,
+		SkipApproval: skipApproval,
+	}
+	if old, err := load[mergeTarget](m.db, targetKey(mr.ID)); err == nil {
+		target.History = old.History
 	}
-	return m.process(target)
+	target.record(target.Next, "enabled", target.Info)
+	return target
 }
 
 func RenderDiffString(diff []*gitlab.MergeRequestDiff) string {
@@ -367,167 +614,519 @@ type mergeTarget struct {
 	Latest    time.Time
 	Next      time.Time
 	Active    bool
+	Outcome   MergeOutcome
+	// SkipApproval waits for approvals from elsewhere instead of approving
+	SkipApproval bool
+	// History are the latest state transitions, oldest first
+	History []TargetEvent
+	// Unknown counts the consecutive checks that found an unknown status, they back off
+	Unknown int `json:",omitempty"`
+	// Errors counts the consecutive checks that failed with an error, they back off
+	Errors int `json:",omitempty"`
 }
 
-func GetDefaultDb() (*pebble.DB, error) {
-	tempDir := path.Join(os.TempDir(), "merge-request-manager")
-	err := os.MkdirAll(tempDir, 0700)
-	if err != nil {
-		return nil, err
+// processMerge decides on the next step for the target based on the merge request and stores the result
+func (m *MergeRequestManager) processMerge(ctx context.Context, target mergeTarget, mr *gitlab.MergeRequest) MergeOutcome {
+	mergeStatus := mr.DetailedMergeStatus
+	if !target.Active {
+		m.logger.Info("target is not active", "target", target.Id)
+		return MergeOutcome{State: OutcomeInactive}
+	}
+	target.Latest = m.clock.Now()
+	var outcome MergeOutcome
+	if delegated(mr) {
+		m.logger.Info("merge when pipeline succeeds is set, leaving the merge to gitlab", "target", target.Id)
+		outcome = MergeOutcome{State: OutcomeDelegated}
+	} else {
+		outcome = m.mergeStep(ctx, target, mr)
+	}
+	if outcome.State == OutcomeUnknown {
+		m.logger.Warn("unknown status", "target", target.Id, "status", mergeStatus)
+		target.Unknown++
+		outcome.RetryAfter = unknownStatusBackoff(target.Unknown)
+	} else {
+		target.Unknown = 0
 	}
-	return pebble.Open(tempDir, nil)
+	if outcome.State == OutcomeError && !outcome.NeedsHuman() {
+		target.Errors++
+		outcome.RetryAfter = errorBackoff(outcome.ErrorClass, target.Errors)
+	} else {
+		target.Errors = 0
+	}
+	target.Outcome = outcome
+	switch {
+	case outcome.Finished():
+		m.stopProcessing(target, outcome.Info())
+		m.recordReportEntry(target, outcome)
+	default:
+		m.reschedule(target, outcome.RetryAfter, outcome.Info())
+	}
+	m.emit(outcomeEvent(target.Id, outcome))
+	return outcome
 }
 
-func (m *MergeRequestManager) processMerge(target mergeTarget, mergeStatus string) {
-	if !target.Active {
-		log.Println("Target is not active - ", target.Id)
-		return
+// delegated reports whether somebody set merge when pipeline succeeds, so that gitlab merges the merge request on
+// its own. Approving and merging an already mergeable merge request is still left to the target.
+func delegated(mr *gitlab.MergeRequest) bool {
+	return mr.MergeWhenPipelineSucceeds && mr.DetailedMergeStatus != "not_approved" && mr.DetailedMergeStatus != "mergeable"
+}
+
+func (m *MergeRequestManager) mergeStep(ctx context.Context, target mergeTarget, current *gitlab.MergeRequest) MergeOutcome {
+	mergeStatus := current.DetailedMergeStatus
+	if outcome, ok := m.configuredStatusOutcome(mergeStatus); ok {
+		return outcome
+	}
+	if m.Excluded(current) {
+		return abortOutcome("author " + current.Author.Username + " excluded")
+	}
+	if !m.allowedSourceBranch(current) {
+		return abortOutcome("source branch " + current.SourceBranch + " not allowed")
+	}
+	if marker := HoldMarker(current); marker != "" && current.State == "opened" {
+		return abortOutcome("held by marker " + marker)
+	}
+	if outcome, ok := m.ageOutcome(ctx, target, current); ok {
+		return outcome
 	}
-	target.Latest = time.Now()
 	switch mergeStatus {
-	case "approvals_syncing", "blocked_status", "checking", "ci_must_pass", "ci_still_running", "conflict",
-		"external_status_checks", "jira_association_missing", "need_rebase", "unchecked", "locked_paths", "locked_lfs_files":
-		m.reschedule(target, 1*time.Minute, "status "+mergeStatus+" - will check again in 1 minute")
-		break
+	case "ci_still_running":
+		if downstream, err := m.downstreamStatus(ctx, current); err == nil && downstream != downstreamNone {
+			return retryOutcome("status ci_still_running, downstream pipelines "+downstream, 1*time.Minute)
+		}
+		return retryOutcome("status "+mergeStatus, 1*time.Minute)
+	case "need_rebase":
+		if !m.rebasesBeforeMerge(target.ProjectID) || current.RebaseInProgress {
+			return retryOutcome("status "+mergeStatus, 1*time.Minute)
+		}
+		_, err := m.gl.MergeRequests.RebaseMergeRequest(target.ProjectID, target.MergeID, &gitlab.RebaseMergeRequestOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			m.logger.Error("error rebasing merge request", "target", target.Id, "err", err)
+			return errorOutcome("rebasing", err)
+		}
+		m.logger.Info("rebasing merge request for fast-forward merge", "target", target.Id)
+		return retryOutcome("rebasing for fast-forward merge", 1*time.Minute)
+	case "jira_association_missing":
+		if m.jiraKey == "" || mentionsJiraKey(current, m.jiraKey) {
+			return retryOutcome("status "+mergeStatus, 1*time.Minute)
+		}
+		err := m.addJiraKey(ctx, current)
+		if err != nil {
+			m.logger.Error("error adding jira key", "target", target.Id, "err", err)
+			return errorOutcome("adding jira key", err)
+		}
+		return retryOutcome("added jira key "+m.jiraKey, 1*time.Minute)
+	case "ci_must_pass":
+		if outcome, ok := m.flakyPipelineOutcome(ctx, target, current); ok {
+			return outcome
+		}
+		return retryOutcome("status "+mergeStatus, 1*time.Minute)
+	case "approvals_syncing", "blocked_status", "checking", "conflict",
+		"external_status_checks", "unchecked", "locked_paths", "locked_lfs_files":
+		return retryOutcome("status "+mergeStatus, 1*time.Minute)
 	case "not_approved":
-		diff, err := m.PullDiff(target.Id)
+		if target.SkipApproval {
+			return retryOutcome("waiting for approval", 1*time.Minute)
+		}
+		if approve, _ := m.projectAllows(target.ProjectID); !approve {
+			return retryOutcome("waiting for approval (auto-approve disabled for project)", 1*time.Minute)
+		}
+		diff, err := m.PullDiff(ctx, target.Id)
 		if err != nil {
-			log.Println("Error pulling diff", err)
-			m.reschedule(target, 1*time.Minute, "error pulling diff - will check again in 1 minute")
-			break
+			m.logger.Error("error pulling diff", "target", target.Id, "err", err)
+			return errorOutcome("pulling diff", err)
 		}
 		currentDiff := RenderDiffString(diff)
 		if currentDiff != target.DiffHash {
-			log.Println("Diff changed ", target.Id)
-			m.stopProcessing(target, "aborted - diff changed")
-			break
+			m.logger.Info("diff changed", "target", target.Id)
+			return abortOutcome(ReasonDiffChanged)
+		}
+		if m.requireSignedCommits {
+			unsigned, err := m.unsignedCommits(ctx, target)
+			if err != nil {
+				m.logger.Error("error verifying commit signatures", "target", target.Id, "err", err)
+				return errorOutcome("verifying commit signatures", err)
+			}
+			if len(unsigned) > 0 {
+				m.logger.Info("unsigned commits", "target", target.Id, "commits", unsigned)
+				return abortOutcome(ReasonUnsignedCommits)
+			}
 		}
 
-		mr, _, err := m.gl.MergeRequestApprovals.ApproveMergeRequest(target.ProjectID, target.MergeID, &gitlab.ApproveMergeRequestOptions{})
+		// the lease may have expired during the checks
+		if !m.Leading() {
+			return retryOutcome(ReasonStandingBy, 1*time.Minute)
+		}
+		mr, _, err := m.gl.MergeRequestApprovals.ApproveMergeRequest(target.ProjectID, target.MergeID, approveAt(current),
+			append(m.approveOptions(), gitlab.WithContext(ctx))...)
+		if isForbidden(err) {
+			return abortOutcome(ReasonCannotApprove)
+		}
+		if isUnauthorized(err) {
+			m.logger.Warn("approval password required", "target", target.Id)
+			return retryOutcome(ReasonApprovalPassword, 1*time.Hour)
+		}
+		if errorClass(err) == ErrorClassConflict {
+			m.logger.Info("pushed while approving", "target", target.Id)
+			return abortOutcome(ReasonDiffChanged)
+		}
 		if err != nil {
-			log.Println("Error approving merge request", err)
-			m.reschedule(target, 1*time.Minute, "error approving - will check again in 1 minute")
-			break
+			m.logger.Error("error approving merge request", "target", target.Id, "err", err)
+			return errorOutcome("approving", err)
 		}
-		log.Println("Approved merge request", mr.ID)
-		err = m.store(mrKey(mr.ID), mr)
+		m.logger.Info("approved merge request", "target", target.Id)
+		err = m.storeFetched(mrKey(mr.ID), mr)
 		if err != nil {
-			log.Println("Error storing merge request", err)
+			m.logger.Error("error storing merge request", "err", err)
 		}
-		m.reschedule(target, 0*time.Minute, "approved - will try to merge")
-		break
+		return MergeOutcome{State: OutcomeApproved}
 	case "mergeable":
-		mr, _, err := m.gl.MergeRequests.AcceptMergeRequest(target.ProjectID, target.MergeID, &gitlab.AcceptMergeRequestOptions{})
+		if _, merge := m.projectAllows(target.ProjectID); !merge {
+			return humanMergeOutcome()
+		}
+		downstream, err := m.downstreamStatus(ctx, current)
 		if err != nil {
-			log.Println("Error merging merge request", err)
-			m.reschedule(target, 1*time.Minute, "error merging - will check again in 1 minute")
-			break
+			m.logger.Error("error checking downstream pipelines", "target", target.Id, "err", err)
+			return errorOutcome("checking downstream pipelines", err)
+		}
+		if downstream == downstreamRunning || downstream == downstreamFailed {
+			return retryOutcome("downstream pipelines "+downstream, 1*time.Minute)
+		}
+		next, err := m.throttled()
+		if err != nil {
+			m.logger.Error("error checking merge throttle", "target", target.Id, "err", err)
+			return errorOutcome("checking merge throttle", err)
 		}
-		log.Println(mr.Title, " new status ", mr.State, "-", mr.DetailedMergeStatus)
-		err = m.store(mrKey(mr.ID), mr)
+		if !next.IsZero() {
+			m.logger.Info("merge throttled", "target", target.Id, "next", next)
+			return m.throttledOutcome(next)
+		}
+		// somebody else may have approved after a push, only the diff that was enabled is merged
+		diff, err := m.PullDiff(ctx, target.Id)
 		if err != nil {
-			log.Println("Error storing merge request", err)
+			m.logger.Error("error pulling diff", "target", target.Id, "err", err)
+			return errorOutcome("pulling diff", err)
+		}
+		if RenderDiffString(diff) != target.DiffHash {
+			m.logger.Info("diff changed", "target", target.Id)
+			return abortOutcome(ReasonDiffChanged)
+		}
+		if !m.Leading() {
+			return retryOutcome(ReasonStandingBy, 1*time.Minute)
 		}
-		m.stopProcessing(target, "merged")
-		break
+		mr, _, err := m.gl.MergeRequests.AcceptMergeRequest(target.ProjectID, target.MergeID, m.acceptOptions(current), gitlab.WithContext(ctx))
+		if isForbidden(err) {
+			return abortOutcome(ReasonCannotMerge)
+		}
+		if err != nil {
+			m.logger.Error("error merging merge request", "target", target.Id, "err", err)
+			return errorOutcome("merging", err)
+		}
+		m.logger.Info("merged merge request", "target", target.Id, "title", mr.Title, "state", mr.State, "status", mr.DetailedMergeStatus)
+		err = m.storeFetched(mrKey(mr.ID), mr)
+		if err != nil {
+			m.logger.Error("error storing merge request", "err", err)
+		}
+		return MergeOutcome{State: OutcomeMerged}
 	case "discussions_not_resolved", "draft_status", "not_open", "requested_changes":
-		m.stopProcessing(target, "aborted - "+mergeStatus)
-		break
+		return abortOutcome(mergeStatus)
 	default:
-		log.Println("Unknown status", mergeStatus)
+		return MergeOutcome{State: OutcomeUnknown, Reason: mergeStatus}
 	}
 }
 
 func (m *MergeRequestManager) stopProcessing(target mergeTarget, info string) {
-	log.Println("Stopping target", target.Id, "with info", info)
+	m.logger.Info("stopping target", "target", target.Id, "info", info)
 	target.Active = false
-	target.Next = time.Now()
+	target.Next = m.clock.Now()
 	target.Info = info
+	target.record(target.Next, string(target.Outcome.State), info)
 	m.storeTargetSilent(target)
 }
 
 func (m *MergeRequestManager) reschedule(target mergeTarget, delay time.Duration, info string) {
-	log.Println("Rescheduling target", target.Id, "in", delay, "with info", info)
-	target.Next = time.Now().Add(delay)
+	m.logger.Info("rescheduling target", "target", target.Id, "delay", delay, "info", info)
+	target.Next = m.clock.Now().Add(delay)
 	target.Info = info
+	target.record(m.clock.Now(), string(target.Outcome.State), info)
 	m.storeTargetSilent(target)
 }
 
 func (m *MergeRequestManager) storeTargetSilent(target mergeTarget) {
-	err := m.store("merge-target-"+strconv.Itoa(target.Id), target)
+	err := store(m.db, targetKey(target.Id), target)
 	if err != nil {
-		log.Println("Error storing merge target", err)
+		m.logger.Error("error storing merge target", "target", target.Id, "err", err)
 	}
 }
 
-func (m *MergeRequestManager) process(target mergeTarget) error {
-	err := m.store("merge-target-"+strconv.Itoa(target.Id), target)
+// process stores the target and queues it, if it can't be queued right now the enqueuer picks it up
+func (m *MergeRequestManager) process(ctx context.Context, target mergeTarget) error {
+	err := store(m.db, targetKey(target.Id), target)
 	if err != nil {
 		return err
 	}
-	m.processQueue <- target
+	if !m.enqueue(target.Id) {
+		m.wake()
+	}
 	return nil
 }
 
-func (m *MergeRequestManager) processor() {
-	log.Println("Starting processor")
-	for target := range m.processQueue {
-		mr, _, err := m.gl.MergeRequests.GetMergeRequest(target.ProjectID, target.MergeID, &gitlab.GetMergeRequestsOptions{})
-		if err != nil {
-			log.Println("Error fetching merge request for id", target.Id, err)
+func (m *MergeRequestManager) processor(ctx context.Context) {
+	m.logger.Debug("starting processor")
+	for {
+		m.processorBeat.beat(m.clock.Now())
+		var id int
+		timer := m.clock.NewTimer(heartbeatInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.logger.Debug("stopping processor")
+			return
+		case <-timer.C():
 			continue
+		case id = <-m.processQueue:
 		}
-		err = m.store(mrKey(mr.ID), mr)
-		if err != nil {
-			log.Println("Error storing merge request", err)
-		}
+		timer.Stop()
+		m.processQueued(ctx, id)
+	}
+}
 
-		m.processMerge(target, mr.DetailedMergeStatus)
+// processQueued fetches the merge request of the queued target and takes the next step. The target is loaded when
+// it is processed, so changes while it was queued are not lost.
+func (m *MergeRequestManager) processQueued(ctx context.Context, id int) {
+	defer m.dequeued(id)
+	if !m.Leading() {
+		m.logger.Debug("standing by, skipping target", "target", id)
+		return
+	}
+	if !m.claim(id) {
+		m.logger.Debug("target is already being processed", "target", id)
+		return
+	}
+	defer m.release(id)
+	target, err := load[mergeTarget](m.db, targetKey(id))
+	if err != nil {
+		m.logger.Error("error loading merge target", "target", id, "err", err)
+		return
+	}
+	mr, _, err := m.gl.MergeRequests.GetMergeRequest(target.ProjectID, target.MergeID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		m.logger.Error("error fetching merge request", "target", target.Id, "err", err)
+		target.Errors++
+		target.Outcome = errorOutcome("fetching", err)
+		if target.Outcome.NeedsHuman() {
+			m.stopProcessing(target, target.Outcome.Info())
+			m.recordReportEntry(target, target.Outcome)
+			m.emit(outcomeEvent(target.Id, target.Outcome))
+			return
+		}
+		target.Outcome.RetryAfter = errorBackoff(target.Outcome.ErrorClass, target.Errors)
+		m.reschedule(target, target.Outcome.RetryAfter, target.Outcome.Info())
+		return
 	}
+	err = m.storeFetched(mrKey(mr.ID), mr)
+	if err != nil {
+		m.logger.Error("error storing merge request", "err", err)
+	}
+
+	m.processMergeSafely(ctx, target, mr)
 }
 
-func (m *MergeRequestManager) processEnqueuer() {
-	log.Println("Starting enqueuer")
+func (m *MergeRequestManager) processEnqueuer(ctx context.Context) {
+	m.logger.Debug("starting enqueuer")
 	for {
-		var mrt []mergeTarget
-		err := m.loadPrefix("merge-target-", &mrt)
+		m.enqueuerBeat.beat(m.clock.Now())
+		mrt, err := loadAll[mergeTarget](m.db, targetPrefix)
 		if err != nil {
-			log.Println("Error loading merge targets", err)
-			time.Sleep(2 * time.Second)
+			m.logger.Error("error loading merge targets", "err", err)
+			if !m.sleep(ctx, 2*time.Second) {
+				return
+			}
 			continue
 		}
 		for _, target := range mrt {
-			if target.Active && target.Next.Before(time.Now()) {
-				log.Println("Enqueuing target", target.Id)
-				m.processQueue <- target
+			if target.Active && target.Next.Before(m.clock.Now()) && m.Leading() && m.enqueue(target.Id) {
+				m.logger.Debug("enqueued target", "target", target.Id)
 			}
-			if !target.Active && target.Latest.Before(time.Now().Add(-30*time.Minute)) && target.Info != "aborted - diff changed" {
-				log.Println("Deleting target", target.Id)
-				err = m.db.Delete([]byte("merge-target-"+strconv.Itoa(target.Id)), pebble.Sync)
+			if !target.Active && target.Latest.Before(m.clock.Now().Add(-30*time.Minute)) && target.Outcome.Reason != ReasonDiffChanged &&
+				target.Outcome.State != OutcomeQuarantined {
+				m.logger.Debug("deleting target", "target", target.Id)
+				err = m.db.Delete([]byte(targetKey(target.Id)), pebble.Sync)
 				if err != nil {
-					log.Println("Error deleting target", target.Id, err)
+					m.logger.Error("error deleting target", "target", target.Id, "err", err)
 				}
 			}
 		}
-		time.Sleep(5 * time.Second)
+		timer := m.clock.NewTimer(5 * time.Second)
+		select {
+		case <-timer.C():
+		case <-m.wakeEnqueuer:
+		case <-ctx.Done():
+			timer.Stop()
+			m.logger.Debug("stopping enqueuer")
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// RetryNow schedules the active target of the merge request for right now and wakes up the enqueuer
+func (m *MergeRequestManager) RetryNow(id int) error {
+	target, err := load[mergeTarget](m.db, targetKey(id))
+	if err != nil {
+		return err
+	}
+	if !target.Active {
+		return errors.New("merge target is not active")
 	}
+	target.Next = m.clock.Now()
+	target.Info = "retry requested"
+	target.record(target.Next, "retry", target.Info)
+	err = store(m.db, targetKey(id), target)
+	if err != nil {
+		return err
+	}
+	m.wake()
+	m.emit(Event{Type: EventRescheduled, TargetID: id})
+	return nil
 }
 
-func (m *MergeRequestManager) Start() *MergeRequestManager {
-	go m.processor()
-	go m.processEnqueuer()
+// sleep waits for d on the clock and returns false if the context got cancelled before
+func (m *MergeRequestManager) sleep(ctx context.Context, d time.Duration) bool {
+	timer := m.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Start starts the background processing of merge targets until ctx is cancelled, the processor and enqueuer are
+// restarted if they panic or stall
+func (m *MergeRequestManager) Start(ctx context.Context) *MergeRequestManager {
+	m.running.Add(2)
+	go func() {
+		defer m.running.Done()
+		m.supervise(ctx, "processor", &m.processorBeat, m.processor)
+	}()
+	go func() {
+		defer m.running.Done()
+		m.supervise(ctx, "enqueuer", &m.enqueuerBeat, m.processEnqueuer)
+	}()
+	if m.leader != nil {
+		m.running.Add(1)
+		go func() {
+			defer m.running.Done()
+			m.leaderElection(ctx)
+		}()
+	}
 	return m
 }
 
+// Wait blocks until the processing started by Start stopped after its context got cancelled, the merge step in
+// progress is finished so the database can be closed safely
+func (m *MergeRequestManager) Wait() {
+	m.running.Wait()
+}
+
+// OnceResult summarizes a single non-interactive processing run
+type OnceResult struct {
+	Merged int
+	Held   int
+	Errors int
+}
+
+// ProcessOnce processes all active merge targets a single time without starting the background processor.
+// Targets that get approved are retried right away so that they can be merged in the same run, targets waiting for
+// their next attempt or processed elsewhere are held.
+func (m *MergeRequestManager) ProcessOnce(ctx context.Context) (OnceResult, error) {
+	var result OnceResult
+	mrt, err := loadAll[mergeTarget](m.db, targetPrefix)
+	if err != nil {
+		return result, err
+	}
+	for _, target := range mrt {
+		if !target.Active {
+			continue
+		}
+		if target.Next.After(m.clock.Now()) || !m.claim(target.Id) {
+			result.Held++
+			continue
+		}
+		outcome, err := m.processOnce(ctx, target)
+		m.release(target.Id)
+		if err != nil {
+			return result, err
+		}
+		switch outcome.State {
+		case OutcomeError, OutcomeQuarantined:
+			result.Errors++
+		case OutcomeMerged:
+			result.Merged++
+		default:
+			result.Held++
+		}
+	}
+	return result, nil
+}
+
+// processOnce processes the claimed target, approved targets are retried right away
+func (m *MergeRequestManager) processOnce(ctx context.Context, target mergeTarget) (MergeOutcome, error) {
+	var outcome MergeOutcome
+	for i := 0; i < 3; i++ {
+		mr, _, err := m.gl.MergeRequests.GetMergeRequest(target.ProjectID, target.MergeID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			m.logger.Error("error fetching merge request", "target", target.Id, "err", err)
+			return errorOutcome("fetching", err), nil
+		}
+		err = m.storeFetched(mrKey(mr.ID), mr)
+		if err != nil {
+			m.logger.Error("error storing merge request", "err", err)
+		}
+		outcome = m.processMergeSafely(ctx, target, mr)
+		if outcome.State != OutcomeApproved {
+			break
+		}
+		target, err = load[mergeTarget](m.db, targetKey(target.Id))
+		if err != nil {
+			return outcome, err
+		}
+		if !target.Active || target.Next.After(m.clock.Now()) {
+			break
+		}
+	}
+	return outcome, nil
+}
+
+// Close closes the underlying database and releases its lock
+func (m *MergeRequestManager) Close() error {
+	err := m.db.Close()
+	if m.dbLock != nil {
+		err = errors.Join(err, m.dbLock.Close())
+	}
+	return err
+}
+
 func (m *MergeRequestManager) ClearMerge(id int) error {
 	target := mergeTarget{
-		Id:     id,
-		Active: false,
-		Next:   time.Now(),
-		Latest: time.Now(),
-		Info:   "cleared",
+		Id:      id,
+		Active:  false,
+		Next:    m.clock.Now(),
+		Latest:  m.clock.Now(),
+		Info:    "cleared",
+		Outcome: MergeOutcome{State: OutcomeCleared},
 	}
-	return m.store("merge-target-"+strconv.Itoa(id), target)
+	if old, err := load[mergeTarget](m.db, targetKey(id)); err == nil {
+		target.ProjectID, target.MergeID, target.History = old.ProjectID, old.MergeID, old.History
+	}
+	target.record(target.Next, string(target.Outcome.State), target.Info)
+	err := store(m.db, targetKey(id), target)
+	if err != nil {
+		return err
+	}
+	m.emit(outcomeEvent(id, target.Outcome))
+	return nil
 }
 
 func (m *MergeRequestManager) Reviewer(reviewer string) *MergeRequestManager {
@@ -539,6 +1138,17 @@ func (m *MergeRequestManager) Reviewer(reviewer string) *MergeRequestManager {
 	return m
 }
 
+// ReviewerGroup also fetches the merge requests with a member of the group (path or id) as reviewer, e.g. a team that
+// is asked for reviews as code owner
+func (m *MergeRequestManager) ReviewerGroup(group string) *MergeRequestManager {
+	if group != "" {
+		m.ReviewerGroupPath = &group
+	} else {
+		m.ReviewerGroupPath = nil
+	}
+	return m
+}
+
 func (m *MergeRequestManager) Author(author string) *MergeRequestManager {
 	if author != "" {
 		m.AuthorUsername = &author
@@ -547,3 +1157,47 @@ func (m *MergeRequestManager) Author(author string) *MergeRequestManager {
 	}
 	return m
 }
+
+// Assignee only fetches merge requests assigned to the user, None and Any select unassigned and assigned ones
+func (m *MergeRequestManager) Assignee(assignee string) *MergeRequestManager {
+	if assignee != "" {
+		m.AssigneeUsername = &assignee
+	} else {
+		m.AssigneeUsername = nil
+	}
+	return m
+}
+
+// Milestone only fetches merge requests of the milestone (by title), None and Any select merge requests without and
+// with a milestone
+func (m *MergeRequestManager) Milestone(milestone string) *MergeRequestManager {
+	if milestone != "" {
+		m.MilestoneTitle = &milestone
+	} else {
+		m.MilestoneTitle = nil
+	}
+	return m
+}
+
+// assigneeID resolves the assignee username, the api filters merge requests by assignee id only
+func (m *MergeRequestManager) assigneeID(ctx context.Context) (*gitlab.AssigneeIDValue, error) {
+	if m.AssigneeUsername == nil {
+		return nil, nil
+	}
+	switch strings.ToLower(*m.AssigneeUsername) {
+	case "none":
+		return gitlab.AssigneeID(gitlab.UserIDNone), nil
+	case "any":
+		return gitlab.AssigneeID(gitlab.UserIDAny), nil
+	}
+	ids, err := m.userIDs(ctx, []string{*m.AssigneeUsername})
+	if err != nil {
+		return nil, err
+	}
+	return gitlab.AssigneeID(ids[0]), nil
+}
+
+// allowedSourceBranch reports whether the merge request comes from a source branch matching the pattern
+func (m *MergeRequestManager) allowedSourceBranch(mr *gitlab.MergeRequest) bool {
+	return m.sourceBranches == nil || m.sourceBranches.MatchString(mr.SourceBranch)
+}
@@ -0,0 +1,381 @@
+package ggl_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFetchByAssigneeAndMilestone(t *testing.T) {
+	tests := []struct {
+		assignee  string
+		milestone string
+		want      []int
+	}{
+		{"", "", []int{201, 202, 203}},
+		{"bob", "", []int{203}},
+		{"none", "", []int{201, 202}},
+		{"any", "", []int{203}},
+		{"", "2024.06", []int{202}},
+		{"", "None", []int{201, 203}},
+		{"bob", "2024.06", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assignee+"-"+tt.milestone, func(t *testing.T) {
+			scenario := fakegitlab.Mixed()
+			scenario.MergeRequests[1].Milestone = &gitlab.Milestone{Title: "2024.06"}
+			scenario.MergeRequests[2].Assignees = []*gitlab.BasicUser{{ID: 12, Username: "bob"}}
+			h := newHarness(t, scenario)
+			m := h.Manager.Assignee(tt.assignee).Milestone(tt.milestone)
+			if err := m.FetchMergeRequests(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			mrs, err := m.GetMergeRequests()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, mr := range mrs {
+				ids = append(ids, mr.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("fetched %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedUnderAnotherFilter(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	scenario.MergeRequests[1].Milestone = &gitlab.Milestone{Title: "2024.06"}
+	h := newHarness(t, scenario)
+	cached := func() []int {
+		t.Helper()
+		mrs, err := h.Manager.GetMergeRequests()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, mr := range mrs {
+			ids = append(ids, mr.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	if ids := cached(); !slices.Equal(ids, []int{201, 202, 203}) {
+		t.Errorf("cached %v after fetching", ids)
+	}
+	h.Manager.Milestone("2024.06")
+	if ids := cached(); len(ids) > 0 {
+		t.Errorf("cached %v under another milestone", ids)
+	}
+	if err := h.Manager.FetchMergeRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ids := cached(); !slices.Equal(ids, []int{202}) {
+		t.Errorf("cached %v after fetching the milestone", ids)
+	}
+	h.Manager.Author("")
+	if ids := cached(); len(ids) > 0 {
+		t.Errorf("cached %v with only the milestone", ids)
+	}
+	h.Manager.Milestone("")
+	if ids := cached(); !slices.Equal(ids, []int{202}) {
+		t.Errorf("cached %v without filter", ids)
+	}
+}
+
+func TestEnqueuerDeletesInactiveTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed bool
+		outcome ggl.OutcomeState
+		deleted bool
+	}{
+		{"merged", false, ggl.OutcomeMerged, true},
+		{"diff changed", true, ggl.OutcomeAborted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.Mixed()
+			clock := fakegitlab.NewClock(time.Now())
+			h := newHarness(t, scenario, ggl.WithClock(clock))
+			enable(t, h.Manager, 203)
+			if tt.changed {
+				h.Server.SetDiff(203, fakegitlab.Diff("package.json", "@@ -1 +1 @@\n-\"vite\": \"5.0.0\"\n+\"vite\": \"6.0.0\"\n"))
+			}
+			if target := processOnce(t, h.Manager, 203); target.Active || target.Outcome.State != tt.outcome {
+				t.Fatalf("target is %+v", target)
+			}
+
+			// the processor and enqueuer wait for a timer, their supervisors for a ticker
+			start(t, h.Manager)
+			settle(t, clock, 4)
+			// in steps below the stall timeout of the supervisors
+			for range 29 {
+				clock.Advance(time.Minute)
+				settle(t, clock, 4)
+			}
+			if _, err := h.Manager.Target(203); err != nil {
+				t.Fatalf("deleted the target before 30 minutes: %v", err)
+			}
+			clock.Advance(2 * time.Minute)
+			settle(t, clock, 4)
+			if _, err := h.Manager.Target(203); (err != nil) != tt.deleted {
+				t.Errorf("target deleted %t after 30 minutes, want %t", err != nil, tt.deleted)
+			}
+		})
+	}
+}
+
+// mergeCalls counts the merge calls the fake received for the merge request with the iid
+func mergeCalls(s *fakegitlab.Server, iid int) int {
+	n := 0
+	for _, call := range s.Calls() {
+		if strings.HasPrefix(call, "PUT ") && strings.HasSuffix(call, fmt.Sprintf("/merge_requests/%d/merge", iid)) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestProcessEveryMergeStatus(t *testing.T) {
+	merged := []string{"mergeable", "not_approved"}
+	aborted := []string{"discussions_not_resolved", "draft_status", "not_open", "requested_changes"}
+	for _, status := range fakegitlab.DetailedMergeStatuses {
+		t.Run(status, func(t *testing.T) {
+			scenario := fakegitlab.RenovateBump()
+			scenario.Statuses = nil
+			scenario.MergeRequests[0].DetailedMergeStatus = status
+			clock := fakegitlab.NewClock(time.Now())
+			h := newHarness(t, scenario, ggl.WithClock(clock))
+			enable(t, h.Manager, 101)
+			for range 3 {
+				clock.Advance(2 * time.Hour)
+				processOnce(t, h.Manager, 101)
+			}
+			target, err := h.Manager.Target(101)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := h.Server.MergeRequest(101).State
+			switch {
+			case slices.Contains(merged, status):
+				if state != "merged" || target.Outcome.State != ggl.OutcomeMerged {
+					t.Errorf("%s, target %s: %s", state, target.Outcome.State, target.Info)
+				}
+			case slices.Contains(aborted, status):
+				if target.Active || target.Outcome.State != ggl.OutcomeAborted || target.Outcome.Reason != status {
+					t.Errorf("target %s: %s", target.Outcome.State, target.Info)
+				}
+			default:
+				if !target.Active {
+					t.Errorf("target stopped: %s", target.Info)
+				}
+			}
+			if calls := mergeCalls(h.Server, 1); !slices.Contains(merged, status) && calls > 0 || calls > 1 {
+				t.Errorf("merged %d times", calls)
+			}
+		})
+	}
+}
+
+func TestProcessMergeApiErrors(t *testing.T) {
+	type failure struct {
+		endpoint string
+		n        int
+		code     int
+	}
+	tests := []struct {
+		name     string
+		failures []failure
+		merged   bool
+		reason   string
+	}{
+		{"approve fails", []failure{{"POST approve", 2, 422}}, true, ""},
+		{"approve forbidden", []failure{{"POST approve", 1, 403}}, false, ggl.ReasonCannotApprove},
+		{"approval password", []failure{{"POST approve", 1, 401}}, true, ""},
+		{"merge fails", []failure{{"PUT merge", 3, 422}}, true, ""},
+		{"merge forbidden", []failure{{"PUT merge", 1, 403}}, false, ggl.ReasonCannotMerge},
+		{"approve and merge fail", []failure{{"POST approve", 1, 422}, {"PUT merge", 1, 422}}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.RenovateBump()
+			scenario.Statuses = nil
+			clock := fakegitlab.NewClock(time.Now())
+			h := newHarness(t, scenario, ggl.WithClock(clock))
+			for _, f := range tt.failures {
+				h.Server.FailWith(f.endpoint, f.n, f.code)
+			}
+			enable(t, h.Manager, 101)
+			for range 10 {
+				clock.Advance(2 * time.Hour)
+				processOnce(t, h.Manager, 101)
+			}
+			target, err := h.Manager.Target(101)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged := h.Server.MergeRequest(101).State == "merged"; merged != tt.merged {
+				t.Errorf("merged %t, want %t: %s", merged, tt.merged, target.Info)
+			}
+			if !tt.merged && (target.Active || target.Outcome.Reason != tt.reason) {
+				t.Errorf("target %s: %s", target.Outcome.State, target.Info)
+			}
+			// failed merges are retried, but nothing is merged again once it went through
+			want := 0
+			for _, f := range tt.failures {
+				if f.endpoint == "PUT merge" {
+					want += f.n
+				}
+			}
+			if tt.merged {
+				want++
+			}
+			if calls := mergeCalls(h.Server, 1); calls != want {
+				t.Errorf("%d merge calls, want %d", calls, want)
+			}
+		})
+	}
+}
+
+// TestProcessMergeFlapping processes merge requests whose status changes randomly on every read while they get new
+// pushes and the api fails in between. Whatever the sequence, a merge request is never merged after a push changed
+// its diff, and never merged twice.
+func TestProcessMergeFlapping(t *testing.T) {
+	endpoints := []string{"POST approve", "PUT merge", "GET diffs"}
+	codes := []int{401, 403, 422}
+	for seed := int64(1); seed <= 10; seed++ {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(seed))
+			scenario := fakegitlab.Flapping(seed, 40)
+			clock := fakegitlab.NewClock(time.Now())
+			h := newHarness(t, scenario, ggl.WithClock(clock))
+			pushed := make(map[int]bool)
+			merged := make(map[int]int)
+			for _, mr := range scenario.MergeRequests {
+				enable(t, h.Manager, mr.ID)
+			}
+			for step := range 25 {
+				mr := scenario.MergeRequests[rnd.Intn(len(scenario.MergeRequests))]
+				switch rnd.Intn(3) {
+				case 0:
+					if h.Server.MergeRequest(mr.ID).State != "merged" {
+						h.Server.SetDiff(mr.ID, fakegitlab.Diff("go.mod", fmt.Sprintf("@@ -1 +1 @@\n-example.com/dep v1.0.0\n+example.com/dep v1.%d.0\n", step+2)))
+						pushed[mr.ID] = true
+					}
+				case 1:
+					h.Server.FailWith(endpoints[rnd.Intn(len(endpoints))], 1+rnd.Intn(3), codes[rnd.Intn(len(codes))])
+				}
+				clock.Advance(2 * time.Hour)
+				if _, err := h.Manager.ProcessOnce(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, mr := range scenario.MergeRequests {
+					if h.Server.MergeRequest(mr.ID).State != "merged" {
+						continue
+					}
+					if pushed[mr.ID] {
+						t.Fatalf("step %d: merged %d after its diff changed", step, mr.ID)
+					}
+					calls := mergeCalls(h.Server, mr.IID)
+					if last, ok := merged[mr.ID]; ok && calls != last {
+						t.Fatalf("step %d: merged %d again", step, mr.ID)
+					}
+					merged[mr.ID] = calls
+				}
+			}
+		})
+	}
+}
+
+// pushAfterRead pushes to the merge request once right after it was read, before it is merged
+type pushAfterRead struct {
+	ggl.MergeRequestsService
+	push func()
+	once sync.Once
+}
+
+func (p *pushAfterRead) GetMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.GetMergeRequestsOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	mr, resp, err := p.MergeRequestsService.GetMergeRequest(pid, mergeRequest, opt, options...)
+	p.once.Do(p.push)
+	return mr, resp, err
+}
+
+// pushingInstance is a manager of the fake whose first read of merge request 101 is followed by a push changing its
+// diff
+func pushingInstance(t *testing.T, h *fakegitlab.Harness, clock *fakegitlab.Clock) *ggl.MergeRequestManager {
+	t.Helper()
+	gl, err := h.Server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ggl.WrapClient(gl)
+	client.MergeRequests = &pushAfterRead{MergeRequestsService: client.MergeRequests, push: func() {
+		h.Server.SetDiff(101, fakegitlab.Diff("go.mod", "@@ -1 +1 @@\n-golang.org/x/net v0.35.0\n+golang.org/x/net v0.37.0\n"))
+	}}
+	m := secondInstance(t, h, ggl.WithClient(client), ggl.WithClock(clock))
+	if err := m.Author("renovate-bot").FetchMergeRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestPushBetweenReadAndMerge(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	scenario.Statuses = nil
+	scenario.MergeRequests[0].DetailedMergeStatus = "mergeable"
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario)
+	m := pushingInstance(t, h, clock)
+
+	// the merge names the head that was read, gitlab refuses it after the push
+	enable(t, m, 101)
+	if target := processOnce(t, m, 101); !target.Active || target.Outcome.State != ggl.OutcomeError {
+		t.Fatalf("target %s: %s", target.Outcome.State, target.Info)
+	}
+	if state := h.Server.MergeRequest(101).State; state == "merged" {
+		t.Fatal("merged the pushed merge request")
+	}
+	clock.Advance(2 * time.Hour)
+	target := processOnce(t, m, 101)
+	if target.Active || target.Outcome.Reason != ggl.ReasonDiffChanged {
+		t.Errorf("target %s: %s", target.Outcome.State, target.Info)
+	}
+	if state := h.Server.MergeRequest(101).State; state == "merged" {
+		t.Error("merged the pushed merge request")
+	}
+}
+
+func TestPushBetweenReadAndApprove(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	scenario.Statuses = nil
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario)
+	m := pushingInstance(t, h, clock)
+
+	// the approval names the head that was read, gitlab refuses it after the push
+	enable(t, m, 101)
+	target := processOnce(t, m, 101)
+	if target.Active || target.Outcome.Reason != ggl.ReasonDiffChanged {
+		t.Errorf("target %s: %s", target.Outcome.State, target.Info)
+	}
+	if sha := h.Server.ApprovedAt(101); sha != "" {
+		t.Errorf("approved the pushed head %s", sha)
+	}
+	if state := h.Server.MergeRequest(101).State; state == "merged" {
+		t.Error("merged the pushed merge request")
+	}
+}
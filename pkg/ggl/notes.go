@@ -0,0 +1,32 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+)
+
+// notesLimit is the number of the oldest comments fetched for a merge request
+const notesLimit = 50
+
+// MergeRequestNotes fetches the comments of the merge request, oldest first, without the system notes gitlab adds
+// for pushes, label and status changes
+func (m *MergeRequestManager) MergeRequestNotes(ctx context.Context, id int) ([]*gitlab.Note, error) {
+	if m.gl.Notes == nil {
+		return nil, errors.New("the client has no notes api")
+	}
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	notes, _, err := m.gl.Notes.ListMergeRequestNotes(mr.ProjectID, mr.IID, &gitlab.ListMergeRequestNotesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: notesLimit},
+		OrderBy:     gitlab.Ptr("created_at"),
+		Sort:        gitlab.Ptr("asc"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(notes, func(n *gitlab.Note) bool { return n.System }), nil
+}
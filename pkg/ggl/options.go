@@ -0,0 +1,253 @@
+package ggl
+
+import (
+	"github.com/cockroachdb/pebble"
+	"github.com/xanzy/go-gitlab"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// Clock provides the current time and the timers of the scheduling to the MergeRequestManager, a fake clock lets
+// tests run the scheduling deterministically
+type Clock interface {
+	Now() time.Time
+	// NewTimer sends the time after d like time.NewTimer, a timer that isn't waited for any more must be stopped
+	NewTimer(d time.Duration) Timer
+	// NewTicker sends the time every d like time.NewTicker
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a timer of a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Ticker is a ticker of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (t realTimer) Stop() {
+	t.Timer.Stop()
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// Option configures a MergeRequestManager
+type Option func(m *MergeRequestManager)
+
+// WithGitLab uses the go-gitlab client to talk to gitlab
+func WithGitLab(gl *gitlab.Client) Option {
+	return func(m *MergeRequestManager) {
+		m.gl = WrapClient(gl)
+	}
+}
+
+// WithClient uses the given service implementations to talk to gitlab
+func WithClient(client *Client) Option {
+	return func(m *MergeRequestManager) {
+		m.gl = client
+	}
+}
+
+// WithDB stores merge requests, projects and merge targets in the given database
+func WithDB(db *pebble.DB) Option {
+	return func(m *MergeRequestManager) {
+		m.db = db
+	}
+}
+
+// WithLogger sets the logger, defaults to slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *MergeRequestManager) {
+		m.logger = logger
+	}
+}
+
+// ProjectFilter limits the projects kept in the project cache
+type ProjectFilter struct {
+	// Membership only keeps projects the user is a member of
+	Membership bool
+	// Archived also keeps archived projects
+	Archived bool
+	// MinAccessLevel only keeps projects the user has at least this access level in (e.g. 30 for developer), 0 for any
+	MinAccessLevel int
+	// Owned only keeps projects owned by the user
+	Owned bool
+}
+
+// DefaultProjectFilter keeps the non-archived projects the user is a member of
+var DefaultProjectFilter = ProjectFilter{Membership: true}
+
+// WithProjectFilter sets which projects are fetched into the project cache, defaults to DefaultProjectFilter
+func WithProjectFilter(filter ProjectFilter) Option {
+	return func(m *MergeRequestManager) {
+		m.projectFilter = filter
+	}
+}
+
+// WithRequireSignedCommits only approves merge requests whose commits all have a verified signature
+func WithRequireSignedCommits(require bool) Option {
+	return func(m *MergeRequestManager) {
+		m.requireSignedCommits = require
+	}
+}
+
+// WithJiraKey adds the jira issue key (e.g. a standing DEPS-123 ticket) to the description of merge requests that
+// are blocked because they are not associated with a jira issue
+func WithJiraKey(key string) Option {
+	return func(m *MergeRequestManager) {
+		m.jiraKey = key
+	}
+}
+
+// WithStatusActions overrides the handling of detailed merge statuses with StatusActionRetry or StatusActionAbort,
+// e.g. for statuses added by newer gitlab versions
+func WithStatusActions(actions map[string]string) Option {
+	return func(m *MergeRequestManager) {
+		m.statusActions = actions
+	}
+}
+
+// WithApprovalPassword sets the password sent along approvals on instances that require re-authentication to approve
+func WithApprovalPassword(password string) Option {
+	return func(m *MergeRequestManager) {
+		m.approvalPassword.Store(&password)
+	}
+}
+
+// WithProjectPermissions restricts per project path (e.g. group/infra) whether merge requests are approved, merged,
+// both (the default) or neither (observe-only), see ProjectAllowBoth
+func WithProjectPermissions(permissions map[string]string) Option {
+	return func(m *MergeRequestManager) {
+		m.projectPermissions = permissions
+	}
+}
+
+// WithMergeLimits throttles merges to at most perHour in the last hour and perDay in the last day, 0 is unlimited
+func WithMergeLimits(perHour int, perDay int) Option {
+	return func(m *MergeRequestManager) {
+		m.maxMergesPerHour = perHour
+		m.maxMergesPerDay = perDay
+	}
+}
+
+// WithAgeLimits holds merge requests younger than minAge to give humans a chance to object and stops processing
+// merge requests older than maxAge, closing them if closeStale is set. 0 disables a limit.
+func WithAgeLimits(minAge time.Duration, maxAge time.Duration, closeStale bool) Option {
+	return func(m *MergeRequestManager) {
+		m.minAge = minAge
+		m.maxAge = maxAge
+		m.closeStale = closeStale
+	}
+}
+
+// WithExcludedAuthors never processes merge requests of the authors, e.g. humans whose merge requests show up when
+// filtering by reviewer. They stay visible.
+func WithExcludedAuthors(usernames []string) Option {
+	return func(m *MergeRequestManager) {
+		m.excludedAuthors = usernames
+	}
+}
+
+// WithSourceBranchPattern only considers merge requests whose source branch matches the pattern (e.g. ^renovate/),
+// guarding against a reused or spoofed author username
+func WithSourceBranchPattern(pattern *regexp.Regexp) Option {
+	return func(m *MergeRequestManager) {
+		m.sourceBranches = pattern
+	}
+}
+
+// WithFlakyJobs retries a failed pipeline up to retries times if only jobs matching the pattern failed (e.g.
+// ^e2e-), each retry is recorded in the history of the target
+func WithFlakyJobs(pattern *regexp.Regexp, retries int) Option {
+	return func(m *MergeRequestManager) {
+		m.flakyJobs = pattern
+		m.flakyRetries = retries
+	}
+}
+
+// WithLeaderLock lets only one of the instances using the same lock project approve and merge, the others stand by
+// until its lease expires. holder identifies this instance (e.g. the hostname).
+func WithLeaderLock(project string, holder string) Option {
+	return func(m *MergeRequestManager) {
+		m.leader = &leaderLock{project: project, holder: holder}
+	}
+}
+
+// WithClock sets the clock used for scheduling, defaults to the system clock
+func WithClock(clock Clock) Option {
+	return func(m *MergeRequestManager) {
+		m.clock = clock
+	}
+}
+
+// WithGitLabURL selects the gitlab instance of NewDefaultMergeRequestManager, GetDefaultClient and the token
+// functions, empty uses the default, see DefaultURL
+func WithGitLabURL(url string) Option {
+	return func(m *MergeRequestManager) {
+		m.instance.url = url
+	}
+}
+
+// WithDbPath selects the database directory of NewDefaultMergeRequestManager and GetDefaultDb, empty uses the per
+// host default
+func WithDbPath(dir string) Option {
+	return func(m *MergeRequestManager) {
+		m.instance.dbPath = dir
+	}
+}
+
+// WithPassphraseFunc asks for the passphrase of encrypted tokens if PassphraseEnv isn't set, e.g. on the terminal.
+// Without it encrypted tokens can only be unlocked with PassphraseEnv.
+func WithPassphraseFunc(passphrase func() ([]byte, error)) Option {
+	return func(m *MergeRequestManager) {
+		m.instance.passphrase = passphrase
+	}
+}
+
+// WithInstancePicker selects the instance to use if tokens for several gitlab instances are stored and none is
+// selected with WithGitLabURL. Without it DefaultURL fails listing the instances.
+func WithInstancePicker(pick func(instances []string) (string, error)) Option {
+	return func(m *MergeRequestManager) {
+		m.instance.pickInstance = pick
+	}
+}
+
+// WithAPIStats counts the api calls of the client created by NewDefaultMergeRequestManager in stats
+func WithAPIStats(stats *APIStats) Option {
+	return func(m *MergeRequestManager) {
+		m.apiStats = stats
+	}
+}
@@ -0,0 +1,35 @@
+package ggl
+
+import (
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+)
+
+// abort reasons of merge targets the token user lacks the permissions for
+const (
+	ReasonCannotApprove = "insufficient permissions to approve"
+	ReasonCannotMerge   = "insufficient permissions (needs Maintainer)"
+)
+
+// isForbidden reports whether gitlab answered the request with 403 Forbidden
+func isForbidden(err error) bool {
+	var e *gitlab.ErrorResponse
+	return errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusForbidden
+}
+
+// canMerge reports whether the access level of the token user in the project allows merging into the default
+// branch. Projects without permissions in the cache are assumed to allow it.
+func canMerge(p *gitlab.Project) bool {
+	if p.Permissions == nil {
+		return true
+	}
+	level := gitlab.NoPermissions
+	if a := p.Permissions.ProjectAccess; a != nil {
+		level = max(level, a.AccessLevel)
+	}
+	if a := p.Permissions.GroupAccess; a != nil {
+		level = max(level, a.AccessLevel)
+	}
+	return level >= gitlab.MaintainerPermissions
+}
@@ -0,0 +1,53 @@
+package ggl_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"testing"
+)
+
+func TestForbiddenAbortsTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		endpoint string
+		reason   string
+	}{
+		{"approve", "not_approved", "POST approve", ggl.ReasonCannotApprove},
+		{"merge", "mergeable", "PUT merge", ggl.ReasonCannotMerge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.RenovateBump()
+			scenario.MergeRequests[0].DetailedMergeStatus = tt.status
+			scenario.Statuses = nil
+			h := newHarness(t, scenario)
+			enable(t, h.Manager, 101)
+			h.Server.FailWith(tt.endpoint, 1, http.StatusForbidden)
+
+			target := processOnce(t, h.Manager, 101)
+			if target.Active || target.Outcome.State != ggl.OutcomeAborted || target.Outcome.Reason != tt.reason {
+				t.Errorf("target is active %v with outcome %+v, want aborted with %q", target.Active, target.Outcome, tt.reason)
+			}
+			if state := h.Server.MergeRequest(101).State; state != "opened" {
+				t.Errorf("merge request is %s, want opened", state)
+			}
+		})
+	}
+}
+
+func TestCanMergeFromProjectAccess(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	scenario.Projects[0].Permissions = &gitlab.Permissions{ProjectAccess: &gitlab.ProjectAccess{AccessLevel: gitlab.DeveloperPermissions}}
+	scenario.Projects[1].Permissions = &gitlab.Permissions{GroupAccess: &gitlab.GroupAccess{AccessLevel: gitlab.MaintainerPermissions}}
+	h := newHarness(t, scenario)
+
+	if mergeRequestInfo(t, h.Manager, 201).CanMerge {
+		t.Error("developers can merge in project 1, want only maintainers")
+	}
+	if !mergeRequestInfo(t, h.Manager, 203).CanMerge {
+		t.Error("group maintainers can't merge in project 2")
+	}
+}
@@ -0,0 +1,120 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+)
+
+// maxPipelineDepth limits how deep nested downstream pipelines are followed
+const maxPipelineDepth = 3
+
+// aggregated states of the downstream pipelines of a merge request
+const (
+	downstreamNone    = ""
+	downstreamSuccess = "success"
+	downstreamRunning = "running"
+	downstreamFailed  = "failed"
+)
+
+var runningPipelineStatuses = []string{"created", "waiting_for_resource", "preparing", "pending", "running", "scheduled", "manual"}
+
+// downstreamStatus aggregates the status of the child and multi-project pipelines triggered by the head pipeline of
+// the merge request: failed if any of them failed, running if any is still running, success otherwise and none
+// if there are no downstream pipelines. Bridges that are allowed to fail are ignored.
+func (m *MergeRequestManager) downstreamStatus(ctx context.Context, mr *gitlab.MergeRequest) (string, error) {
+	if mr.HeadPipeline == nil || m.gl.Jobs == nil {
+		return downstreamNone, nil
+	}
+	return m.pipelineDownstreamStatus(ctx, mr.HeadPipeline.ProjectID, mr.HeadPipeline.ID, 1)
+}
+
+func (m *MergeRequestManager) pipelineDownstreamStatus(ctx context.Context, projectID int, pipelineID int, depth int) (string, error) {
+	bridges, _, err := m.gl.Jobs.ListPipelineBridges(projectID, pipelineID, &gitlab.ListJobsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return downstreamNone, err
+	}
+	status := downstreamNone
+	for _, b := range bridges {
+		if b.AllowFailure || b.DownstreamPipeline == nil {
+			continue
+		}
+		s := pipelineState(b.DownstreamPipeline.Status)
+		if s == downstreamSuccess && depth < maxPipelineDepth {
+			nested, err := m.pipelineDownstreamStatus(ctx, b.DownstreamPipeline.ProjectID, b.DownstreamPipeline.ID, depth+1)
+			if err != nil {
+				return downstreamNone, err
+			}
+			if nested != downstreamNone {
+				s = nested
+			}
+		}
+		status = worse(status, s)
+	}
+	return status, nil
+}
+
+func pipelineState(status string) string {
+	switch {
+	case status == "success", status == "skipped":
+		return downstreamSuccess
+	case slices.Contains(runningPipelineStatuses, status):
+		return downstreamRunning
+	}
+	return downstreamFailed
+}
+
+// worse returns the state that blocks merging more
+func worse(a string, b string) string {
+	order := []string{downstreamNone, downstreamSuccess, downstreamRunning, downstreamFailed}
+	if slices.Index(order, b) > slices.Index(order, a) {
+		return b
+	}
+	return a
+}
+
+// ListProjectPipelines lists the latest pipelines of a project, of all refs if ref is empty
+func (m *MergeRequestManager) ListProjectPipelines(ctx context.Context, project string, ref string, limit int) ([]*gitlab.PipelineInfo, error) {
+	if m.gl.Pipelines == nil {
+		return nil, errors.New("the client has no pipelines api")
+	}
+	opts := &gitlab.ListProjectPipelinesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: min(limit, 100),
+			Page:    1,
+		},
+		OrderBy: gitlab.Ptr("id"),
+		Sort:    gitlab.Ptr("desc"),
+	}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	var pipelines []*gitlab.PipelineInfo
+	for len(pipelines) < limit {
+		page, resp, err := m.gl.Pipelines.ListProjectPipelines(project, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return pipelines[:min(len(pipelines), limit)], nil
+}
+
+// LintCi validates the ci configuration in the context of the project, includes are resolved at ref or the default
+// branch if ref is empty
+func (m *MergeRequestManager) LintCi(ctx context.Context, project string, content string, ref string) (*gitlab.ProjectLintResult, error) {
+	if m.gl.Validate == nil {
+		return nil, errors.New("the client has no ci lint api")
+	}
+	opts := &gitlab.ProjectNamespaceLintOptions{Content: gitlab.Ptr(content)}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	result, _, err := m.gl.Validate.ProjectNamespaceLint(project, opts, gitlab.WithContext(ctx))
+	return result, err
+}
@@ -0,0 +1,47 @@
+package ggl_test
+
+import (
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"testing"
+	"time"
+)
+
+func TestDownstreamPipelinesBlockMerge(t *testing.T) {
+	scenario := fakegitlab.RenovateBump()
+	p := scenario.Projects[0]
+	mr := scenario.MergeRequests[0]
+	mr.DetailedMergeStatus = "mergeable"
+	mr.HeadPipeline = &gitlab.Pipeline{ID: 500, ProjectID: p.ID, Status: "success"}
+	scenario.Statuses = nil
+	optional := fakegitlab.Bridge("docs", p, 502, "failed")
+	optional.AllowFailure = true
+	scenario.Bridges = map[int][]*gitlab.Bridge{500: {fakegitlab.Bridge("deploy-preview", p, 501, "running"), optional}}
+	clock := fakegitlab.NewClock(time.Now())
+	h := newHarness(t, scenario, ggl.WithClock(clock))
+	enable(t, h.Manager, mr.ID)
+
+	if target := processOnce(t, h.Manager, mr.ID); target.Info != "downstream pipelines running - will check again in 1 minute" {
+		t.Errorf("target with a running child pipeline is %q", target.Info)
+	}
+
+	// the child pipeline passed but triggered a pipeline that failed
+	h.Server.Load(fakegitlab.Scenario{Bridges: map[int][]*gitlab.Bridge{
+		500: {fakegitlab.Bridge("deploy-preview", p, 501, "success"), optional},
+		501: {fakegitlab.Bridge("e2e", p, 503, "failed")},
+	}})
+	clock.Advance(time.Minute)
+	if target := processOnce(t, h.Manager, mr.ID); target.Info != "downstream pipelines failed - will check again in 1 minute" {
+		t.Errorf("target with a failed nested pipeline is %q", target.Info)
+	}
+	if state := h.Server.MergeRequest(mr.ID).State; state != "opened" {
+		t.Fatalf("merge request is %s while downstream pipelines failed", state)
+	}
+
+	h.Server.Load(fakegitlab.Scenario{Bridges: map[int][]*gitlab.Bridge{501: {fakegitlab.Bridge("e2e", p, 503, "success")}}})
+	clock.Advance(time.Minute)
+	if target := processOnce(t, h.Manager, mr.ID); target.Outcome.State != ggl.OutcomeMerged {
+		t.Errorf("target is %s once the downstream pipelines passed, want merged", target.Outcome.State)
+	}
+}
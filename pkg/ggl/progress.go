@@ -0,0 +1,27 @@
+package ggl
+
+import "fmt"
+
+// Progress describes how far a multi-page fetch got
+type Progress struct {
+	// What is being fetched, e.g. "merge requests"
+	What string
+	Page int
+	// Pages is the total number of pages or 0 if gitlab does not report it (keyset pagination)
+	Pages int
+	// Items is the number of items fetched so far
+	Items int
+}
+
+func (p Progress) String() string {
+	if p.Pages > 0 {
+		return fmt.Sprintf("%s: page %d of %d, %d items", p.What, p.Page, p.Pages, p.Items)
+	}
+	return fmt.Sprintf("%s: page %d, %d items", p.What, p.Page, p.Items)
+}
+
+// reportProgress logs the progress at debug level and reports it to the subscribers
+func (m *MergeRequestManager) reportProgress(p Progress) {
+	m.logger.Debug("fetching "+p.What, "page", p.Page, "pages", p.Pages, "items", p.Items)
+	m.emit(Event{Type: EventProgress, Progress: &p})
+}
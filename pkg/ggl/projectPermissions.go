@@ -0,0 +1,60 @@
+package ggl
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// what the tool may do in a project, configured per project path
+const (
+	ProjectAllowBoth    = "both"
+	ProjectAllowApprove = "approve"
+	ProjectAllowMerge   = "merge"
+	ProjectAllowNone    = "none"
+)
+
+// ReasonMergeByHuman is the info of approved targets in projects where a human has to merge
+const ReasonMergeByHuman = "waiting for a human to merge (auto-merge disabled for project)"
+
+// validateProjectPermissions checks that only known permissions are configured
+func validateProjectPermissions(permissions map[string]string) error {
+	for project, allow := range permissions {
+		switch allow {
+		case ProjectAllowBoth, ProjectAllowApprove, ProjectAllowMerge, ProjectAllowNone:
+		default:
+			return fmt.Errorf("unknown permission %q for project %s (use %s, %s, %s or %s)", allow, project,
+				ProjectAllowBoth, ProjectAllowApprove, ProjectAllowMerge, ProjectAllowNone)
+		}
+	}
+	return nil
+}
+
+// projectAllows reports whether the tool may approve and merge in the project, both are allowed if nothing is
+// configured for it
+func (m *MergeRequestManager) projectAllows(projectID int) (approve bool, merge bool) {
+	if len(m.projectPermissions) == 0 {
+		return true, true
+	}
+	p, err := m.GetProject(projectID)
+	if err != nil {
+		return true, true
+	}
+	switch m.projectPermissions[p.PathWithNamespace] {
+	case ProjectAllowApprove:
+		return true, false
+	case ProjectAllowMerge:
+		return false, true
+	case ProjectAllowNone:
+		return false, false
+	}
+	return true, true
+}
+
+// errObserveOnly is returned when acting on merge requests of observe-only projects
+var errObserveOnly = errors.New("project is observe-only")
+
+// humanMergeOutcome waits for somebody else to merge an approved merge request
+func humanMergeOutcome() MergeOutcome {
+	return retryOutcome(ReasonMergeByHuman, 5*time.Minute)
+}
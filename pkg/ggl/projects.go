@@ -0,0 +1,81 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"strings"
+)
+
+// SearchProjects searches the cached projects for term in name and path (case-insensitive)
+func (m *MergeRequestManager) SearchProjects(ctx context.Context, term string) ([]gitlab.Project, error) {
+	err := m.FetchProjectsIfNotOutdated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := m.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	term = strings.ToLower(term)
+	var found []gitlab.Project
+	for _, p := range projects {
+		if strings.Contains(strings.ToLower(p.NameWithNamespace), term) || strings.Contains(strings.ToLower(p.PathWithNamespace), term) {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+// SearchRemoteProjects searches the projects on gitlab and adds the results to the cache
+func (m *MergeRequestManager) SearchRemoteProjects(ctx context.Context, term string) ([]gitlab.Project, error) {
+	opts := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 50,
+			Page:    1,
+		},
+		Search:           gitlab.Ptr(term),
+		SearchNamespaces: gitlab.Ptr(true),
+	}
+	var found []gitlab.Project
+	for {
+		projects, resp, err := m.gl.Projects.ListProjects(opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			err := m.storeFetched(projectKey(project.ID), project)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, *project)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return found, nil
+}
+
+// ListGroups lists all groups visible to the token
+func (m *MergeRequestManager) ListGroups(ctx context.Context) ([]*gitlab.Group, error) {
+	opts := &gitlab.ListGroupsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 50,
+			Page:    1,
+		},
+	}
+	var groups []*gitlab.Group
+	for {
+		page, resp, err := m.gl.Groups.ListGroups(opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return groups, nil
+}
@@ -0,0 +1,43 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"runtime/debug"
+)
+
+// maxQuarantineStack bounds the stack trace kept in the history of a quarantined target
+const maxQuarantineStack = 4096
+
+// processMergeSafely processes the target like processMerge, but a panic quarantines the target instead of killing the
+// processor, so the other targets are still merged
+func (m *MergeRequestManager) processMergeSafely(ctx context.Context, target mergeTarget, mr *gitlab.MergeRequest) (outcome MergeOutcome) {
+	defer m.quarantineOnPanic(target, &outcome)
+	return m.processMerge(ctx, target, mr)
+}
+
+// quarantineOnPanic recovers a panic while processing the target, stops processing it and keeps the stack trace in
+// its history. The target stays quarantined until it is enabled again.
+func (m *MergeRequestManager) quarantineOnPanic(target mergeTarget, outcome *MergeOutcome) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	m.logger.Error("processing panicked, quarantining target", "target", target.Id, "panic", r, "stack", stack)
+	if len(stack) > maxQuarantineStack {
+		stack = stack[:maxQuarantineStack] + "\n..."
+	}
+	*outcome = MergeOutcome{State: OutcomeQuarantined, Reason: fmt.Sprint(r)}
+	now := m.clock.Now()
+	target.Active = false
+	target.Latest = now
+	target.Next = now
+	target.Outcome = *outcome
+	target.Info = outcome.Info()
+	target.record(now, string(OutcomeQuarantined), fmt.Sprintf("panic: %v\n%s", r, stack))
+	m.storeTargetSilent(target)
+	m.recordReportEntry(target, *outcome)
+	m.emit(outcomeEvent(target.Id, *outcome))
+}
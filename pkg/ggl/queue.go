@@ -0,0 +1,61 @@
+package ggl
+
+// processQueueSize bounds the targets waiting for the processor. The enqueuer never blocks on a full queue, the
+// targets that didn't fit are queued in its next round.
+const processQueueSize = 64
+
+// enqueue queues the target for the processor unless it is already queued or being processed or the queue is full,
+// it reports whether the target was queued
+func (m *MergeRequestManager) enqueue(id int) bool {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	if _, ok := m.pending[id]; ok {
+		return false
+	}
+	select {
+	case m.processQueue <- id:
+		m.pending[id] = struct{}{}
+		return true
+	default:
+		m.logger.Debug("process queue full, target waits for the next round", "target", id, "depth", len(m.processQueue))
+		return false
+	}
+}
+
+// dequeued allows to queue the target again after it was processed
+func (m *MergeRequestManager) dequeued(id int) {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	delete(m.pending, id)
+}
+
+// QueueDepth is the number of targets waiting for the processor
+func (m *MergeRequestManager) QueueDepth() int {
+	return len(m.processQueue)
+}
+
+// wake lets the enqueuer check the targets right away instead of after its pause
+func (m *MergeRequestManager) wake() {
+	select {
+	case m.wakeEnqueuer <- struct{}{}:
+	default:
+	}
+}
+
+// claim marks the target as being processed, false if another worker is processing it already
+func (m *MergeRequestManager) claim(id int) bool {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	if _, ok := m.inFlight[id]; ok {
+		return false
+	}
+	m.inFlight[id] = struct{}{}
+	return true
+}
+
+// release ends the processing of a claimed target
+func (m *MergeRequestManager) release(id int) {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	delete(m.inFlight, id)
+}
@@ -0,0 +1,192 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"strconv"
+	"strings"
+)
+
+// ParseReference splits a human merge request reference like group/project!12 into project and iid.
+// The project is empty for short references like !12.
+func ParseReference(ref string) (string, int, error) {
+	i := strings.LastIndex(ref, "!")
+	if i < 0 || i == len(ref)-1 {
+		return "", 0, fmt.Errorf("invalid merge request reference %q (expected project!iid)", ref)
+	}
+	iid, err := strconv.Atoi(ref[i+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid merge request iid in %q: %w", ref, err)
+	}
+	return ref[:i], iid, nil
+}
+
+// ResolveReference looks up the project of a project!iid reference in the cached projects.
+// The project can be given by its full path or by its name or path if that is unique. If it is omitted (!iid)
+// the project the remote of the local git repository points to is used.
+func (m *MergeRequestManager) ResolveReference(ctx context.Context, ref string, remote string) (*gitlab.Project, int, error) {
+	projectRef, iid, err := ParseReference(ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	if projectRef == "" {
+		projectRef = m.LocalDefaultProject(remote)
+		if projectRef == "" {
+			return nil, 0, fmt.Errorf("no project in reference %q and not inside a clone of a project on %s", ref, m.gl.BaseURL.Hostname())
+		}
+	}
+	err = m.FetchProjectsIfNotOutdated(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	projects, err := m.GetProjects()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matches []gitlab.Project
+	for _, p := range projects {
+		if p.PathWithNamespace == projectRef {
+			return &p, iid, nil
+		}
+		if p.Path == projectRef || p.Name == projectRef {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		project, _, err := m.gl.Projects.GetProject(projectRef, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, 0, err
+		}
+		err = m.storeFetched(projectKey(project.ID), project)
+		return project, iid, err
+	case 1:
+		return &matches[0], iid, nil
+	default:
+		return nil, 0, fmt.Errorf("project %q is ambiguous, use the full path (e.g. %s)", projectRef, matches[0].PathWithNamespace)
+	}
+}
+
+// FetchByReference fetches and caches the merge request referenced by project!iid
+func (m *MergeRequestManager) FetchByReference(ctx context.Context, ref string, remote string) (*gitlab.MergeRequest, error) {
+	project, iid, err := m.ResolveReference(ctx, ref, remote)
+	if err != nil {
+		return nil, err
+	}
+	mr, _, err := m.gl.MergeRequests.GetMergeRequest(project.ID, iid, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return mr, m.storeFetched(mrKey(mr.ID), mr)
+}
+
+// ApproveByReference approves the merge request referenced by project!iid unless the project or its author are
+// excluded from approving
+func (m *MergeRequestManager) ApproveByReference(ctx context.Context, ref string, remote string) (*gitlab.MergeRequest, error) {
+	project, iid, err := m.ResolveReference(ctx, ref, remote)
+	if err != nil {
+		return nil, err
+	}
+	current, _, err := m.gl.MergeRequests.GetMergeRequest(project.ID, iid, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if m.Excluded(current) {
+		return nil, errExcludedAuthor
+	}
+	if approve, _ := m.projectAllows(project.ID); !approve {
+		return nil, errors.New("approving is disabled for the project")
+	}
+	_, _, err = m.gl.MergeRequestApprovals.ApproveMergeRequest(project.ID, iid, &gitlab.ApproveMergeRequestOptions{},
+		append(m.approveOptions(), gitlab.WithContext(ctx))...)
+	if isUnauthorized(err) {
+		return nil, errors.New(ReasonApprovalPassword)
+	}
+	if err != nil {
+		return nil, err
+	}
+	mr, _, err := m.gl.MergeRequests.GetMergeRequest(project.ID, iid, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return mr, m.storeFetched(mrKey(mr.ID), mr)
+}
+
+// MergeByReference merges the current head of the merge request referenced by project!iid with the squash setting
+// of the project, unless the project or its author are excluded from merging
+func (m *MergeRequestManager) MergeByReference(ctx context.Context, ref string, remote string) (*gitlab.MergeRequest, error) {
+	project, iid, err := m.ResolveReference(ctx, ref, remote)
+	if err != nil {
+		return nil, err
+	}
+	current, _, err := m.gl.MergeRequests.GetMergeRequest(project.ID, iid, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if m.Excluded(current) {
+		return nil, errExcludedAuthor
+	}
+	if _, merge := m.projectAllows(project.ID); !merge {
+		return nil, errors.New("merging is disabled for the project")
+	}
+	mr, _, err := m.gl.MergeRequests.AcceptMergeRequest(project.ID, iid, m.acceptOptions(current), gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return mr, m.storeFetched(mrKey(mr.ID), mr)
+}
+
+// CheckoutByReference checks out the merge request referenced by project!iid in the local repository,
+// which must be a clone of the merge request's project
+func (m *MergeRequestManager) CheckoutByReference(ctx context.Context, ref string, remote string) (string, error) {
+	project, iid, err := m.ResolveReference(ctx, ref, remote)
+	if err != nil {
+		return "", err
+	}
+	host, projectPath, err := LocalProject(remote)
+	if err != nil {
+		return "", err
+	}
+	if host != m.gl.BaseURL.Hostname() || projectPath != project.PathWithNamespace {
+		return "", fmt.Errorf("remote %s points to %s/%s and not to %s", remote, host, projectPath, project.PathWithNamespace)
+	}
+	return CheckoutMergeRequestHead(remote, iid)
+}
+
+// LocalDefaultProject returns the path of the project the remote of the local git repository points to, or an
+// empty string if not inside a git repository with such a remote on the logged-in host
+func (m *MergeRequestManager) LocalDefaultProject(remote string) string {
+	host, projectPath, err := LocalProject(remote)
+	if err != nil || host != m.gl.BaseURL.Hostname() {
+		return ""
+	}
+	return projectPath
+}
+
+// ListProjectMergeRequests lists all merge requests of a project in the given state
+func (m *MergeRequestManager) ListProjectMergeRequests(ctx context.Context, project string, state string) ([]*gitlab.MergeRequest, error) {
+	opt := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: 50,
+		},
+		State:   gitlab.Ptr(state),
+		OrderBy: gitlab.Ptr("updated_at"),
+	}
+	var all []*gitlab.MergeRequest
+	for {
+		mrs, resp, err := m.gl.MergeRequests.ListProjectMergeRequests(project, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, mrs...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return all, nil
+}
@@ -0,0 +1,76 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"testing"
+)
+
+func TestMergeByReference(t *testing.T) {
+	scenario := fakegitlab.Mixed()
+	scenario.Projects[1].SquashOption = gitlab.SquashOptionAlways
+	h := newHarness(t, scenario)
+	// the head moved since the merge request was cached
+	h.Server.SetDiff(203, fakegitlab.Diff("package.json", "@@ -1 +1 @@\n-\"vite\": \"5.0.0\"\n+\"vite\": \"5.2.0\"\n"))
+
+	mr, err := h.Manager.MergeByReference(context.Background(), "group/frontend!4", "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.State != "merged" {
+		t.Errorf("merge request is %s, want merged", mr.State)
+	}
+	if !h.Server.MergeRequest(203).Squash {
+		t.Error("not squashed although the project always squashes")
+	}
+}
+
+func TestApproveByReferenceWithPassword(t *testing.T) {
+	h := newHarness(t, approvalPasswordScenario())
+	h.Server.RequireApprovalPassword("secret")
+
+	_, err := h.Manager.ApproveByReference(context.Background(), "group/service!1", "origin")
+	if err == nil || err.Error() != ggl.ReasonApprovalPassword {
+		t.Errorf("approving without the password returned %v, want %s", err, ggl.ReasonApprovalPassword)
+	}
+	if err := h.Manager.SetApprovalPassword("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Manager.ApproveByReference(context.Background(), "group/service!1", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if h.Server.ApprovedAt(101) == "" {
+		t.Error("not approved with the password")
+	}
+}
+
+func TestByReferenceChecksPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     ggl.Option
+		approve bool
+		merge   bool
+	}{
+		{"both", ggl.WithProjectPermissions(map[string]string{"group/frontend": ggl.ProjectAllowBoth}), true, true},
+		{"observe-only", ggl.WithProjectPermissions(map[string]string{"group/frontend": ggl.ProjectAllowNone}), false, false},
+		{"approve-only", ggl.WithProjectPermissions(map[string]string{"group/frontend": ggl.ProjectAllowApprove}), true, false},
+		{"merge-only", ggl.WithProjectPermissions(map[string]string{"group/frontend": ggl.ProjectAllowMerge}), false, true},
+		{"excluded author", ggl.WithExcludedAuthors([]string{"renovate-bot"}), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, fakegitlab.Mixed(), tt.opt)
+
+			_, err := h.Manager.ApproveByReference(context.Background(), "group/frontend!4", "origin")
+			if approved := h.Server.ApprovedAt(203) != ""; (err == nil) != tt.approve || approved != tt.approve {
+				t.Errorf("approved %t with error %v, want approved %t", approved, err, tt.approve)
+			}
+			_, err = h.Manager.MergeByReference(context.Background(), "group/frontend!4", "origin")
+			if merged := h.Server.MergeRequest(203).State == "merged"; (err == nil) != tt.merge || merged != tt.merge {
+				t.Errorf("merged %t with error %v, want merged %t", merged, err, tt.merge)
+			}
+		})
+	}
+}
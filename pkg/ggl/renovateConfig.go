@@ -0,0 +1,135 @@
+package ggl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"regexp"
+	"strings"
+)
+
+// StopRule is how a dependency is kept out of future renovate merge requests
+type StopRule string
+
+const (
+	// StopIgnore adds the dependency to ignoreDeps, renovate stops updating it
+	StopIgnore StopRule = "ignore"
+	// StopPin only allows versions below the one the merge request updates to
+	StopPin StopRule = "pin"
+)
+
+// renovateConfigFiles are the locations renovate reads its repository config from, in the order it looks for them
+var renovateConfigFiles = []string{"renovate.json", "renovate.json5", ".github/renovate.json", ".github/renovate.json5",
+	".gitlab/renovate.json", ".gitlab/renovate.json5", ".renovaterc", ".renovaterc.json", ".renovaterc.json5"}
+
+var branchUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// StopDependency opens a follow-up merge request that changes the renovate config of the project so the dependency
+// updated by the renovate merge request is ignored or pinned below the proposed version
+func (m *MergeRequestManager) StopDependency(ctx context.Context, id int, rule StopRule) (*gitlab.MergeRequest, error) {
+	mr, err := m.GetMergeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	name, version, ok := Dependency(mr.Title)
+	if !ok {
+		return nil, fmt.Errorf("%q doesn't name a dependency", mr.Title)
+	}
+	if rule == StopPin && version == "" {
+		return nil, fmt.Errorf("%q doesn't name the version to pin below", mr.Title)
+	}
+	project, err := m.GetProject(mr.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	path, config, err := m.renovateConfig(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := stopDependencyInConfig(config, name, version, rule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	title := "Ignore " + name + " in renovate"
+	if rule == StopPin {
+		title = "Pin " + name + " below " + version + " in renovate"
+	}
+	branch := "gitlab-util/" + string(rule) + "-" + strings.Trim(branchUnsafe.ReplaceAllString(name, "-"), "-")
+	_, _, err = m.gl.Commits.CreateCommit(project.ID, &gitlab.CreateCommitOptions{
+		Branch:        gitlab.Ptr(branch),
+		StartBranch:   gitlab.Ptr(project.DefaultBranch),
+		CommitMessage: gitlab.Ptr(title),
+		Actions: []*gitlab.CommitActionOptions{{
+			Action:   gitlab.Ptr(gitlab.FileUpdate),
+			FilePath: gitlab.Ptr(path),
+			Content:  gitlab.Ptr(string(updated)),
+		}},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	followUp, _, err := m.gl.MergeRequests.CreateMergeRequest(project.ID, &gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.Ptr(title),
+		Description:        gitlab.Ptr(fmt.Sprintf("Stops renovate from proposing updates like \"%s\" (!%d, %s).", mr.Title, mr.IID, mr.WebURL)),
+		SourceBranch:       gitlab.Ptr(branch),
+		TargetBranch:       gitlab.Ptr(project.DefaultBranch),
+		RemoveSourceBranch: gitlab.Ptr(true),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("opened merge request to stop dependency", "mr", id, "dependency", name, "rule", rule, "url", followUp.WebURL)
+	return followUp, nil
+}
+
+// renovateConfig reads the renovate config of the project from the default branch
+func (m *MergeRequestManager) renovateConfig(ctx context.Context, project *gitlab.Project) (string, []byte, error) {
+	for _, path := range renovateConfigFiles {
+		data, _, err := m.gl.RepositoryFiles.GetRawFile(project.ID, path, &gitlab.GetRawFileOptions{Ref: gitlab.Ptr(project.DefaultBranch)}, gitlab.WithContext(ctx))
+		if errors.Is(err, gitlab.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return path, data, nil
+	}
+	return "", nil, fmt.Errorf("no renovate config in %s", project.PathWithNamespace)
+}
+
+// stopDependencyInConfig adds the dependency to ignoreDeps or a package rule pinning it below version. Only plain
+// json configs can be changed, keys are written in alphabetical order.
+func stopDependencyInConfig(data []byte, name string, version string, rule StopRule) ([]byte, error) {
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("only json renovate configs can be changed: %w", err)
+	}
+	switch rule {
+	case StopIgnore:
+		ignored, _ := config["ignoreDeps"].([]any)
+		for _, dep := range ignored {
+			if dep == name {
+				return nil, fmt.Errorf("%s is already ignored", name)
+			}
+		}
+		config["ignoreDeps"] = append(ignored, name)
+	case StopPin:
+		rules, _ := config["packageRules"].([]any)
+		config["packageRules"] = append(rules, map[string]any{
+			"matchPackageNames": []string{name},
+			"allowedVersions":   "<" + strings.TrimPrefix(version, "v"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown rule %q", rule)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(config)
+	return b.Bytes(), err
+}
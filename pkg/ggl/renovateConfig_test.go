@@ -0,0 +1,88 @@
+package ggl_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"reflect"
+	"testing"
+)
+
+func TestStopDependency(t *testing.T) {
+	tests := []struct {
+		rule   ggl.StopRule
+		title  string
+		branch string
+		config map[string]any
+	}{
+		{ggl.StopIgnore, "Ignore golang.org/x/net in renovate", "gitlab-util/ignore-golang.org-x-net", map[string]any{
+			"extends":    []any{"config:recommended"},
+			"ignoreDeps": []any{"golang.org/x/net"},
+		}},
+		{ggl.StopPin, "Pin golang.org/x/net below v0.36.0 in renovate", "gitlab-util/pin-golang.org-x-net", map[string]any{
+			"extends": []any{"config:recommended"},
+			"packageRules": []any{map[string]any{
+				"matchPackageNames": []any{"golang.org/x/net"},
+				"allowedVersions":   "<0.36.0",
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.rule), func(t *testing.T) {
+			scenario := fakegitlab.RenovateBump()
+			scenario.Files = map[int]map[string]string{1: {".github/renovate.json": `{"extends": ["config:recommended"]}`}}
+			h := newHarness(t, scenario)
+
+			followUp, err := h.Manager.StopDependency(context.Background(), 101, tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if followUp.Title != tt.title || followUp.SourceBranch != tt.branch || followUp.TargetBranch != "main" {
+				t.Errorf("follow-up merge request %q from %s into %s, want %q from %s into main",
+					followUp.Title, followUp.SourceBranch, followUp.TargetBranch, tt.title, tt.branch)
+			}
+			commits := h.Server.Commits()
+			if len(commits) != 1 || *commits[0].Branch != tt.branch || len(commits[0].Actions) != 1 {
+				t.Fatalf("commits are %+v, want one on %s", commits, tt.branch)
+			}
+			action := commits[0].Actions[0]
+			if *action.FilePath != ".github/renovate.json" {
+				t.Errorf("changed %s, want the existing config .github/renovate.json", *action.FilePath)
+			}
+			var config map[string]any
+			if err := json.Unmarshal([]byte(*action.Content), &config); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(config, tt.config) {
+				t.Errorf("config is %v, want %v", config, tt.config)
+			}
+		})
+	}
+}
+
+func TestStopDependencyFails(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no config", nil},
+		{"already ignored", map[string]string{"renovate.json": `{"ignoreDeps": ["golang.org/x/net"]}`}},
+		{"json5", map[string]string{"renovate.json5": `{extends: ["config:recommended"]}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.RenovateBump()
+			scenario.Files = map[int]map[string]string{1: tt.files}
+			h := newHarness(t, scenario)
+
+			_, err := h.Manager.StopDependency(context.Background(), 101, ggl.StopIgnore)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if commits := h.Server.Commits(); len(commits) > 0 {
+				t.Errorf("committed %+v", commits)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"regexp"
+)
+
+// renovateRebaseCheckbox matches the checkbox renovate adds to the description to ask for a rebase, current versions
+// mark it with a rebase-check comment, older ones only have the text
+var renovateRebaseCheckbox = regexp.MustCompile(`(?m)^(\s*[-*]\s+)\[([ xX])\](\s*(?:<!--\s*rebase-check\s*-->|If you want to rebase))`)
+
+var (
+	errNoRebaseCheckbox       = errors.New("the description has no renovate rebase checkbox")
+	errRebaseAlreadyRequested = errors.New("the renovate rebase checkbox is already ticked, renovate didn't pick it up yet")
+)
+
+// RequestRenovateRebase ticks renovate's rebase checkbox in the description of the merge request, renovate rebases or
+// recreates the branch on its next run. Unlike RebaseMergeRequest this also works if the branch can't be rebased by
+// gitlab, e.g. because of conflicts in lock files.
+func (m *MergeRequestManager) RequestRenovateRebase(ctx context.Context, id int) error {
+	cached, err := m.GetMergeRequest(id)
+	if err != nil {
+		return err
+	}
+	// edit the current description, renovate updates it on every run
+	current, _, err := m.gl.MergeRequests.GetMergeRequest(cached.ProjectID, cached.IID, &gitlab.GetMergeRequestsOptions{}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	match := renovateRebaseCheckbox.FindStringSubmatchIndex(current.Description)
+	if match == nil {
+		return errNoRebaseCheckbox
+	}
+	if current.Description[match[4]:match[5]] != " " {
+		return errRebaseAlreadyRequested
+	}
+	description := current.Description[:match[4]] + "x" + current.Description[match[5]:]
+	mr, _, err := m.gl.MergeRequests.UpdateMergeRequest(current.ProjectID, current.IID, &gitlab.UpdateMergeRequestOptions{
+		Description: gitlab.Ptr(description),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.logger.Info("renovate rebase requested", "mr", id)
+	err = m.storeFetched(mrKey(mr.ID), mr)
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventUpdated, TargetID: id})
+	return nil
+}
@@ -0,0 +1,168 @@
+package ggl
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"github.com/cockroachdb/pebble"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportRetention is how long finished merge targets are kept for reports
+const reportRetention = 90 * 24 * time.Hour
+
+// ReportEntry records a merge target that finished with a merge or an abort
+type ReportEntry struct {
+	Time      time.Time
+	ID        int
+	ProjectID int
+	Project   string
+	Reference string
+	State     OutcomeState
+	Reason    string
+	// OpenedAt is when the merge request was created
+	OpenedAt time.Time
+}
+
+// Report summarizes the work of auto-merge since a point in time
+type Report struct {
+	Since  time.Time
+	Merged int
+	// AverageTimeToMerge is the average time from opening a merge request to merging it
+	AverageTimeToMerge time.Duration
+	Aborted            int
+	AbortsByReason     map[string]int
+	Projects           []ProjectReport
+}
+
+// ProjectReport are the counts of a single project in a Report
+type ProjectReport struct {
+	Project string
+	Merged  int
+	Aborted int
+}
+
+// recordReportEntry stores the finished target for reports and drops entries older than the retention
+func (m *MergeRequestManager) recordReportEntry(target mergeTarget, outcome MergeOutcome) {
+	now := m.clock.Now()
+	entry := ReportEntry{Time: now, ID: target.Id, ProjectID: target.ProjectID, State: outcome.State, Reason: outcome.Reason}
+	if p, err := m.GetProject(target.ProjectID); err == nil {
+		entry.Project = p.PathWithNamespace
+		entry.Reference = p.PathWithNamespace + "!" + strconv.Itoa(target.MergeID)
+	}
+	if mr, err := m.GetMergeRequest(target.Id); err == nil && mr.CreatedAt != nil {
+		entry.OpenedAt = *mr.CreatedAt
+	}
+	err := store(m.db, reportKey(now, target.Id), entry)
+	if err != nil {
+		m.logger.Error("error storing report entry", "target", target.Id, "err", err)
+	}
+	err = m.db.DeleteRange([]byte(reportPrefix), []byte(reportKey(now.Add(-reportRetention), 0)), pebble.Sync)
+	if err != nil {
+		m.logger.Error("error pruning report entries", "err", err)
+	}
+}
+
+// ReportEntries returns the merge targets finished since the given time, oldest first
+func (m *MergeRequestManager) ReportEntries(since time.Time) ([]ReportEntry, error) {
+	iter, err := m.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(reportKey(since, 0)),
+		UpperBound: keyUpperBound([]byte(reportPrefix)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var entries []ReportEntry
+	for iter.First(); iter.Valid(); iter.Next() {
+		data, err := iter.ValueAndErr()
+		if err != nil {
+			return nil, err
+		}
+		var entry ReportEntry
+		err = json.Unmarshal(data, &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Report summarizes the merge targets finished since the given time
+func (m *MergeRequestManager) Report(since time.Time) (Report, error) {
+	entries, err := m.ReportEntries(since)
+	if err != nil {
+		return Report{}, err
+	}
+	r := Report{Since: since, AbortsByReason: make(map[string]int)}
+	projects := make(map[string]*ProjectReport)
+	var timeToMerge time.Duration
+	var timed int
+	for _, e := range entries {
+		p, ok := projects[e.Project]
+		if !ok {
+			p = &ProjectReport{Project: e.Project}
+			projects[e.Project] = p
+		}
+		switch e.State {
+		case OutcomeMerged:
+			r.Merged++
+			p.Merged++
+			if !e.OpenedAt.IsZero() {
+				timeToMerge += e.Time.Sub(e.OpenedAt)
+				timed++
+			}
+		case OutcomeAborted:
+			r.Aborted++
+			p.Aborted++
+			r.AbortsByReason[e.Reason]++
+		case OutcomeError:
+			r.Aborted++
+			p.Aborted++
+			r.AbortsByReason["error "+e.Reason]++
+		case OutcomeQuarantined:
+			r.Aborted++
+			p.Aborted++
+			r.AbortsByReason["quarantined"]++
+		}
+	}
+	if timed > 0 {
+		r.AverageTimeToMerge = timeToMerge / time.Duration(timed)
+	}
+	for _, p := range projects {
+		r.Projects = append(r.Projects, *p)
+	}
+	slices.SortFunc(r.Projects, func(a, b ProjectReport) int {
+		return cmp.Or(cmp.Compare(b.Merged+b.Aborted, a.Merged+a.Aborted), strings.Compare(a.Project, b.Project))
+	})
+	return r, nil
+}
+
+// AbortReasons returns the reasons of the aborts in alphabetical order
+func (r Report) AbortReasons() []string {
+	reasons := make([]string, 0, len(r.AbortsByReason))
+	for reason := range r.AbortsByReason {
+		reasons = append(reasons, reason)
+	}
+	slices.Sort(reasons)
+	return reasons
+}
+
+// ParseSince parses a relative time span like 7d, 2w or 36h
+func ParseSince(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			i, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid time span %q", s)
+			}
+			return time.Duration(i) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
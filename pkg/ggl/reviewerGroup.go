@@ -0,0 +1,41 @@
+package ggl
+
+import (
+	"context"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+)
+
+// reviewerGroupOptions returns the list options fetching the merge requests of the filter. The api filters by a single
+// reviewer, so with a reviewer group there is one list per active member of the group, including inherited members,
+// next to the one of the reviewer.
+func (m *MergeRequestManager) reviewerGroupOptions(ctx context.Context, opt *gitlab.ListMergeRequestsOptions) ([]*gitlab.ListMergeRequestsOptions, error) {
+	if m.ReviewerGroupPath == nil {
+		return []*gitlab.ListMergeRequestsOptions{opt}, nil
+	}
+	var opts []*gitlab.ListMergeRequestsOptions
+	if m.ReviewerUsername != nil {
+		opts = append(opts, opt)
+	}
+	listOpt := &gitlab.ListGroupMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}}
+	for {
+		members, resp, err := m.gl.Groups.ListAllGroupMembers(*m.ReviewerGroupPath, listOpt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("members of reviewer group %s: %w", *m.ReviewerGroupPath, err)
+		}
+		for _, member := range members {
+			if member.State != "active" || (m.ReviewerUsername != nil && member.Username == *m.ReviewerUsername) {
+				continue
+			}
+			memberOpt := *opt
+			memberOpt.ReviewerUsername = nil
+			memberOpt.ReviewerID = gitlab.ReviewerID(member.ID)
+			opts = append(opts, &memberOpt)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpt.Page = resp.NextPage
+	}
+	return opts, nil
+}
@@ -0,0 +1,61 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+	"testing"
+)
+
+func TestFetchByReviewerGroup(t *testing.T) {
+	alice := &gitlab.BasicUser{ID: 11, Username: "alice"}
+	bob := &gitlab.BasicUser{ID: 12, Username: "bob"}
+	carol := &gitlab.BasicUser{ID: 13, Username: "carol"}
+	tests := []struct {
+		name     string
+		reviewer string
+		want     []int
+	}{
+		{"group", "", []int{201, 203}},
+		{"group and reviewer", "carol", []int{201, 202, 203}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := fakegitlab.Mixed()
+			scenario.MergeRequests[0].Reviewers = []*gitlab.BasicUser{alice}
+			scenario.MergeRequests[1].Reviewers = []*gitlab.BasicUser{carol}
+			scenario.MergeRequests[2].Reviewers = []*gitlab.BasicUser{alice, bob}
+			scenario.GroupMembers = map[string][]*gitlab.GroupMember{"platform": {
+				{ID: 11, Username: "alice", State: "active"},
+				{ID: 12, Username: "bob", State: "active"},
+				{ID: 13, Username: "carol", State: "blocked"},
+			}}
+			h := newHarness(t, scenario)
+			m := h.Manager.Author("").Reviewer(tt.reviewer).ReviewerGroup("platform")
+			if err := m.FetchMergeRequests(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			mrs, err := m.GetMergeRequests()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, mr := range mrs {
+				ids = append(ids, mr.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("fetched %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchByUnknownReviewerGroup(t *testing.T) {
+	h := newHarness(t, fakegitlab.Mixed())
+	m := h.Manager.ReviewerGroup("unknown")
+	if err := m.FetchMergeRequests(context.Background()); err == nil {
+		t.Error("no error for an unknown group")
+	}
+}
@@ -0,0 +1,108 @@
+package ggl
+
+import (
+	"context"
+	"errors"
+	"github.com/xanzy/go-gitlab"
+	"strconv"
+	"strings"
+)
+
+// kinds of search results
+const (
+	SearchMergeRequest = "merge request"
+	SearchProject      = "project"
+)
+
+// remoteSearchLimit is the number of merge requests and projects requested from the gitlab search api
+const remoteSearchLimit = 20
+
+// SearchResult is a merge request or project matching a search query
+type SearchResult struct {
+	Kind      string
+	ID        int
+	Reference string
+	Title     string
+	WebURL    string
+	// Remote results come from the gitlab search api instead of the cache
+	Remote bool
+}
+
+// MatchesQuery reports whether every whitespace separated term of the query is contained in one of the fields,
+// ignoring case. An empty query matches everything.
+func MatchesQuery(query string, fields ...string) bool {
+	text := strings.ToLower(strings.Join(fields, "\n"))
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Search finds the cached merge requests by title, description, branch and project path and the cached projects by
+// name and path, merge requests first
+func (m *MergeRequestManager) Search(query string) ([]SearchResult, error) {
+	mrs, err := m.GetMergeRequests()
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, mr := range mrs {
+		projectPath := ""
+		if p, err := m.GetProject(mr.ProjectID); err == nil {
+			projectPath = p.PathWithNamespace
+		}
+		if MatchesQuery(query, mr.Title, mr.Description, mr.SourceBranch, projectPath) {
+			results = append(results, mergeRequestResult(&mr.MergeRequest, projectPath, false))
+		}
+	}
+	projects, err := m.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if MatchesQuery(query, p.NameWithNamespace, p.PathWithNamespace, p.Description) {
+			results = append(results, projectResult(&p, false))
+		}
+	}
+	return results, nil
+}
+
+// SearchRemote queries the gitlab search api for open merge requests and projects, it covers what isn't cached
+func (m *MergeRequestManager) SearchRemote(ctx context.Context, query string) ([]SearchResult, error) {
+	if m.gl.Search == nil {
+		return nil, errors.New("the client has no search api")
+	}
+	opt := &gitlab.SearchOptions{ListOptions: gitlab.ListOptions{PerPage: remoteSearchLimit}}
+	mrs, _, err := m.gl.Search.MergeRequests(query, opt, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, mr := range mrs {
+		if mr.State == "opened" {
+			results = append(results, mergeRequestResult(mr, "", true))
+		}
+	}
+	projects, _, err := m.gl.Search.Projects(query, opt, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		results = append(results, projectResult(p, true))
+	}
+	return results, nil
+}
+
+func mergeRequestResult(mr *gitlab.MergeRequest, projectPath string, remote bool) SearchResult {
+	reference := fullReference(mr.References)
+	if reference == "" {
+		reference = projectPath + "!" + strconv.Itoa(mr.IID)
+	}
+	return SearchResult{Kind: SearchMergeRequest, ID: mr.ID, Reference: reference, Title: mr.Title, WebURL: mr.WebURL, Remote: remote}
+}
+
+func projectResult(p *gitlab.Project, remote bool) SearchResult {
+	return SearchResult{Kind: SearchProject, ID: p.ID, Reference: p.PathWithNamespace, Title: p.NameWithNamespace, WebURL: p.WebURL, Remote: remote}
+}
@@ -0,0 +1,25 @@
+package ggl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/cockroachdb/pebble"
+)
+
+// LoadSetting loads the setting stored under name into v, v is left untouched if it was never stored
+func (m *MergeRequestManager) LoadSetting(name string, v any) error {
+	data, closer, err := m.db.Get([]byte(settingPrefix + name))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	return json.Unmarshal(data, v)
+}
+
+// StoreSetting persists v under name, e.g. the layout of the ui across sessions
+func (m *MergeRequestManager) StoreSetting(name string, v any) error {
+	return store(m.db, settingPrefix+name, v)
+}
@@ -0,0 +1,36 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+)
+
+// ReasonUnsignedCommits is the abort reason for merge requests with commits without a verified signature
+const ReasonUnsignedCommits = "unsigned commits"
+
+// unsignedCommits returns the short ids of the commits of the target's merge request that have no verified gpg
+// signature
+func (m *MergeRequestManager) unsignedCommits(ctx context.Context, target mergeTarget) ([]string, error) {
+	opt := &gitlab.GetMergeRequestCommitsOptions{Page: 1, PerPage: 100}
+	var unsigned []string
+	for {
+		commits, resp, err := m.gl.MergeRequests.GetMergeRequestCommits(target.ProjectID, target.MergeID, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			signature, resp, err := m.gl.Commits.GetGPGSignature(target.ProjectID, c.ID, gitlab.WithContext(ctx))
+			if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+				return nil, err
+			}
+			if err != nil || signature.VerificationStatus != "verified" {
+				unsigned = append(unsigned, c.ShortID)
+			}
+		}
+		if resp.NextPage == 0 {
+			return unsigned, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
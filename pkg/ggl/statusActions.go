@@ -0,0 +1,45 @@
+package ggl
+
+import (
+	"fmt"
+	"time"
+)
+
+// actions configurable per detailed merge status, they take precedence over the built-in handling
+const (
+	StatusActionRetry = "retry"
+	StatusActionAbort = "abort"
+)
+
+// maxUnknownBackoff caps the delay between checks of a merge request with an unknown status
+const maxUnknownBackoff = 30 * time.Minute
+
+// validateStatusActions checks that only known actions are configured
+func validateStatusActions(actions map[string]string) error {
+	for status, action := range actions {
+		if action != StatusActionRetry && action != StatusActionAbort {
+			return fmt.Errorf("unknown action %q for status %s (use %s or %s)", action, status, StatusActionRetry, StatusActionAbort)
+		}
+	}
+	return nil
+}
+
+// configuredStatusOutcome is the outcome of the action configured for the status, ok is false without one
+func (m *MergeRequestManager) configuredStatusOutcome(mergeStatus string) (MergeOutcome, bool) {
+	switch m.statusActions[mergeStatus] {
+	case StatusActionRetry:
+		return retryOutcome("status "+mergeStatus, 1*time.Minute), true
+	case StatusActionAbort:
+		return abortOutcome(mergeStatus), true
+	}
+	return MergeOutcome{}, false
+}
+
+// unknownStatusBackoff doubles the delay for every consecutive unknown status, starting at one minute
+func unknownStatusBackoff(unknown int) time.Duration {
+	d := time.Minute
+	for i := 1; i < unknown && d < maxUnknownBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxUnknownBackoff)
+}
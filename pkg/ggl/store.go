@@ -0,0 +1,139 @@
+package ggl
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cockroachdb/pebble"
+	"strconv"
+	"time"
+)
+
+// key prefixes of the record types kept in the database
+const (
+	mrPrefix            = "mr-"
+	projectPrefix       = "project-"
+	targetPrefix        = "merge-target-"
+	approvalsPrefix     = "approvals-"
+	labelsPrefix        = "labels-"
+	membersPrefix       = "members-"
+	settingPrefix       = "setting-"
+	gitattributesPrefix = "gitattributes-"
+	diffPrefix          = "diff-"
+	reportPrefix        = "report-"
+	fetchedPrefix       = "fetched-"
+)
+
+func mrKey(id int) string {
+	return mrPrefix + strconv.Itoa(id)
+}
+
+func projectKey(id int) string {
+	return projectPrefix + strconv.Itoa(id)
+}
+
+func targetKey(id int) string {
+	return targetPrefix + strconv.Itoa(id)
+}
+
+func approvalsKey(id int) string {
+	return approvalsPrefix + strconv.Itoa(id)
+}
+
+func labelsKey(projectID int) string {
+	return labelsPrefix + strconv.Itoa(projectID)
+}
+
+func membersKey(projectID int) string {
+	return membersPrefix + strconv.Itoa(projectID)
+}
+
+func gitattributesKey(projectID int) string {
+	return gitattributesPrefix + strconv.Itoa(projectID)
+}
+
+func diffKey(id int, sha string) string {
+	return diffPrefix + strconv.Itoa(id) + "-" + sha
+}
+
+// fetchedKey is the key of the time the merge request or project record under key was fetched
+func fetchedKey(key string) string {
+	return fetchedPrefix + key
+}
+
+// reportKey orders the report entries by the time they finished
+func reportKey(t time.Time, id int) string {
+	return fmt.Sprintf("%s%020d-%d", reportPrefix, t.UnixNano(), id)
+}
+
+// store stores v as json under key
+func store[T any](db *pebble.DB, key string, v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return db.Set([]byte(key), data, pebble.Sync)
+}
+
+// storeBatch adds v as json under key to the batch
+func storeBatch[T any](b *pebble.Batch, key string, v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Set([]byte(key), data, nil)
+}
+
+// load loads the record stored under key
+func load[T any](db *pebble.DB, key string) (T, error) {
+	var v T
+	data, closer, err := db.Get([]byte(key))
+	if err != nil {
+		return v, err
+	}
+	defer closer.Close()
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// loadAll loads all records stored under keys with the given prefix
+func loadAll[T any](db *pebble.DB, prefix string) ([]T, error) {
+	iter, err := db.NewIter(prefixIterOptions([]byte(prefix)))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var all []T
+	for iter.First(); iter.Valid(); iter.Next() {
+		item, err := iter.ValueAndErr()
+		if err != nil {
+			return nil, err
+		}
+		var v T
+		err = json.Unmarshal(item, &v)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, v)
+	}
+	return all, nil
+}
+
+func keyUpperBound(b []byte) []byte {
+	end := make([]byte, len(b))
+	copy(end, b)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i] = end[i] + 1
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil // no upper-bound
+}
+
+func prefixIterOptions(prefix []byte) *pebble.IterOptions {
+	return &pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: keyUpperBound(prefix),
+	}
+}
@@ -0,0 +1,89 @@
+package ggl
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maxTargetHistory bounds the number of transitions kept per merge target
+const maxTargetHistory = 50
+
+// TargetEvent is a state transition of a merge target
+type TargetEvent struct {
+	Time  time.Time
+	State string
+	Info  string
+}
+
+// record appends a transition to the history of the target and drops the oldest ones beyond maxTargetHistory
+func (t *mergeTarget) record(now time.Time, state string, info string) {
+	t.History = append(t.History, TargetEvent{Time: now, State: state, Info: info})
+	if len(t.History) > maxTargetHistory {
+		t.History = slices.Clone(t.History[len(t.History)-maxTargetHistory:])
+	}
+}
+
+// TargetStatus is the state of a merge target as reported by `targets list`
+type TargetStatus struct {
+	ID        int
+	ProjectID int
+	IID       int
+	// Reference is the human reference (group/project!iid), empty if the project is not cached
+	Reference string
+	// Title of the merge request, empty if it is no longer cached
+	Title       string
+	Active      bool
+	Info        string
+	LastAttempt time.Time
+	NextAttempt time.Time
+	Outcome     MergeOutcome
+	History     []TargetEvent
+}
+
+// Targets returns the current merge targets ordered by their next attempt
+func (m *MergeRequestManager) Targets() ([]TargetStatus, error) {
+	targets, err := loadAll[mergeTarget](m.db, targetPrefix)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]TargetStatus, 0, len(targets))
+	for _, t := range targets {
+		statuses = append(statuses, m.targetStatus(t))
+	}
+	slices.SortFunc(statuses, func(a, b TargetStatus) int {
+		return cmp.Compare(a.NextAttempt.UnixNano(), b.NextAttempt.UnixNano())
+	})
+	return statuses, nil
+}
+
+// Target returns the merge target of the merge request with the given id
+func (m *MergeRequestManager) Target(id int) (TargetStatus, error) {
+	t, err := load[mergeTarget](m.db, targetKey(id))
+	if err != nil {
+		return TargetStatus{}, err
+	}
+	return m.targetStatus(t), nil
+}
+
+func (m *MergeRequestManager) targetStatus(t mergeTarget) TargetStatus {
+	s := TargetStatus{
+		ID:          t.Id,
+		ProjectID:   t.ProjectID,
+		IID:         t.MergeID,
+		Active:      t.Active,
+		Info:        t.Info,
+		LastAttempt: t.Latest,
+		NextAttempt: t.Next,
+		Outcome:     t.Outcome,
+		History:     t.History,
+	}
+	if p, err := m.GetProject(t.ProjectID); err == nil {
+		s.Reference = p.PathWithNamespace + "!" + strconv.Itoa(t.MergeID)
+	}
+	if mr, err := m.GetMergeRequest(t.Id); err == nil {
+		s.Title = mr.Title
+	}
+	return s
+}
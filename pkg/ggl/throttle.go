@@ -0,0 +1,44 @@
+package ggl
+
+import (
+	"time"
+)
+
+// throttled checks the merges of the last hour and day against the limits, it returns the time the next merge is
+// allowed at or zero if merging is allowed now
+func (m *MergeRequestManager) throttled() (time.Time, error) {
+	now := m.clock.Now()
+	var next time.Time
+	for _, limit := range []struct {
+		max    int
+		window time.Duration
+	}{{m.maxMergesPerHour, time.Hour}, {m.maxMergesPerDay, 24 * time.Hour}} {
+		if limit.max <= 0 {
+			continue
+		}
+		entries, err := m.ReportEntries(now.Add(-limit.window))
+		if err != nil {
+			return time.Time{}, err
+		}
+		var merges []time.Time
+		for _, e := range entries {
+			if e.State == OutcomeMerged {
+				merges = append(merges, e.Time)
+			}
+		}
+		if len(merges) < limit.max {
+			continue
+		}
+		// the slot frees up once enough of the merges in the window have left it
+		slot := merges[len(merges)-limit.max].Add(limit.window)
+		if slot.After(next) {
+			next = slot
+		}
+	}
+	return next, nil
+}
+
+// throttledOutcome holds the target until the next merge slot
+func (m *MergeRequestManager) throttledOutcome(next time.Time) MergeOutcome {
+	return retryOutcome("throttled, next slot at "+next.Local().Format("15:04"), next.Sub(m.clock.Now()).Round(time.Second)+time.Second)
+}
@@ -0,0 +1,107 @@
+package ggl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTokenValidity is how long rotated tokens stay valid
+const DefaultTokenValidity = 30 * 24 * time.Hour
+
+// RotateToken replaces the stored token of url (the default url if empty) by a fresh one that is valid for validity.
+// The old token is revoked by gitlab, if the new one can't be stored it is returned together with the error.
+func RotateToken(url string, validity time.Duration, opts ...Option) (*gitlab.PersonalAccessToken, error) {
+	return configure(opts).rotateToken(url, validity)
+}
+
+func (m *MergeRequestManager) rotateToken(url string, validity time.Duration) (*gitlab.PersonalAccessToken, error) {
+	gl, url, err := m.storedTokenClient(url)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := gitlab.ISOTime(time.Now().Add(validity))
+	token, _, err := gl.PersonalAccessTokens.RotatePersonalAccessTokenSelf(&gitlab.RotatePersonalAccessTokenOptions{
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = storeToken(token.Token, url, m.instance.readPassphrase)
+	if err != nil {
+		return token, fmt.Errorf("storing the rotated token: %w", err)
+	}
+	return token, nil
+}
+
+// RotateTokenIfExpiring rotates the stored token of url (the default url if empty) if it expires within the given
+// duration, tokens without expiry are kept
+func RotateTokenIfExpiring(url string, within time.Duration, validity time.Duration, opts ...Option) (*gitlab.PersonalAccessToken, error) {
+	return configure(opts).rotateTokenIfExpiring(url, within, validity)
+}
+
+func (m *MergeRequestManager) rotateTokenIfExpiring(url string, within time.Duration, validity time.Duration) (*gitlab.PersonalAccessToken, error) {
+	gl, url, err := m.storedTokenClient(url)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := gl.PersonalAccessTokens.GetSinglePersonalAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	if token.ExpiresAt == nil || time.Time(*token.ExpiresAt).After(time.Now().Add(within)) {
+		return nil, nil
+	}
+	return m.rotateToken(url, validity)
+}
+
+// RotateExpiringToken rotates the stored token like RotateTokenIfExpiring and switches the client of the manager to
+// the new token, gitlab revokes the old one. Only the client of NewDefaultMergeRequestManager with a stored token can
+// be switched, e.g. for a daemon running longer than the validity of its token.
+func (m *MergeRequestManager) RotateExpiringToken(within time.Duration, validity time.Duration) (*gitlab.PersonalAccessToken, error) {
+	if m.token == nil || os.Getenv("GITLAB_TOKEN") != "" || os.Getenv("CI_JOB_TOKEN") != "" {
+		return nil, errors.New("only the stored token of the default client can be rotated")
+	}
+	token, err := m.rotateTokenIfExpiring("", within, validity)
+	if token != nil {
+		m.token.token.Store(&token.Token)
+	}
+	return token, err
+}
+
+// rotatedToken is the transport of the default client, it replaces the token the client was created with once it got
+// rotated
+type rotatedToken struct {
+	next  http.RoundTripper
+	token atomic.Pointer[string]
+}
+
+func (t *rotatedToken) RoundTrip(req *http.Request) (*http.Response, error) {
+	if token := t.token.Load(); token != nil && req.Header.Get("PRIVATE-TOKEN") != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("PRIVATE-TOKEN", *token)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// storedTokenClient creates a client with the token stored by Login, tokens from the environment can't be rotated
+// persistently
+func (m *MergeRequestManager) storedTokenClient(url string) (*gitlab.Client, string, error) {
+	if url == "" {
+		var err error
+		url, err = m.instance.defaultURL()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	token, err := readTokenForUrl(url, m.instance.readPassphrase)
+	if err != nil {
+		return nil, "", errors.Join(errors.New("no stored token, login first"), err)
+	}
+	gl, err := gitlab.NewClient(token, gitlab.WithBaseURL(url), gitlab.WithHTTPClient(&http.Client{Transport: m.apiStats}))
+	return gl, url, err
+}
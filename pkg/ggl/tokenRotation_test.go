@@ -0,0 +1,55 @@
+package ggl_test
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/internal/fakegitlab"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"testing"
+	"time"
+)
+
+func TestRotateExpiringToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("CI_JOB_TOKEN", "")
+	s := fakegitlab.New(fakegitlab.RenovateBump())
+	t.Cleanup(s.Close)
+	if err := ggl.Login("stored-token", s.URL, false); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ggl.NewDefaultMergeRequestManager(ggl.WithGitLabURL(s.URL), ggl.WithDbPath(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = m.Close()
+	})
+	m.Author("renovate-bot")
+
+	s.SetTokenExpiry(time.Now().Add(30 * 24 * time.Hour))
+	token, err := m.RotateExpiringToken(7*24*time.Hour, ggl.DefaultTokenValidity)
+	if err != nil || token != nil {
+		t.Fatalf("rotated %v, %v a token valid for 30 days", token, err)
+	}
+
+	s.SetTokenExpiry(time.Now().Add(2 * 24 * time.Hour))
+	token, err = m.RotateExpiringToken(7*24*time.Hour, ggl.DefaultTokenValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.Token != "rotated-1" {
+		t.Fatalf("rotated token is %+v", token)
+	}
+	// the token the manager was created with is revoked now
+	if err := m.FetchMergeRequests(context.Background()); err != nil {
+		t.Fatalf("fetching with the rotated token: %v", err)
+	}
+	if _, err := m.RotateExpiringToken(7*24*time.Hour, ggl.DefaultTokenValidity); err != nil {
+		t.Errorf("checking the stored rotated token: %v", err)
+	}
+
+	t.Setenv("GITLAB_TOKEN", "env-token")
+	if _, err := m.RotateExpiringToken(7*24*time.Hour, ggl.DefaultTokenValidity); err == nil {
+		t.Error("rotated with a token from the environment")
+	}
+}
@@ -0,0 +1,27 @@
+package ggl
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+	"slices"
+)
+
+// TokenAccess describes what the token of the client may do
+type TokenAccess struct {
+	Scopes []string
+	// CanWrite is set if the token may approve, merge and change merge requests (the api scope)
+	CanWrite bool
+}
+
+// TokenAccess probes the scopes of the token. Tokens whose scopes can't be read (e.g. oauth or job tokens) are
+// assumed to be able to write, the error tells why.
+func (m *MergeRequestManager) TokenAccess(ctx context.Context) (TokenAccess, error) {
+	if m.gl.PersonalAccessTokens == nil {
+		return TokenAccess{CanWrite: true}, nil
+	}
+	token, _, err := m.gl.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+	if err != nil {
+		return TokenAccess{CanWrite: true}, err
+	}
+	return TokenAccess{Scopes: token.Scopes, CanWrite: slices.Contains(token.Scopes, "api")}, nil
+}
@@ -0,0 +1,131 @@
+package ggl
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// encrypted token file layout: salt, nonce and the sealed token
+const (
+	encryptedTokenFile = "token.enc"
+	saltSize           = 16
+	nonceSize          = 24
+)
+
+// PassphraseEnv provides the passphrase of encrypted tokens without prompting (e.g. for daemons and ci jobs)
+const PassphraseEnv = "GITLAB_UTIL_TOKEN_PASSPHRASE"
+
+// readPassphrase returns the passphrase of encrypted tokens from PassphraseEnv or asks for it with the function set
+// by WithPassphraseFunc
+func (i instance) readPassphrase() ([]byte, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return []byte(p), nil
+	}
+	if i.passphrase == nil {
+		return nil, fmt.Errorf("the token is encrypted, set %s to unlock it", PassphraseEnv)
+	}
+	p, err := i.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return p, nil
+}
+
+// EncryptToken replaces the plaintext token of url (the default url if empty) by a token file encrypted with the
+// passphrase
+func EncryptToken(url string, opts ...Option) error {
+	inst := configure(opts).instance
+	if url == "" {
+		var err error
+		url, err = inst.defaultURL()
+		if err != nil {
+			return err
+		}
+	}
+	dir, err := tokenDir(url)
+	if err != nil {
+		return err
+	}
+	token, err := os.ReadFile(filepath.Join(dir, "token"))
+	if err != nil {
+		return err
+	}
+	err = writeEncryptedToken(dir, string(token), inst.readPassphrase)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, "token"))
+}
+
+// hasEncryptedToken reports whether the token in dir is stored encrypted
+func hasEncryptedToken(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, encryptedTokenFile))
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+func writeEncryptedToken(dir string, token string, passphrase func() ([]byte, error)) error {
+	p, err := passphrase()
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, saltSize)
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	key, err := tokenKey(p, salt)
+	if err != nil {
+		return err
+	}
+	data := append(salt, nonce[:]...)
+	data = secretbox.Seal(data, []byte(token), &nonce, key)
+	return os.WriteFile(filepath.Join(dir, encryptedTokenFile), data, 0600)
+}
+
+func readEncryptedToken(dir string, passphrase func() ([]byte, error)) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, encryptedTokenFile))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < saltSize+nonceSize+secretbox.Overhead {
+		return "", errors.New("encrypted token file is corrupt")
+	}
+	p, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+	key, err := tokenKey(p, data[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], data[saltSize:saltSize+nonceSize])
+	token, ok := secretbox.Open(nil, data[saltSize+nonceSize:], &nonce, key)
+	if !ok {
+		return "", errors.New("wrong passphrase for the encrypted token")
+	}
+	return string(token), nil
+}
+
+// tokenKey derives the secretbox key from the passphrase
+func tokenKey(passphrase []byte, salt []byte) (*[32]byte, error) {
+	k, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], k)
+	return &key, nil
+}
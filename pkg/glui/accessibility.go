@@ -0,0 +1,114 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+// symbols are the non-ascii characters of the ui, UseASCII replaces them
+type symbols struct {
+	excluded   string
+	filtered   string
+	expanded   string
+	collapsed  string
+	ascending  string
+	descending string
+	arrow      string
+	rule       string
+	ellipsis   string
+	warning    string
+	stale      string
+	echo       rune
+}
+
+var sym = symbols{
+	excluded:   "⊘ ",
+	filtered:   "◂",
+	expanded:   "▾",
+	collapsed:  "▸",
+	ascending:  " ▲",
+	descending: " ▼",
+	arrow:      " → ",
+	rule:       "─",
+	ellipsis:   "…",
+	warning:    "⚠ ",
+	stale:      "◷ ",
+	echo:       '•',
+}
+
+var asciiSymbols = symbols{
+	excluded:   "x ",
+	filtered:   "<",
+	expanded:   "v",
+	collapsed:  ">",
+	ascending:  " ^",
+	descending: " v",
+	arrow:      " -> ",
+	rule:       "-",
+	ellipsis:   "~",
+	warning:    "! ",
+	stale:      "? ",
+	echo:       '*',
+}
+
+// asciiBorder draws boxes with ascii characters, lipgloss has no such border
+var asciiBorder = lipgloss.Border{
+	Top: "-", Bottom: "-", Left: "|", Right: "|",
+	TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+	MiddleLeft: "+", MiddleRight: "+", Middle: "+", MiddleTop: "+", MiddleBottom: "+",
+}
+
+var (
+	// noColor is set by DisableColors, state that is otherwise only shown by colors is then spelled out
+	noColor bool
+	// ascii is set by UseASCII
+	ascii bool
+)
+
+// DisableColors renders the ui and the diffs without colors, e.g. for terminals and logs that mangle ansi escapes
+func DisableColors() {
+	noColor = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// UseASCII draws the ui with ascii characters only instead of box drawing characters, arrows and emojis, e.g. for
+// screen readers
+func UseASCII() {
+	ascii = true
+	sym = asciiSymbols
+	baseStyle = baseStyle.BorderStyle(asciiBorder)
+	titleStyle = titleStyle.BorderStyle(asciiBorder)
+	infoStyle = infoStyle.BorderStyle(asciiBorder)
+	confirmStyle = confirmStyle.BorderStyle(asciiBorder)
+}
+
+// fitASCII truncates the cells to the widths of the columns with an ascii ellipsis in ascii mode, the table would
+// truncate them with a unicode one
+func fitASCII(cells []string, columns []table.Column) []string {
+	if !ascii {
+		return cells
+	}
+	for i := range min(len(cells), len(columns)) {
+		cells[i] = ansi.Truncate(cells[i], columns[i].Width, sym.ellipsis)
+	}
+	return cells
+}
+
+// tableBorder separates the header of the table from its rows
+func tableBorder() lipgloss.Border {
+	if ascii {
+		return asciiBorder
+	}
+	return lipgloss.NormalBorder()
+}
+
+// loadingSpinner is the spinner shown while loading and syncing
+func loadingSpinner() spinner.Spinner {
+	if ascii {
+		return spinner.Line
+	}
+	return spinner.Moon
+}
@@ -0,0 +1,59 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"log"
+)
+
+type approvalPasswordSet struct{}
+
+// promptsApprovalPassword reports whether the event is a target waiting for the approval password
+func (m model) promptsApprovalPassword(e ggl.Event) bool {
+	return m.passwordPrompt == nil && m.readOnly == "" && e.Type == ggl.EventRescheduled &&
+		e.Outcome.Reason == ggl.ReasonApprovalPassword
+}
+
+func newPasswordPrompt() *textinput.Model {
+	input := textinput.New()
+	input.Placeholder = "approval password"
+	input.EchoMode = textinput.EchoPassword
+	input.EchoCharacter = sym.echo
+	input.Focus()
+	return &input
+}
+
+// updatePasswordPrompt handles the keys while the approval password is asked for
+func (m model) updatePasswordPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		password := m.passwordPrompt.Value()
+		m.passwordPrompt = nil
+		return m, m.setApprovalPassword(password)
+	case "esc":
+		m.passwordPrompt = nil
+		return m, nil
+	}
+	input, cmd := m.passwordPrompt.Update(msg)
+	m.passwordPrompt = &input
+	return m, cmd
+}
+
+func (m model) setApprovalPassword(password string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.SetApprovalPassword(password)
+		if err != nil {
+			log.Println("Error retrying targets with the approval password", err)
+			return err
+		}
+		return approvalPasswordSet{}
+	}
+}
+
+func (m model) passwordPromptView() string {
+	body := "GitLab requires the password to approve merge requests\n\n" + m.passwordPrompt.View() +
+		"\n\n[enter] approve  [esc] cancel"
+	return lipgloss.Place(m.table.Width(), m.table.Height(), lipgloss.Center, lipgloss.Center, confirmStyle.Render(body))
+}
@@ -2,9 +2,12 @@ package glui
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -23,6 +26,8 @@ var baseStyle = lipgloss.NewStyle().
 	BorderStyle(lipgloss.NormalBorder()).
 	BorderForeground(lipgloss.Color("240"))
 
+var statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
 var (
 	titleStyle = func() lipgloss.Style {
 		b := lipgloss.RoundedBorder()
@@ -39,21 +44,71 @@ var (
 
 type model struct {
 	table         table.Model
-	gl            *gitlab.Client
 	mergeRequests []mergeRequest
 	spinner       spinner.Model
 	loading       string
 	mrm           *ggl.MergeRequestManager
-	rowmap        map[string]int
 	diff          []*gitlab.MergeRequestDiff
 	diffView      viewport.Model
 	ready         bool
 	diffId        int
 	diffTitle     string
+	ctx           context.Context
+	cancelRefresh context.CancelFunc
+	events        <-chan ggl.Event
+	lastSync      time.Time
+	labelFilter   string
+	confirm       mergeAction
+	yolo          bool
+	picker        *reviewerPicker
+	details       *targetDetails
+	notice        string
+	columns       []column
+	visible       []mergeRequest
+	rendered      map[int]renderedRow
+	rowsStale     bool
+	groupBy       groupMode
+	// groupIDs are the merge requests of the dependency group the pending action runs on
+	groupIDs        []int
+	collapsed       map[string]bool
+	statusFilter    statusFilter
+	generated       []string
+	expandGenerated bool
+	prefetching     bool
+	// syncing is set while the first fetch after the start runs in the background
+	syncing bool
+	// progress of the running fetch, shown under the spinner
+	progress string
+	// readOnly is the reason why approving, merging and changing merge requests is disabled, empty if they are enabled
+	readOnly string
+	// passwordPrompt asks for the approval password when gitlab requires it
+	passwordPrompt *textinput.Model
+	// searchInput edits search, the merge requests are filtered by it while typing
+	searchInput *textinput.Model
+	search      string
+	// sortColumn is the title of the column the table is sorted by, empty for the order of the manager
+	sortColumn string
+	sortDesc   bool
+	// fittedColumns are the columns of the table as fitted to its width
+	fittedColumns []table.Column
+	// lastClick is the time of the last click on a row to detect double clicks
+	lastClick time.Time
+	// markdownStyle is the glamour style descriptions and comments are rendered with
+	markdownStyle string
+	// tab is the selected view, tabView scrolls the ones other than the merge requests
+	tab      tab
+	tabView  viewport.Model
+	targets  []ggl.TargetStatus
+	activity []ggl.Event
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, m.InitialFetch())
+	cmds := []tea.Cmd{m.spinner.Tick, m.InitialFetch(), m.waitForEvent(), tick()}
+	if m.loading == "" {
+		// show the cached merge requests right away, the initial fetch refreshes them in the background
+		cmds = append(cmds, m.reloadMergeRequests)
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -63,42 +118,137 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case mergeRequests:
 		if msg.err == nil {
 			m.mergeRequests = msg.requests
-			m.rowmap = make(map[string]int)
-			rows := make([]table.Row, len(msg.requests))
-			for i, r := range msg.requests {
-				m.rowmap[r.HumanId] = r.Id
-				lastAction := humanize.RelTime(r.LastAction, time.Now(), "ago", "from now")
-				nextAction := humanize.RelTime(r.NextAction, time.Now(), "ago", "from now")
-				if r.Info == "" {
-					lastAction = ""
-				}
-				if !r.Active {
-					nextAction = ""
-				}
-				rows[i] = table.Row{
-					r.HumanId,
-					r.Title,
-					humanize.RelTime(r.LastUpdate, time.Now(), "ago", "from now"),
-					r.MergeStatus,
-					r.Info,
-					lastAction,
-					nextAction,
-				}
+			m.labelFilter = m.validLabelFilter()
+			if m.modalOpen() {
+				// the rows are replaced once the diff or dialog is closed, so that actions in there can't hit
+				// a different merge request
+				m.rowsStale = true
+			} else {
+				m.updateRows(time.Now())
 			}
-			m.table.SetRows(rows)
+			m.lastSync = msg.lastSync
 			m.loading = ""
 		}
 		if !msg.oneShot {
-			return m, m.mergeRequestor()
+			m.syncing = false
+		}
+		if msg.err != nil {
+			m.progress = ""
+		}
+		var prefetch tea.Cmd
+		if !m.prefetching {
+			prefetch = m.prefetchDiffs()
+			m.prefetching = prefetch != nil
+		}
+		if !msg.oneShot {
+			return m, tea.Batch(m.mergeRequestor(), prefetch)
 		}
+		return m, prefetch
+	case prefetchDone:
+		m.prefetching = false
 		return m, nil
-	case []*gitlab.MergeRequestDiff:
+	case tickMsg:
+		if m.rowsStale && !m.modalOpen() {
+			m.updateRows(time.Time(msg))
+		}
+		m.refreshWindow(time.Time(msg))
+		return m, tick()
+	case *reviewerPicker:
 		m.loading = ""
-		m.diff = msg
-		m.diffView.SetContent(ggl.RenderDiffString(msg))
-		m.diffView, cmd = m.diffView.Update(msg)
-		return m, cmd
+		m.picker = msg
+		return m, nil
+	case *targetDetails:
+		m.details = m.showDetails(msg)
+		return m, nil
+	case reviewersUpdated:
+		m.loading = ""
+		return m, nil
+	case approvalPasswordSet:
+		m.notice = "retrying approvals with the password"
+		return m, nil
+	case followUpOpened:
+		m.loading = ""
+		m.notice = "opened " + string(msg)
+		return m, nil
+	case diffPaged:
+		if msg.err != nil {
+			m.notice = "pager failed: " + msg.err.Error()
+		}
+		return m, nil
+	case editText:
+		m.loading = ""
+		return m, m.editMergeRequest(msg)
+	case mergeRequestEdited:
+		if msg.err != nil {
+			_ = os.Remove(msg.text.file)
+			m.notice = "editor failed: " + msg.err.Error()
+			return m, nil
+		}
+		return m, m.saveEditText(msg.text)
+	case mergeRequestSaved:
+		m.notice = "saved the title and description, t retries the merge request"
+		if msg.unchanged {
+			m.notice = "title and description unchanged"
+		}
+		return m, nil
+	case approversPinged:
+		m.notice = "asked the eligible approvers for approval in a comment"
+		return m, nil
+	case renovateRebaseRequested:
+		m.notice = "ticked the rebase checkbox, renovate rebases on its next run"
+		return m, nil
+	case enabledAll:
+		m.loading = ""
+		m.notice = fmt.Sprintf("enabled %d merge requests", int(msg))
+		return m, m.fetchMergeRequests
+	case error:
+		m.loading = ""
+		m.notice = msg.Error()
+		return m, nil
+	case loadedTargets:
+		m.targets = msg
+		return m, nil
+	case ggl.Event:
+		m.recordActivity(msg)
+		switch msg.Type {
+		case ggl.EventProgress:
+			m.progress = msg.Progress.String()
+			return m, m.waitForEvent()
+		case ggl.EventFetched:
+			m.progress = ""
+		case ggl.EventStalled:
+			m.notice = "warning: " + msg.Info
+		case ggl.EventQuarantined:
+			m.notice = "warning: " + msg.Outcome.Info()
+		}
+		if m.promptsApprovalPassword(msg) {
+			m.passwordPrompt = newPasswordPrompt()
+		}
+		if m.tab == targetsTab {
+			return m, tea.Batch(m.reloadMergeRequests, m.loadTargets, m.waitForEvent())
+		}
+		return m, tea.Batch(m.reloadMergeRequests, m.waitForEvent())
+	case tea.MouseMsg:
+		if m.diff == nil && msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft && msg.Y == 0 {
+			if name, ok := m.labelAt(msg.X); ok {
+				m.labelFilter = m.toggleLabelFilter(name)
+				m.updateRows(time.Now())
+				return m, m.saveViewState()
+			}
+		}
+		if !m.modalOpen() && m.tab == mergeRequestsTab && m.searchInput == nil {
+			return m.updateTableMouse(msg)
+		}
+	case loadedDiff:
+		m.loading = ""
+		m.diff = msg.diffs
+		m.generated = msg.generated
+		m.expandGenerated = false
+		m.diffView.SetContent(renderDiff(m.diff, m.generated, m.expandGenerated))
+		m.diffView.GotoTop()
+		return m, nil
 	case tea.KeyMsg:
+		m.notice = ""
 		switch msg.String() {
 		case "esc":
 			if m.table.Focused() {
@@ -110,14 +260,70 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c":
 			return m, tea.Quit
 		}
+		if m.passwordPrompt != nil {
+			return m.updatePasswordPrompt(msg)
+		}
+		if m.searchInput != nil {
+			return m.updateSearch(msg)
+		}
+		if m.picker != nil {
+			return m.updatePicker(msg)
+		}
+		if m.details != nil {
+			return m.updateDetails(msg)
+		}
+		if m.diff == nil && m.confirm == noAction {
+			if m, cmd, ok := m.switchTab(msg.String()); ok {
+				return m, cmd
+			}
+			if m.tab != mergeRequestsTab {
+				return m.updateTab(msg)
+			}
+		}
+		if m.blockedByReadOnly(msg.String()) {
+			m.notice = m.readOnly + ", approving, merging and changing merge requests is disabled"
+			return m, nil
+		}
+		if m.blockedByExclusion(msg.String()) {
+			m.notice = "the author of the merge request is excluded, it can't be approved, merged or changed"
+			return m, nil
+		}
+		if m.confirm != noAction {
+			action := m.confirm
+			m.confirm = noAction
+			if msg.String() == "y" {
+				return m.run(action)
+			}
+			return m, nil
+		}
 		if m.diff != nil {
+			action := noAction
 			switch msg.String() {
 			case "q":
 				m.diff = nil
 				break
 			case "m":
-				m.loading = "Approving & Merging " + m.diffTitle
-				return m, m.approveAndMergeMergeRequest(m.diffId, m.diff)
+				action = approveAndMerge
+			case "a":
+				action = approveOnly
+			case "M":
+				action = mergeOnly
+			case "e":
+				m.expandGenerated = !m.expandGenerated
+				m.diffView.SetContent(renderDiff(m.diff, m.generated, m.expandGenerated))
+				return m, nil
+			case "w":
+				m.notice = m.exportDiff()
+				return m, nil
+			case "|":
+				return m, m.pageDiff()
+			}
+			if action != noAction {
+				if !m.yolo {
+					m.confirm = action
+					return m, nil
+				}
+				return m.run(action)
 			}
 
 			break
@@ -125,25 +331,154 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q":
 			return m, tea.Quit
+		case "1", "2", "3":
+			m.statusFilter = m.toggleStatusFilter(msg.String())
+			m.updateRows(time.Now())
+			return m, m.saveViewState()
+		case "/":
+			m.searchInput = newSearchInput(m.search)
+			return m, textinput.Blink
+		case "s":
+			m.cycleSort()
+			m.setColumns(m.table.Width())
+			m.updateRows(time.Now())
+			return m, m.saveViewState()
+		case "g":
+			m.groupBy = m.groupBy.next()
+			m.updateRows(time.Now())
+			return m, m.saveViewState()
+		case " ":
+			if m.toggleCollapsed() {
+				m.updateRows(time.Now())
+				return m, m.saveViewState()
+			}
 		case "d", "enter":
-			m.loading = "Diff"
-			m.diffId = m.rowmap[m.table.SelectedRow()[0]]
-			m.diffTitle = m.table.SelectedRow()[0] + " | " + m.table.SelectedRow()[1]
-			return m, m.loadDiff(m.diffId)
+			if m.toggleCollapsed() {
+				m.updateRows(time.Now())
+				return m, m.saveViewState()
+			}
+			if r, ok := m.selected(); ok {
+				m.loading = "Diff"
+				m.diffId = r.Id
+				m.diffTitle = r.HumanId + " | " + r.Title
+				return m, m.loadDiff(m.diffId)
+			}
+			return m, nil
 		case "o":
-			id := m.rowmap[m.table.SelectedRow()[0]]
-			request, err := m.mrm.GetMergeRequest(id)
+			r, ok := m.selected()
+			if !ok {
+				return m, nil
+			}
+			request, err := m.mrm.GetMergeRequest(r.Id)
 			if err != nil {
 				return m, nil
 			}
 			url := request.WebURL
 			_ = osx.OpenDefault(url)
 			return m, nil
+		case "E":
+			m.notice = m.exportCSV(time.Now())
+			return m, nil
+		case "y", "Y":
+			if r, ok := m.selected(); ok {
+				m.notice = m.yank(r.Id, msg.String() == "Y")
+			}
+			return m, nil
 		case "c":
-			id := m.rowmap[m.table.SelectedRow()[0]]
-			return m, m.clearMerge(id)
+			if r, ok := m.selected(); ok {
+				return m, m.clearMerge(r.Id)
+			}
+			return m, nil
+		case "R":
+			if r, ok := m.selected(); ok {
+				m.loading = "Project members"
+				return m, m.loadReviewerPicker(r.Id, r.HumanId+" | "+r.Title)
+			}
+			return m, nil
+		case "i", "p":
+			if r, ok := m.selected(); ok {
+				action := ignoreDependency
+				if msg.String() == "p" {
+					action = pinDependency
+				}
+				m.diffId = r.Id
+				m.diffTitle = r.HumanId + " | " + r.Title
+				if !m.yolo {
+					m.confirm = action
+					return m, nil
+				}
+				return m.run(action)
+			}
+			return m, nil
+		case "x":
+			if r, ok := m.selected(); ok {
+				m.diffId = r.Id
+				m.diffTitle = r.HumanId + " | " + r.Title
+				if !m.yolo {
+					m.confirm = closeMergeRequest
+					return m, nil
+				}
+				return m.run(closeMergeRequest)
+			}
+			return m, nil
+		case "h":
+			if r, ok := m.selected(); ok {
+				return m, m.loadTargetDetails(r.Id, r.HumanId+" | "+r.Title)
+			}
+			return m, nil
+		case "t":
+			if r, ok := m.selected(); ok {
+				return m, m.retryNow(r.Id)
+			}
+			return m, nil
+		case "b":
+			if r, ok := m.selected(); ok {
+				return m, m.rebaseMergeRequest(r.Id)
+			}
+			return m, nil
+		case "e":
+			if r, ok := m.selected(); ok {
+				m.loading = "Description"
+				return m, m.loadEditText(r.Id)
+			}
+			return m, nil
+		case "B":
+			if r, ok := m.selected(); ok {
+				return m, m.requestRenovateRebase(r.Id)
+			}
+			return m, nil
+		case "m":
+			r, ok := m.cursorRow()
+			if !ok || !r.isHeader() || m.groupBy != byDependency || r.Project == otherDependencies {
+				return m, nil
+			}
+			m.groupIDs = nil
+			for _, member := range m.groupMembers(r) {
+				m.groupIDs = append(m.groupIDs, member.Id)
+			}
+			m.diffTitle = r.Project
+			if !m.yolo {
+				m.confirm = approveAndMergeGroup
+				return m, nil
+			}
+			return m.run(approveAndMergeGroup)
+		case "A":
+			if !m.yolo {
+				m.confirm = enableAllMatching
+				return m, nil
+			}
+			return m.run(enableAllMatching)
+		case "l":
+			m.labelFilter = m.nextLabelFilter()
+			m.updateRows(time.Now())
+			return m, m.saveViewState()
 		case "r":
-			return m, m.fetchMergeRequestsForced
+			if m.cancelRefresh != nil {
+				m.cancelRefresh()
+			}
+			var ctx context.Context
+			ctx, m.cancelRefresh = context.WithCancel(m.ctx)
+			return m, m.fetchMergeRequestsForced(ctx)
 		}
 		m.table, cmd = m.table.Update(msg)
 		return m, cmd
@@ -152,8 +487,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		footerHeight := lipgloss.Height(m.footerView())
 		verticalMarginHeight := headerHeight + footerHeight
 
-		m.table.SetHeight(msg.Height - 6)
+		m.table.SetHeight(msg.Height - 9)
+		m.tabView.Width = msg.Width
+		m.tabView.Height = msg.Height - 3
 		m.table.SetWidth(msg.Width - 5)
+		m.setColumns(msg.Width - 5)
 
 		if !m.ready {
 			// Since this program is using the full size of the viewport we
@@ -198,40 +536,157 @@ func (m model) View() string {
 		return "\n  Initializing..."
 	}
 	if m.loading != "" {
-		return m.spinner.View() + " Loading " + m.loading + "...\n"
+		view := m.spinner.View() + " Loading " + m.loading + "...\n"
+		if m.progress != "" {
+			view += "   " + m.progress + "\n"
+		}
+		return view
+	}
+	if m.passwordPrompt != nil {
+		return m.passwordPromptView() + "\n" + m.statusBar() + "\n"
+	}
+	if m.picker != nil {
+		return m.pickerView() + "\n" + m.statusBar() + "\n"
+	}
+	if m.details != nil {
+		return m.detailsView() + "\n" + m.statusBar() + "\n"
+	}
+	if m.confirm != noAction && m.diff == nil {
+		return m.confirmView() + "\n" + m.statusBar() + "\n"
+	}
+	if m.confirm != noAction {
+		return fmt.Sprintf("%s\n%s\n%s", m.headerView(), m.confirmView(), m.footerView())
 	}
 	if m.diff != nil {
 		return fmt.Sprintf("%s\n%s\n%s", m.headerView(), m.diffView.View(), m.footerView())
 	}
-	return baseStyle.Render(m.table.View()) + "\n"
+	if m.tab != mergeRequestsTab {
+		return m.tabViewContent() + "\n" + m.tabBar() + "\n" + m.statusBar() + "\n"
+	}
+	header := m.labelBar() + m.filterInfo() + m.searchInfo()
+	if m.searchInput != nil {
+		header = m.searchInput.View()
+	}
+	return header + "\n" + baseStyle.Render(m.table.View()) + "\n" + m.tabBar() + "\n" + m.statusBar() + "\n"
+}
+
+// selected returns the merge request under the cursor
+func (m model) selected() (mergeRequest, bool) {
+	r, ok := m.cursorRow()
+	if !ok || r.isHeader() {
+		return mergeRequest{}, false
+	}
+	return r, true
+}
+
+// cursorRow returns the merge request or project group header under the cursor
+func (m model) cursorRow() (mergeRequest, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.visible) {
+		return mergeRequest{}, false
+	}
+	return m.visible[cursor], true
+}
+
+// countdown renders the time until t like "in 1m05s", or "now" if t has passed
+func countdown(t time.Time, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	switch {
+	case d <= 0:
+		return "now"
+	case d < time.Minute:
+		return fmt.Sprintf("in %ds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("in %dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+type tickMsg time.Time
+
+// tick re-renders the relative times in the table every second
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// statusBar shows when the merge requests were synced last and how much of the api is used
+func (m model) statusBar() string {
+	lastSync := "never synced"
+	if !m.lastSync.IsZero() {
+		lastSync = "synced " + humanize.RelTime(m.lastSync, time.Now(), "ago", "from now")
+	}
+	if m.syncing {
+		lastSync = m.spinner.View() + " syncing, " + lastSync
+	}
+	if m.progress != "" {
+		lastSync += " (" + m.progress + ")"
+	}
+	active := 0
+	for _, r := range m.mergeRequests {
+		if r.Active {
+			active++
+		}
+	}
+	rateLimit := "rate limit unknown"
+	if limit, remaining := m.mrm.APIStats().RateLimit(); remaining >= 0 {
+		rateLimit = fmt.Sprintf("rate limit %d/%d left", remaining, limit)
+	}
+	position := "-"
+	if len(m.visible) > 0 {
+		position = fmt.Sprintf("%d/%d", m.table.Cursor()+1, len(m.visible))
+	}
+	if r, ok := m.cursorRow(); ok && noColor {
+		// the selected row is only highlighted by colors
+		position += " " + r.HumanId
+		if r.isHeader() {
+			position += r.Project
+		}
+	}
+	status := fmt.Sprintf(" %s | row %s | %d merge requests, %d active targets | %d api calls in the last minute | %s",
+		lastSync, position, len(m.mergeRequests), active, m.mrm.APIStats().CallsLastMinute(), rateLimit)
+	if m.readOnly != "" {
+		status += " | " + m.readOnly
+	}
+	if m.notice != "" {
+		status += " | " + m.notice
+	}
+	return statusStyle.Render(status)
 }
 
 func (m model) headerView() string {
 	title := titleStyle.Render(m.diffTitle)
-	line := strings.Repeat("─", max(0, m.diffView.Width-lipgloss.Width(title)))
+	line := strings.Repeat(sym.rule, max(0, m.diffView.Width-lipgloss.Width(title)))
 	return lipgloss.JoinHorizontal(lipgloss.Center, title, line)
 }
 
 func (m model) footerView() string {
-	info := infoStyle.Render(fmt.Sprintf("%3.f%%", m.diffView.ScrollPercent()*100))
-	line := strings.Repeat("─", max(0, m.diffView.Width-lipgloss.Width(info)))
+	percent := fmt.Sprintf("%3.f%%", m.diffView.ScrollPercent()*100)
+	if m.notice != "" {
+		percent = m.notice + " | " + percent
+	}
+	info := infoStyle.Render(percent)
+	line := strings.Repeat(sym.rule, max(0, m.diffView.Width-lipgloss.Width(info)))
 	return lipgloss.JoinHorizontal(lipgloss.Center, line, info)
 }
 
+// mergeRequestor periodically triggers a fetch, updates in between are driven by the manager's events
 func (m model) mergeRequestor() tea.Cmd {
-	return tea.Every(1*time.Second, func(time.Time) tea.Msg {
-		return m.fetchMergeRequestsVariable(false, false)
+	return tea.Every(15*time.Second, func(time.Time) tea.Msg {
+		return m.fetchMergeRequestsVariable(m.ctx, false, false)
 	})
 }
 func (m model) fetchMergeRequests() tea.Msg {
-	return m.fetchMergeRequestsVariable(true, false)
+	return m.fetchMergeRequestsVariable(m.ctx, true, false)
 }
 
-func (m model) fetchMergeRequestsForced() tea.Msg {
-	return m.fetchMergeRequestsVariable(true, true)
+// fetchMergeRequestsForced refetches the merge requests, ctx allows to abort the fetch if a new refresh is requested
+func (m model) fetchMergeRequestsForced(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		return m.fetchMergeRequestsVariable(ctx, true, true)
+	}
 }
 
-func (m model) fetchMergeRequestsVariable(oneshot bool, force bool) tea.Msg {
+func (m model) fetchMergeRequestsVariable(ctx context.Context, oneshot bool, force bool) tea.Msg {
 	if oneshot || force {
 		start := time.Now()
 		defer func() {
@@ -239,85 +694,240 @@ func (m model) fetchMergeRequestsVariable(oneshot bool, force bool) tea.Msg {
 		}()
 	}
 
-	requests, err := m.mrm.GetOrFetchMergeRequests(force)
+	requests, err := m.mrm.GetOrFetchMergeRequests(ctx, force)
 	if err != nil {
 		log.Println("Error fetching merge requests", err)
-		return mergeRequests{err: err}
+		return mergeRequests{err: err, oneShot: oneshot}
+	}
+	return m.toMergeRequests(requests, oneshot)
+}
+
+// reloadMergeRequests reads the merge requests from the cache without fetching them
+func (m model) reloadMergeRequests() tea.Msg {
+	requests, err := m.mrm.GetMergeRequests()
+	if err != nil {
+		log.Println("Error loading merge requests", err)
+		return mergeRequests{err: err, oneShot: true}
+	}
+	return m.toMergeRequests(requests, true)
+}
+
+// waitForEvent delivers the next event of the manager as message
+func (m model) waitForEvent() tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-m.events
+		if !ok {
+			return nil
+		}
+		return e
 	}
+}
 
+func (m model) toMergeRequests(requests []ggl.MergeRequestInfo, oneshot bool) mergeRequests {
 	var mrs mergeRequests
+	projects := make(map[int]*gitlab.Project)
 	for _, r := range requests {
-		mrs.requests = append(mrs.requests, m.mapMergeRequest(&r))
+		mrs.requests = append(mrs.requests, m.mapMergeRequest(&r, projects))
 	}
 	mrs.oneShot = oneshot
+	mrs.lastSync, _ = m.mrm.LastSync()
 	return mrs
 }
 
 type mergeRequests struct {
 	requests []mergeRequest
 	oneShot  bool
+	lastSync time.Time
 	err      error
 }
 
 type mergeRequest struct {
 	MergeStatus string
+	Approvals   string
 	HumanId     string
 	Id          int
+	IID         int
 	Title       string
-	Active      bool
-	Info        string
-	LastUpdate  time.Time
+	// Description and SourceBranch aren't shown in the table, they are matched by the search (/)
+	Description  string
+	SourceBranch string
+	Active       bool
+	Info         string
+	LastUpdate   time.Time
+	Created      time.Time
+	// AgeNote flags merge requests that are too young to merge or stale
+	AgeNote string
+	// Dependency is the dependency and version a renovate merge request updates to, empty for other merge requests
+	Dependency string
+	// Excluded merge requests are authored by an excluded author, they are shown but can't be acted on
+	Excluded bool
+	// Pipeline is the status of the head pipeline, empty until the merge request was read on its own
+	Pipeline    string
+	PipelineURL string
 	LastAction  time.Time
 	NextAction  time.Time
+	Labels      []ggl.Label
+	ProjectID   int
+	Project     string
+	// GroupSize is set on the header rows of project groups to the number of merge requests in the group
+	GroupSize int
+	Collapsed bool
+	Outcome   ggl.OutcomeState
+	// FetchedAt is when the merge request was last fetched, zero if unknown
+	FetchedAt time.Time
 }
 
-func (m model) mapMergeRequest(r *ggl.MergeRequestInfo) mergeRequest {
-	p, err := m.mrm.GetProject(r.ProjectID)
-	if err != nil {
-		log.Println("Error fetching project", r.ProjectID, err)
-		return mergeRequest{}
+// mapMergeRequest maps the merge request for the table, projects are looked up once per refresh
+func (m model) mapMergeRequest(r *ggl.MergeRequestInfo, projects map[int]*gitlab.Project) mergeRequest {
+	p, ok := projects[r.ProjectID]
+	if !ok {
+		var err error
+		p, err = m.mrm.GetProject(r.ProjectID)
+		if err != nil {
+			log.Println("Error fetching project", r.ProjectID, err)
+			return mergeRequest{}
+		}
+		projects[r.ProjectID] = p
 	}
-	var lastUpdate time.Time
+	var lastUpdate, created time.Time
 	if r.UpdatedAt != nil {
 		lastUpdate = *r.UpdatedAt
 	}
+	if r.CreatedAt != nil {
+		created = *r.CreatedAt
+	}
+	info := r.Target.Info
+	if info == "" && !r.CanMerge {
+		info = "token user cannot merge (needs Maintainer)"
+	}
+	if marker := ggl.HoldMarker(&r.MergeRequest); info == "" && marker != "" {
+		info = "held by marker " + marker
+	}
+	var pipeline, pipelineURL string
+	if r.HeadPipeline != nil {
+		pipeline, pipelineURL = r.HeadPipeline.Status, r.HeadPipeline.WebURL
+	} else if r.Pipeline != nil {
+		pipeline, pipelineURL = r.Pipeline.Status, r.Pipeline.WebURL
+	}
+	excluded := m.mrm.Excluded(&r.MergeRequest)
+	if excluded {
+		info = "author " + r.Author.Username + " excluded - not processed"
+	}
 	return mergeRequest{
-		Id:          r.ID,
-		HumanId:     p.Name + "!" + strconv.Itoa(r.IID),
-		Title:       r.Title,
-		MergeStatus: r.DetailedMergeStatus,
-		Active:      r.Target.Active,
-		Info:        r.Target.Info,
-		LastAction:  r.Target.Latest,
-		NextAction:  r.Target.Next,
-		LastUpdate:  lastUpdate,
+		Id:           r.ID,
+		HumanId:      p.Name + "!" + strconv.Itoa(r.IID),
+		IID:          r.IID,
+		Title:        r.Title,
+		Description:  r.Description,
+		MergeStatus:  r.DetailedMergeStatus,
+		Approvals:    r.Approvals.String(),
+		Active:       r.Target.Active,
+		Info:         info,
+		LastAction:   r.Target.Latest,
+		NextAction:   r.Target.Next,
+		LastUpdate:   lastUpdate,
+		Created:      created,
+		AgeNote:      m.mrm.AgeNote(&r.MergeRequest),
+		Dependency:   dependency(r.Title),
+		Excluded:     excluded,
+		Pipeline:     pipeline,
+		PipelineURL:  pipelineURL,
+		Labels:       r.LabelDetails,
+		ProjectID:    r.ProjectID,
+		Project:      p.PathWithNamespace,
+		SourceBranch: r.SourceBranch,
+		Outcome:      r.Target.Outcome.State,
+		FetchedAt:    r.FetchedAt,
 	}
 }
 
 func (m model) InitialFetch() tea.Cmd {
 	return func() tea.Msg {
-		return m.fetchMergeRequestsVariable(false, false)
+		return m.fetchMergeRequestsVariable(m.ctx, false, false)
 	}
 }
 
 func (m model) loadDiff(id int) tea.Cmd {
 	return func() tea.Msg {
-		diff, err := m.mrm.PullDiff(id)
+		diff, err := m.mrm.PullDiff(m.ctx, id)
 		if err != nil {
 			log.Println("Error fetching diff", err)
 			return err
 		}
-		log.Println("Diff", diff)
-		return diff
+		mr, err := m.mrm.GetMergeRequest(id)
+		if err != nil {
+			return err
+		}
+		return loadedDiff{diffs: diff, generated: m.mrm.GeneratedPatterns(m.ctx, mr.ProjectID)}
 	}
 }
 
 type approvalState struct {
 }
 
+// mergeAction is what happens to the merge request shown in the diff view
+type mergeAction int
+
+const (
+	noAction mergeAction = iota
+	approveAndMerge
+	approveOnly
+	mergeOnly
+	closeMergeRequest
+	enableAllMatching
+	approveAndMergeGroup
+	ignoreDependency
+	pinDependency
+)
+
+func (a mergeAction) String() string {
+	switch a {
+	case approveAndMerge:
+		return "Approve & merge"
+	case approveOnly:
+		return "Approve only"
+	case mergeOnly:
+		return "Merge without approving"
+	case closeMergeRequest:
+		return "Close"
+	case enableAllMatching:
+		return "Approve & merge all green merge requests"
+	case approveAndMergeGroup:
+		return "Approve & merge group"
+	case ignoreDependency:
+		return "Open merge request to ignore the dependency in renovate"
+	case pinDependency:
+		return "Open merge request to pin the dependency below this version in renovate"
+	}
+	return ""
+}
+
+// run executes the action on the merge request shown in the diff view
+func (m model) run(action mergeAction) (tea.Model, tea.Cmd) {
+	m.loading = action.String() + " " + m.diffTitle
+	switch action {
+	case enableAllMatching:
+		m.loading = action.String()
+		return m, m.enableAllMatching()
+	case approveAndMergeGroup:
+		return m, m.approveAndMergeGroup(m.groupIDs)
+	case ignoreDependency:
+		return m, m.stopDependency(m.diffId, ggl.StopIgnore)
+	case pinDependency:
+		return m, m.stopDependency(m.diffId, ggl.StopPin)
+	case approveOnly:
+		return m, m.approveMergeRequest(m.diffId, m.diff)
+	case mergeOnly:
+		return m, m.mergeMergeRequest(m.diffId, m.diff)
+	case closeMergeRequest:
+		return m, m.closeMergeRequest(m.diffId)
+	}
+	return m, m.approveAndMergeMergeRequest(m.diffId, m.diff)
+}
+
 func (m model) approveAndMergeMergeRequest(id int, diff []*gitlab.MergeRequestDiff) tea.Cmd {
 	return func() tea.Msg {
-		err := m.mrm.ApproveAndMergeMergeRequest(id, diff)
+		err := m.mrm.ApproveAndMergeMergeRequest(m.ctx, id, diff)
 		if err != nil {
 			log.Println("Error approving and merging", err)
 			return err
@@ -326,6 +936,128 @@ func (m model) approveAndMergeMergeRequest(id int, diff []*gitlab.MergeRequestDi
 	}
 }
 
+// approveAndMergeGroup approves and merges every merge request of a dependency group with its current diff
+func (m model) approveAndMergeGroup(ids []int) tea.Cmd {
+	return func() tea.Msg {
+		var errs error
+		for _, id := range ids {
+			diff, err := m.mrm.PullDiff(m.ctx, id)
+			if err != nil {
+				log.Println("Error fetching diff", id, err)
+				errs = errors.Join(errs, err)
+				continue
+			}
+			if err := m.mrm.ApproveAndMergeMergeRequest(m.ctx, id, diff); err != nil {
+				log.Println("Error approving and merging", id, err)
+				errs = errors.Join(errs, err)
+			}
+		}
+		if errs != nil {
+			return errs
+		}
+		return approvalState{}
+	}
+}
+
+func (m model) approveMergeRequest(id int, diff []*gitlab.MergeRequestDiff) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.ApproveMergeRequest(m.ctx, id, diff)
+		if err != nil {
+			log.Println("Error approving", err)
+			return err
+		}
+		return approvalState{}
+	}
+}
+
+func (m model) closeMergeRequest(id int) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.CloseMergeRequest(m.ctx, id)
+		if err != nil {
+			log.Println("Error closing", err)
+			return err
+		}
+		return approvalState{}
+	}
+}
+
+// enabledAll is the number of merge requests enabled by enableAllMatching
+type enabledAll int
+
+func (m model) enableAllMatching() tea.Cmd {
+	return func() tea.Msg {
+		enabled, err := m.mrm.EnableAllMatching(m.ctx)
+		if err != nil {
+			log.Println("Error enabling all matching", err)
+			if enabled == 0 {
+				return err
+			}
+		}
+		return enabledAll(enabled)
+	}
+}
+
+// followUpOpened is the url of the merge request opened to change the renovate config
+type followUpOpened string
+
+func (m model) stopDependency(id int, rule ggl.StopRule) tea.Cmd {
+	return func() tea.Msg {
+		mr, err := m.mrm.StopDependency(m.ctx, id, rule)
+		if err != nil {
+			log.Println("Error stopping dependency", err)
+			return err
+		}
+		return followUpOpened(mr.WebURL)
+	}
+}
+
+func (m model) retryNow(id int) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.RetryNow(id)
+		if err != nil {
+			log.Println("Error retrying", err)
+			return err
+		}
+		return nil
+	}
+}
+
+func (m model) rebaseMergeRequest(id int) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.RebaseMergeRequest(m.ctx, id)
+		if err != nil {
+			log.Println("Error rebasing", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// renovateRebaseRequested confirms that renovate's rebase checkbox was ticked
+type renovateRebaseRequested struct{}
+
+func (m model) requestRenovateRebase(id int) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.RequestRenovateRebase(m.ctx, id)
+		if err != nil {
+			log.Println("Error requesting renovate rebase", err)
+			return err
+		}
+		return renovateRebaseRequested{}
+	}
+}
+
+func (m model) mergeMergeRequest(id int, diff []*gitlab.MergeRequestDiff) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.MergeMergeRequest(m.ctx, id, diff)
+		if err != nil {
+			log.Println("Error merging", err)
+			return err
+		}
+		return approvalState{}
+	}
+}
+
 func (m model) clearMerge(id int) tea.Cmd {
 	return func() tea.Msg {
 		err := m.mrm.ClearMerge(id)
@@ -333,36 +1065,56 @@ func (m model) clearMerge(id int) tea.Cmd {
 			log.Println("Error clearing merge", err)
 			return err
 		}
-		return m.fetchMergeRequestsForced()
+		return m.fetchMergeRequestsVariable(m.ctx, true, true)
 	}
 }
 
-func AutoMerge(author, reviewer, logFile string) error {
+// Options configure the auto merge ui
+type Options struct {
+	Author    string
+	Reviewer  string
+	Assignee  string
+	Milestone string
+	LogFile   string
+	// ReviewerGroup also selects the merge requests with a member of the group as reviewer
+	ReviewerGroup string
+	// Yolo skips the confirmation before a merge request is approved, merged or closed
+	Yolo bool
+	// ReadOnly shows the merge requests, diffs and targets but disables every action that changes them, targets are
+	// not processed
+	ReadOnly bool
+	// Manager are additional options for the MergeRequestManager
+	Manager []ggl.Option
+}
+
+func AutoMerge(ctx context.Context, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	buf := bytes.NewBuffer(nil)
-	log.SetOutput(buf)
-	if logFile != "" {
-		f, err := tea.LogToFile(logFile, "")
+	ggl.SetLogOutput(buf)
+	if opts.LogFile != "" {
+		f, err := os.OpenFile(opts.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
 		if err != nil {
-			fmt.Println("fatal:", err)
-			os.Exit(1)
+			return err
 		}
 		defer f.Close()
+		ggl.SetLogOutput(f)
 	}
 
-	columns := []table.Column{
-		{Title: "#", Width: 20},
-		{Title: "Title", Width: 80},
-		{Title: "Updated", Width: 20},
-		{Title: "State", Width: 15},
-		{Title: "Action Info", Width: 40},
-		{Title: "Last Action", Width: 20},
-		{Title: "Next Try", Width: 20},
+	config, err := ggl.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	columns, err := configuredColumns(config.Columns)
+	if err != nil {
+		return err
 	}
 
 	rows := []table.Row{}
 
 	t := table.New(
-		table.WithColumns(columns),
+		table.WithColumns(tableColumns(columns)),
 		table.WithRows(rows),
 		table.WithFocused(true),
 		table.WithHeight(7),
@@ -370,7 +1122,7 @@ func AutoMerge(author, reviewer, logFile string) error {
 
 	s := table.DefaultStyles()
 	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
+		BorderStyle(tableBorder()).
 		BorderForeground(lipgloss.Color("240")).
 		BorderBottom(true).
 		Bold(false)
@@ -380,28 +1132,61 @@ func AutoMerge(author, reviewer, logFile string) error {
 		Bold(false)
 	t.SetStyles(s)
 
-	gl, err := ggl.GetDefaultClient()
+	mrm, err := ggl.NewDefaultMergeRequestManager(opts.Manager...)
 	if err != nil {
-		fmt.Println("Error getting client", err)
 		return err
 	}
-
-	badger, err := ggl.GetDefaultDb()
+	mrm.Reviewer(opts.Reviewer).ReviewerGroup(opts.ReviewerGroup).Author(opts.Author).Assignee(opts.Assignee).Milestone(opts.Milestone)
+	// an unknown user would show an empty table forever
+	err = mrm.CheckFilterUsers(ctx)
 	if err != nil {
-		fmt.Println("Error getting badger", err)
 		return err
 	}
-
+	var state viewState
+	err = mrm.LoadSetting(viewStateSetting, &state)
+	if err != nil {
+		log.Println("Error loading view state", err)
+	}
+	collapsed := make(map[string]bool)
+	for _, p := range state.Collapsed {
+		collapsed[p] = true
+	}
+	var readOnly string
+	if opts.ReadOnly {
+		readOnly = "read-only"
+	} else if access := tokenAccess(ctx, mrm); !access.CanWrite {
+		readOnly = "read-only, the token has no api scope (" + strings.Join(access.Scopes, ", ") + ")"
+	} else {
+		mrm.Start(ctx)
+	}
+	loading := "Merge Requests"
+	if cached, err := mrm.GetMergeRequests(); err == nil && len(cached) > 0 {
+		loading = ""
+	}
 	m := model{
-		table:   t,
-		gl:      gl,
-		mrm:     ggl.NewMergeRequestManager(badger, gl).Reviewer(reviewer).Author(author).Start(),
-		spinner: spinner.New(spinner.WithSpinner(spinner.Moon)),
-		loading: "Merge Requests"}
+		table:         t,
+		ctx:           ctx,
+		yolo:          opts.Yolo || config.Yolo,
+		columns:       columns,
+		labelFilter:   state.LabelFilter,
+		groupBy:       state.groupMode(),
+		statusFilter:  statusFilter(state.StatusFilter),
+		sortColumn:    state.sortOrder().column,
+		sortDesc:      state.sortOrder().desc,
+		collapsed:     collapsed,
+		events:        mrm.Subscribe(ctx),
+		mrm:           mrm,
+		readOnly:      readOnly,
+		spinner:       spinner.New(spinner.WithSpinner(loadingSpinner())),
+		tabView:       viewport.New(0, 0),
+		markdownStyle: markdownStyle(),
+		syncing:       true,
+		loading:       loading}
 	p := tea.NewProgram(
 		m,
 		tea.WithAltScreen(),       // use the full size of the terminal in its "alternate screen buffer"
 		tea.WithMouseCellMotion(), // turn on mouse support so we can track the mouse wheel
+		tea.WithContext(ctx),
 	)
 	if _, err := p.Run(); err != nil {
 		return err
@@ -0,0 +1,32 @@
+package glui
+
+import (
+	"github.com/muesli/termenv"
+	"strconv"
+)
+
+// yank copies the web url or, if reference is set, the project!iid reference of the merge request to the
+// clipboard using OSC 52, which also works over ssh in terminals supporting it
+func (m model) yank(id int, reference bool) string {
+	mr, err := m.mrm.GetMergeRequest(id)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	text := mr.WebURL
+	if reference {
+		text = m.reference(mr.ProjectID, mr.IID)
+		if mr.References != nil && mr.References.Full != "" {
+			text = mr.References.Full
+		}
+	}
+	termenv.Copy(text)
+	return "copied " + text
+}
+
+func (m model) reference(projectID int, iid int) string {
+	p, err := m.mrm.GetProject(projectID)
+	if err != nil {
+		return "!" + strconv.Itoa(iid)
+	}
+	return p.PathWithNamespace + "!" + strconv.Itoa(iid)
+}
@@ -0,0 +1,163 @@
+package glui
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"log"
+	"strings"
+	"time"
+)
+
+// column is a column of the merge request table and how its cells are rendered
+type column struct {
+	title string
+	width int
+	cell  func(r mergeRequest, now time.Time) string
+	// order compares the merge requests for sorting if the cell text doesn't sort right, e.g. relative times
+	order func(a, b mergeRequest) int
+}
+
+var allColumns = []column{
+	{title: "#", width: 20, cell: func(r mergeRequest, now time.Time) string { return r.HumanId }, order: func(a, b mergeRequest) int {
+		return cmp.Or(strings.Compare(a.Project, b.Project), cmp.Compare(a.IID, b.IID))
+	}},
+	{title: "Title", width: 80, cell: func(r mergeRequest, now time.Time) string { return r.Title }},
+	{title: "Labels", width: 25, cell: func(r mergeRequest, now time.Time) string { return labelNames(r.Labels) }},
+	{title: "Updated", width: 20, cell: func(r mergeRequest, now time.Time) string {
+		return humanize.RelTime(r.LastUpdate, now, "ago", "from now")
+	}, order: func(a, b mergeRequest) int { return a.LastUpdate.Compare(b.LastUpdate) }},
+	{title: "Age", width: 20, cell: func(r mergeRequest, now time.Time) string {
+		if r.Created.IsZero() {
+			return ""
+		}
+		age := strings.TrimSpace(humanize.RelTime(r.Created, now, "", ""))
+		if r.AgeNote != "" {
+			age += " (" + r.AgeNote + ")"
+		}
+		return age
+	}, order: func(a, b mergeRequest) int { return b.Created.Compare(a.Created) }},
+	{title: "State", width: 15, cell: func(r mergeRequest, now time.Time) string { return r.MergeStatus }},
+	{title: "Approvals", width: 14, cell: func(r mergeRequest, now time.Time) string { return r.Approvals }},
+	{title: "Action Info", width: 40, cell: func(r mergeRequest, now time.Time) string { return r.Info }},
+	{title: "Last Action", width: 20, cell: func(r mergeRequest, now time.Time) string {
+		if r.LastAction.IsZero() {
+			return ""
+		}
+		return humanize.RelTime(r.LastAction, now, "ago", "from now")
+	}, order: func(a, b mergeRequest) int { return a.LastAction.Compare(b.LastAction) }},
+	{title: "Next Try", width: 20, cell: func(r mergeRequest, now time.Time) string {
+		if !r.Active {
+			return ""
+		}
+		return countdown(r.NextAction, now)
+	}, order: func(a, b mergeRequest) int {
+		// merge requests without a next try come last
+		if a.Active != b.Active {
+			if a.Active {
+				return -1
+			}
+			return 1
+		}
+		return a.NextAction.Compare(b.NextAction)
+	}},
+}
+
+// configuredColumns selects the columns in the configured order, all columns if none are configured
+func configuredColumns(config []ggl.ColumnConfig) ([]column, error) {
+	if len(config) == 0 {
+		return allColumns, nil
+	}
+	columns := make([]column, 0, len(config))
+	for _, cc := range config {
+		c, ok := columnByTitle(cc.Title)
+		if !ok {
+			titles := make([]string, len(allColumns))
+			for i, c := range allColumns {
+				titles[i] = c.title
+			}
+			return nil, fmt.Errorf("unknown column %q, known columns: %s", cc.Title, strings.Join(titles, ", "))
+		}
+		if cc.Width > 0 {
+			c.width = cc.Width
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+func columnByTitle(title string) (column, bool) {
+	for _, c := range allColumns {
+		if strings.EqualFold(c.title, title) {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// fitColumns scales the configured widths proportionally to the table width, every cell is padded by one space
+// on both sides and content that does not fit is truncated with an ellipsis by the table
+func fitColumns(columns []column, width int) []table.Column {
+	total := 0
+	for _, c := range columns {
+		total += c.width
+	}
+	available := width - 2*len(columns)
+	if total == 0 || available <= 0 {
+		return tableColumns(columns)
+	}
+	tc := make([]table.Column, len(columns))
+	for i, c := range columns {
+		tc[i] = table.Column{Title: c.title, Width: max(minColumnWidth, c.width*available/total)}
+	}
+	return tc
+}
+
+const minColumnWidth = 3
+
+func tableColumns(columns []column) []table.Column {
+	tc := make([]table.Column, len(columns))
+	for i, c := range columns {
+		tc[i] = table.Column{Title: c.title, Width: c.width}
+	}
+	return tc
+}
+
+const viewStateSetting = "automerge-view"
+
+// viewState is the part of the table layout that is restored in the next session
+type viewState struct {
+	LabelFilter string
+	// Grouped is the grouping by project of earlier versions, GroupBy replaces it
+	Grouped   bool
+	GroupBy   string
+	Collapsed []string
+	// StatusFilter is one of the statusFilters, empty for none
+	StatusFilter string
+	// SortColumn is the title of the column the table is sorted by, empty for the last updated first
+	SortColumn string
+	SortDesc   bool
+}
+
+func (m model) saveViewState() tea.Cmd {
+	state := viewState{LabelFilter: m.labelFilter, GroupBy: string(m.groupBy), Collapsed: m.collapsedProjects(),
+		StatusFilter: string(m.statusFilter), SortColumn: m.sortColumn, SortDesc: m.sortDesc}
+	return func() tea.Msg {
+		err := m.mrm.StoreSetting(viewStateSetting, state)
+		if err != nil {
+			log.Println("Error storing view state", err)
+		}
+		return nil
+	}
+}
+
+// groupMode restores the grouping, view states of earlier versions only knew grouping by project
+func (s viewState) groupMode() groupMode {
+	if s.GroupBy == "" && s.Grouped {
+		return byProject
+	}
+	return groupMode(s.GroupBy)
+}
@@ -0,0 +1,73 @@
+package glui
+
+import (
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/xanzy/go-gitlab"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var confirmStyle = lipgloss.NewStyle().
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("57")).
+	Padding(1, 2)
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// confirmView asks whether the pending action should be run on the merge request shown in the diff view
+// or selected in the table, enabling all green merge requests and merging a dependency group are confirmed without one
+func (m model) confirmView() string {
+	if m.confirm == enableAllMatching {
+		body := fmt.Sprintf("%s?\n\nEvery mergeable merge request and every one waiting for its pipeline\nis approved and merged without reviewing its diff.\n\n[y] yes  [n] no", m.confirm)
+		return lipgloss.Place(m.table.Width(), m.table.Height(), lipgloss.Center, lipgloss.Center, confirmStyle.Render(body))
+	}
+	if m.confirm == approveAndMergeGroup {
+		body := fmt.Sprintf("%s?\n\nDependency:     %s\nMerge requests: %d\n\n[y] yes  [n] no", m.confirm, m.diffTitle, len(m.groupIDs))
+		return lipgloss.Place(m.table.Width(), m.table.Height(), lipgloss.Center, lipgloss.Center, confirmStyle.Render(body))
+	}
+	project := ""
+	if mr, err := m.mrm.GetMergeRequest(m.diffId); err == nil {
+		if p, err := m.mrm.GetProject(mr.ProjectID); err == nil {
+			project = p.NameWithNamespace
+		}
+	}
+	body := fmt.Sprintf("%s?\n\nProject:       %s\nMerge request: %s\n", m.confirm, project, m.diffTitle)
+	if m.diff != nil {
+		body += fmt.Sprintf("Files changed: %d\nUpdate type:   %s\n", len(m.diff), updateType(m.diff))
+	}
+	body += "\n[y] yes  [n] no"
+	if m.diff == nil {
+		return lipgloss.Place(m.table.Width(), m.table.Height(), lipgloss.Center, lipgloss.Center, confirmStyle.Render(body))
+	}
+	return lipgloss.Place(m.diffView.Width, m.diffView.Height+2, lipgloss.Center, lipgloss.Center, confirmStyle.Render(body))
+}
+
+// updateType guesses the kind of dependency update (major, minor, patch) by comparing the first version removed
+// with the first version added in the diff
+func updateType(diff []*gitlab.MergeRequestDiff) string {
+	var removed, added []string
+	for _, d := range diff {
+		for _, line := range strings.Split(d.Diff, "\n") {
+			switch {
+			case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			case strings.HasPrefix(line, "-") && removed == nil:
+				removed = versionPattern.FindStringSubmatch(line)
+			case strings.HasPrefix(line, "+") && added == nil:
+				added = versionPattern.FindStringSubmatch(line)
+			}
+		}
+	}
+	if removed == nil || added == nil {
+		return "unknown"
+	}
+	for i, kind := range []string{"major", "minor", "patch"} {
+		from, _ := strconv.Atoi(removed[i+1])
+		to, _ := strconv.Atoi(added[i+1])
+		if from != to {
+			return kind
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,198 @@
+package glui
+
+import (
+	"context"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/icza/gox/osx"
+	"github.com/muesli/termenv"
+	"strings"
+	"time"
+)
+
+var (
+	sectionStyle      = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedItemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
+)
+
+// dashboardSection is one list of the dashboard with the items it shows
+type dashboardSection struct {
+	title string
+	items []ggl.DashboardItem
+}
+
+// fetchedDashboard is the result of refreshing the dashboard
+type fetchedDashboard struct {
+	dashboard ggl.Dashboard
+	err       error
+}
+
+// dashboardModel is the "my work" screen: the issues and merge requests of the current user in sections with a
+// cursor across all of them
+type dashboardModel struct {
+	ctx       context.Context
+	mrm       *ggl.MergeRequestManager
+	dashboard ggl.Dashboard
+	cursor    int
+	height    int
+	loading   bool
+	notice    string
+}
+
+func (m dashboardModel) sections() []dashboardSection {
+	return []dashboardSection{
+		{"Assigned issues", m.dashboard.Issues},
+		{"Awaiting my review", m.dashboard.ReviewRequests},
+		{"My merge requests", m.dashboard.MergeRequests},
+		{"Failed pipelines", m.dashboard.FailedPipelines},
+	}
+}
+
+func (m dashboardModel) items() []ggl.DashboardItem {
+	var items []ggl.DashboardItem
+	for _, s := range m.sections() {
+		items = append(items, s.items...)
+	}
+	return items
+}
+
+func (m dashboardModel) selected() (ggl.DashboardItem, bool) {
+	items := m.items()
+	if m.cursor < 0 || m.cursor >= len(items) {
+		return ggl.DashboardItem{}, false
+	}
+	return items[m.cursor], true
+}
+
+func (m dashboardModel) refresh() tea.Msg {
+	d, err := m.mrm.FetchDashboard(m.ctx)
+	return fetchedDashboard{dashboard: d, err: err}
+}
+
+func (m dashboardModel) Init() tea.Cmd {
+	return m.refresh
+}
+
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case fetchedDashboard:
+		m.loading = false
+		if msg.err != nil {
+			m.notice = "error refreshing: " + msg.err.Error()
+			return m, nil
+		}
+		m.dashboard = msg.dashboard
+		m.cursor = min(m.cursor, max(0, len(m.items())-1))
+		m.notice = ""
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor = max(0, m.cursor-1)
+		case "down", "j":
+			m.cursor = min(max(0, len(m.items())-1), m.cursor+1)
+		case "tab":
+			m.cursor = m.nextSection(m.cursor)
+		case "o", "enter":
+			if item, ok := m.selected(); ok {
+				_ = osx.OpenDefault(item.WebURL)
+			}
+		case "y":
+			if item, ok := m.selected(); ok {
+				termenv.Copy(item.WebURL)
+				m.notice = "copied " + item.WebURL
+			}
+		case "r":
+			m.loading = true
+			return m, m.refresh
+		}
+	}
+	return m, nil
+}
+
+// nextSection is the index of the first item of the next section which has items, wrapping around to the first one
+func (m dashboardModel) nextSection(cursor int) int {
+	var starts []int
+	start := 0
+	for _, s := range m.sections() {
+		if len(s.items) > 0 {
+			starts = append(starts, start)
+		}
+		start += len(s.items)
+	}
+	for _, s := range starts {
+		if s > cursor {
+			return s
+		}
+	}
+	if len(starts) > 0 {
+		return starts[0]
+	}
+	return 0
+}
+
+func (m dashboardModel) View() string {
+	now := time.Now()
+	var lines []string
+	selectedLine := 0
+	i := 0
+	for _, s := range m.sections() {
+		lines = append(lines, sectionStyle.Render(fmt.Sprintf("%s (%d)", s.title, len(s.items))))
+		if len(s.items) == 0 {
+			lines = append(lines, statusStyle.Render("  nothing"))
+		}
+		for _, item := range s.items {
+			cursor := " "
+			if i == m.cursor {
+				cursor = ">"
+			}
+			line := fmt.Sprintf("%s %-40s %-12s %-20s %s", cursor, item.Reference, humanize.RelTime(item.UpdatedAt, now, "ago", ""), item.Info, item.Title)
+			if i == m.cursor {
+				selectedLine = len(lines)
+				line = selectedItemStyle.Render(line)
+			}
+			lines = append(lines, line)
+			i++
+		}
+		lines = append(lines, "")
+	}
+	// keep the selected item visible, the header and status bar take three lines
+	if visible := m.height - 3; visible > 0 && len(lines) > visible {
+		first := min(max(0, selectedLine-visible/2), len(lines)-visible)
+		lines = lines[first : first+visible]
+	}
+
+	status := "o open  y copy url  tab next section  r refresh  q quit"
+	if m.loading {
+		status = "refreshing... " + status
+	}
+	if m.notice != "" {
+		status = m.notice
+	}
+	header := "My work"
+	if m.dashboard.Username != "" {
+		header += " of " + m.dashboard.Username
+	}
+	if !m.dashboard.FetchedAt.IsZero() {
+		header += statusStyle.Render("  fetched " + humanize.RelTime(m.dashboard.FetchedAt, now, "ago", "from now"))
+	}
+	return header + "\n\n" + strings.Join(lines, "\n") + "\n" + statusStyle.Render(status)
+}
+
+// Dashboard shows the assigned issues, the merge requests awaiting review, the own open merge requests and their
+// failed pipelines of the current user. The last fetched dashboard is shown while it is refreshed.
+func Dashboard(ctx context.Context, mrm *ggl.MergeRequestManager) error {
+	cached, err := mrm.CachedDashboard()
+	if err != nil {
+		return err
+	}
+	m := dashboardModel{ctx: ctx, mrm: mrm, dashboard: cached, loading: true}
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
@@ -0,0 +1,80 @@
+package glui
+
+import (
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/muesli/termenv"
+	"github.com/xanzy/go-gitlab"
+	"os"
+	"strings"
+)
+
+// ColorizeDiff colors file headers, hunk headers and added or removed lines of a unified diff.
+// Colors are always emitted, independent of whether stdout is a terminal, unless they are disabled.
+func ColorizeDiff(diff string) string {
+	if noColor {
+		return diff
+	}
+	r := lipgloss.NewRenderer(os.Stdout)
+	r.SetColorProfile(termenv.ANSI)
+	fileStyle := r.NewStyle().Bold(true)
+	hunkStyle := r.NewStyle().Foreground(lipgloss.Color("6"))
+	addedStyle := r.NewStyle().Foreground(lipgloss.Color("2"))
+	removedStyle := r.NewStyle().Foreground(lipgloss.Color("1"))
+
+	lines := strings.Split(diff, "\n")
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			lines[i] = fileStyle.Render(l)
+		case strings.HasPrefix(l, "@@"):
+			lines[i] = hunkStyle.Render(l)
+		case strings.HasPrefix(l, "+"):
+			lines[i] = addedStyle.Render(l)
+		case strings.HasPrefix(l, "-"):
+			lines[i] = removedStyle.Render(l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadedDiff is the diff of a merge request together with the patterns of its project's generated files
+type loadedDiff struct {
+	diffs     []*gitlab.MergeRequestDiff
+	generated []string
+}
+
+// renderDiff renders the file diffs with a header per file, generated files like lockfiles are collapsed to a
+// summary of their additions and deletions unless expand is set
+func renderDiff(diffs []*gitlab.MergeRequestDiff, generated []string, expand bool) string {
+	var b strings.Builder
+	for _, d := range diffs {
+		name := d.NewPath
+		if d.OldPath != d.NewPath {
+			name = d.OldPath + sym.arrow + d.NewPath
+		}
+		if !expand && ggl.IsGenerated(d.NewPath, generated) {
+			added, removed := countChanges(d.Diff)
+			fmt.Fprintf(&b, "=== %s (generated, +%d -%d, press e to expand)\n\n", name, added, removed)
+			continue
+		}
+		fmt.Fprintf(&b, "=== %s\n%s\n", name, d.Diff)
+	}
+	return b.String()
+}
+
+// countChanges counts the added and removed lines of a diff
+func countChanges(diff string) (int, int) {
+	added, removed := 0, 0
+	for _, l := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+		case strings.HasPrefix(l, "+"):
+			added++
+		case strings.HasPrefix(l, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
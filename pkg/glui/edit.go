@@ -0,0 +1,98 @@
+package glui
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editText is the title and description of a merge request being edited, the temporary file holds the title on the
+// first line and the description after a blank line like a commit message
+type editText struct {
+	id          int
+	file        string
+	title       string
+	description string
+}
+
+// mergeRequestEdited is sent when the editor exited
+type mergeRequestEdited struct {
+	text editText
+	err  error
+}
+
+// mergeRequestSaved confirms that the edited title and description were saved, unchanged is set if there was nothing
+// to save
+type mergeRequestSaved struct {
+	unchanged bool
+}
+
+// loadEditText fetches the current title and description of the merge request and writes them to a temporary file
+func (m model) loadEditText(id int) tea.Cmd {
+	return func() tea.Msg {
+		title, description, err := m.mrm.MergeRequestText(m.ctx, id)
+		if err != nil {
+			log.Println("Error loading merge request text", err)
+			return err
+		}
+		f, err := os.CreateTemp("", "gitlab-util-mr-*.md")
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString(title + "\n\n" + description)
+		if err != nil {
+			return err
+		}
+		return editText{id: id, file: f.Name(), title: title, description: description}
+	}
+}
+
+// editMergeRequest suspends the ui and opens the text in the editor. The editor is taken from GITLAB_UTIL_EDITOR,
+// VISUAL or EDITOR and defaults to vi.
+func (m model) editMergeRequest(text editText) tea.Cmd {
+	editor := os.Getenv("GITLAB_UTIL_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], text.file)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return mergeRequestEdited{text: text, err: err}
+	})
+}
+
+// saveEditText saves the edited title and description if they changed and removes the temporary file
+func (m model) saveEditText(text editText) tea.Cmd {
+	return func() tea.Msg {
+		defer os.Remove(text.file)
+		content, err := os.ReadFile(text.file)
+		if err != nil {
+			return err
+		}
+		title, description, _ := strings.Cut(string(content), "\n")
+		title = strings.TrimSpace(title)
+		description = strings.TrimSpace(description)
+		if title == "" {
+			return fmt.Errorf("not saved, the title is empty")
+		}
+		if title == text.title && description == strings.TrimSpace(text.description) {
+			return mergeRequestSaved{unchanged: true}
+		}
+		err = m.mrm.EditMergeRequest(m.ctx, text.id, title, description)
+		if err != nil {
+			log.Println("Error saving merge request text", err)
+			return err
+		}
+		return mergeRequestSaved{}
+	}
+}
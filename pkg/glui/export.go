@@ -0,0 +1,87 @@
+package glui
+
+import (
+	"encoding/csv"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// exportCSV writes the merge requests of the current filtered view with all columns to a csv file in the working
+// directory and returns a notice naming the file
+func (m model) exportCSV(now time.Time) string {
+	name := fmt.Sprintf("merge-requests-%s.csv", now.Format("2006-01-02-150405"))
+	f, err := os.Create(name)
+	if err != nil {
+		return "export failed: " + err.Error()
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	header := []string{"Project", "URL"}
+	for _, c := range allColumns {
+		header = append(header, c.title)
+	}
+	_ = w.Write(header)
+	rows := 0
+	for _, r := range m.visible {
+		if r.isHeader() {
+			continue
+		}
+		record := []string{r.Project, ""}
+		if mr, err := m.mrm.GetMergeRequest(r.Id); err == nil {
+			record[1] = mr.WebURL
+		}
+		for _, c := range allColumns {
+			record = append(record, c.cell(r, now))
+		}
+		_ = w.Write(record)
+		rows++
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "export failed: " + err.Error()
+	}
+	return fmt.Sprintf("exported %d merge requests to %s", rows, name)
+}
+
+// exportDiff writes the viewed diff including the generated files to a file in the working directory named after the
+// merge request and returns a notice naming the file
+func (m model) exportDiff() string {
+	mr, err := m.mrm.GetMergeRequest(m.diffId)
+	if err != nil {
+		return "export failed: " + err.Error()
+	}
+	name := strings.NewReplacer("/", "-", "!", "-").Replace(m.reference(mr.ProjectID, mr.IID)) + ".diff"
+	err = os.WriteFile(name, []byte(ggl.RenderDiffString(m.diff)), 0644)
+	if err != nil {
+		return "export failed: " + err.Error()
+	}
+	return "exported the diff to " + name
+}
+
+// diffPaged is sent when the pager showing the diff exited
+type diffPaged struct {
+	err error
+}
+
+// pageDiff suspends the ui and pipes the viewed diff into the pager, e.g. delta. The pager is taken from
+// GITLAB_UTIL_PAGER like for mr diff or PAGER and defaults to less.
+func (m model) pageDiff() tea.Cmd {
+	pager := os.Getenv("GITLAB_UTIL_PAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less -R"
+	}
+	args := strings.Fields(pager)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(ggl.RenderDiffString(m.diff))
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return diffPaged{err: err}
+	})
+}
@@ -0,0 +1,71 @@
+package glui
+
+import (
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"slices"
+	"strings"
+)
+
+// statusFilter narrows the table to merge requests in a certain state
+type statusFilter string
+
+const (
+	noStatusFilter  statusFilter = ""
+	activeFilter    statusFilter = "active targets"
+	mergeableFilter statusFilter = "mergeable"
+	blockedFilter   statusFilter = "blocked"
+)
+
+// statusFilters are toggled with the keys 1, 2 and 3
+var statusFilters = []statusFilter{activeFilter, mergeableFilter, blockedFilter}
+
+// progressingStatuses are merge statuses that resolve without anybody acting on the merge request
+var progressingStatuses = []string{"mergeable", "checking", "unchecked", "ci_still_running", "approvals_syncing"}
+
+func (f statusFilter) matches(r mergeRequest) bool {
+	switch f {
+	case activeFilter:
+		return r.Active
+	case mergeableFilter:
+		return r.MergeStatus == "mergeable"
+	case blockedFilter:
+		return r.Outcome == ggl.OutcomeAborted || r.Outcome == ggl.OutcomeError || r.Outcome == ggl.OutcomeQuarantined ||
+			!slices.Contains(progressingStatuses, r.MergeStatus)
+	}
+	return true
+}
+
+// toggleStatusFilter sets the filter of the pressed key or removes it if it is already set
+func (m model) toggleStatusFilter(key string) statusFilter {
+	i := strings.Index("123", key)
+	if i < 0 || statusFilters[i] == m.statusFilter {
+		return noStatusFilter
+	}
+	return statusFilters[i]
+}
+
+// filterInfo shows the active status filter in the header
+func (m model) filterInfo() string {
+	if m.statusFilter == noStatusFilter {
+		return ""
+	}
+	return statusStyle.Render("  showing only " + string(m.statusFilter) + " (press again to show all)")
+}
+
+// visibleMergeRequests returns the merge requests matching the label and status filter and the search in the
+// sort order
+func (m model) visibleMergeRequests() []mergeRequest {
+	if m.labelFilter == "" && m.statusFilter == noStatusFilter && m.search == "" {
+		return m.sortMergeRequests(m.mergeRequests)
+	}
+	var visible []mergeRequest
+	for _, r := range m.mergeRequests {
+		if m.labelFilter != "" && !slices.ContainsFunc(r.Labels, func(l ggl.Label) bool { return l.Name == m.labelFilter }) {
+			continue
+		}
+		if m.statusFilter.matches(r) && m.matchesSearch(r) {
+			visible = append(visible, r)
+		}
+	}
+	return m.sortMergeRequests(visible)
+}
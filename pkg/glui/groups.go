@@ -0,0 +1,154 @@
+package glui
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"slices"
+)
+
+// isHeader reports whether the row is the header of a project or dependency group rather than a merge request
+func (r mergeRequest) isHeader() bool {
+	return r.GroupSize > 0
+}
+
+// groupMode is what the table rows are grouped by, the empty mode shows them ungrouped
+type groupMode string
+
+const (
+	noGrouping   groupMode = ""
+	byProject    groupMode = "project"
+	byDependency groupMode = "dependency"
+)
+
+// otherDependencies is the group of merge requests whose title doesn't name a dependency
+const otherDependencies = "other"
+
+// next cycles through no grouping, grouping by project and grouping by dependency
+func (g groupMode) next() groupMode {
+	switch g {
+	case noGrouping:
+		return byProject
+	case byProject:
+		return byDependency
+	}
+	return noGrouping
+}
+
+// groupKey is the project or the dependency and version of the merge request, depending on the mode
+func (g groupMode) groupKey(r mergeRequest) string {
+	if g == byProject {
+		return r.Project
+	}
+	if r.Dependency == "" {
+		return otherDependencies
+	}
+	return r.Dependency
+}
+
+// group orders the merge requests by project or dependency and puts a header row in front of every group,
+// the merge requests of collapsed groups are left out
+func (m model) group(mrs []mergeRequest) []mergeRequest {
+	if m.groupBy == noGrouping {
+		return mrs
+	}
+	byKey := make(map[string][]mergeRequest)
+	var headers []mergeRequest
+	for _, r := range mrs {
+		key := m.groupBy.groupKey(r)
+		if _, ok := byKey[key]; !ok {
+			headers = append(headers, mergeRequest{ProjectID: r.ProjectID, Project: key})
+		}
+		byKey[key] = append(byKey[key], r)
+	}
+	slices.SortFunc(headers, func(a, b mergeRequest) int {
+		switch {
+		case m.groupBy != byDependency, a.Project == b.Project:
+		case a.Project == otherDependencies:
+			return 1
+		case b.Project == otherDependencies:
+			return -1
+		}
+		return cmp.Compare(a.Project, b.Project)
+	})
+	grouped := make([]mergeRequest, 0, len(mrs)+len(headers))
+	for i, h := range headers {
+		// header ids are negative to not collide with merge requests
+		h.Id = -i - 1
+		if m.groupBy == byProject {
+			h.Id = -h.ProjectID
+		}
+		members := byKey[h.Project]
+		h.GroupSize = len(members)
+		h.Collapsed = m.collapsed[h.Project]
+		grouped = append(grouped, h)
+		if !h.Collapsed {
+			grouped = append(grouped, members...)
+		}
+	}
+	return grouped
+}
+
+// dependency is the group key of a renovate merge request updating a dependency, e.g. "golang.org/x/net → v0.36.0"
+func dependency(title string) string {
+	name, version, ok := ggl.Dependency(title)
+	if !ok {
+		return ""
+	}
+	if version == "" {
+		return name
+	}
+	return name + sym.arrow + version
+}
+
+// groupMembers are the visible merge requests in the dependency group of the header, also if it is collapsed
+func (m model) groupMembers(header mergeRequest) []mergeRequest {
+	var members []mergeRequest
+	for _, r := range m.visibleMergeRequests() {
+		if m.groupBy.groupKey(r) == header.Project {
+			members = append(members, r)
+		}
+	}
+	return members
+}
+
+// headerRow renders a group header into the first two columns
+func (m model) headerRow(r mergeRequest) []string {
+	row := make([]string, len(m.columns))
+	marker := sym.expanded
+	if r.Collapsed {
+		marker = sym.collapsed
+	}
+	if len(row) > 0 {
+		row[0] = marker + " " + r.Project
+	}
+	if len(row) > 1 {
+		row[1] = fmt.Sprintf("%d merge requests", r.GroupSize)
+	}
+	return fitASCII(row, m.fittedColumns)
+}
+
+// toggleCollapsed collapses or expands the group of the header under the cursor
+func (m *model) toggleCollapsed() bool {
+	r, ok := m.cursorRow()
+	if !ok || !r.isHeader() {
+		return false
+	}
+	if m.collapsed == nil {
+		m.collapsed = make(map[string]bool)
+	}
+	m.collapsed[r.Project] = !m.collapsed[r.Project]
+	return true
+}
+
+// collapsedProjects lists the collapsed groups for the view state
+func (m model) collapsedProjects() []string {
+	var projects []string
+	for p, collapsed := range m.collapsed {
+		if collapsed {
+			projects = append(projects, p)
+		}
+	}
+	slices.Sort(projects)
+	return projects
+}
@@ -0,0 +1,188 @@
+package glui
+
+import (
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/pebble"
+	"github.com/dustin/go-humanize"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/xanzy/go-gitlab"
+	"log"
+	"strings"
+	"time"
+)
+
+// historyLines is the number of the latest transitions shown in the details
+const historyLines = 15
+
+// targetDetails shows the merge target of a merge request with the history of its state transitions, the
+// description and the comments
+type targetDetails struct {
+	title       string
+	target      *ggl.TargetStatus
+	description string
+	notes       []*gitlab.Note
+	notesErr    error
+	view        viewport.Model
+	// id is the merge request, approvers are who can unblock it while it waits for approvals
+	id           int
+	approvers    []ggl.ApproverRule
+	approversErr error
+	// conflicts are the files changed on both sides of a conflicting merge request
+	conflicts    *ggl.Conflicts
+	conflictsErr error
+}
+
+func (m model) loadTargetDetails(id int, title string) tea.Cmd {
+	return func() tea.Msg {
+		d := &targetDetails{title: title, id: id}
+		target, err := m.mrm.Target(id)
+		if err != nil && !errors.Is(err, pebble.ErrNotFound) {
+			log.Println("Error loading merge target", err)
+			return err
+		}
+		if err == nil {
+			d.target = &target
+		}
+		if mr, err := m.mrm.GetMergeRequest(id); err == nil {
+			d.description = mr.Description
+			if mr.DetailedMergeStatus == "not_approved" {
+				d.approvers, d.approversErr = m.mrm.EligibleApprovers(m.ctx, id)
+			}
+			if mr.DetailedMergeStatus == "conflict" || mr.HasConflicts {
+				conflicts, err := m.mrm.ConflictDetails(m.ctx, id)
+				d.conflicts, d.conflictsErr = &conflicts, err
+			}
+		}
+		d.notes, d.notesErr = m.mrm.MergeRequestNotes(m.ctx, id)
+		return d
+	}
+}
+
+// showDetails renders the details into a viewport of the size of the tabs
+func (m model) showDetails(d *targetDetails) *targetDetails {
+	d.view = viewport.New(m.tabView.Width, m.tabView.Height)
+	d.view.SetContent(m.detailsContent(d))
+	return d
+}
+
+// updateDetails scrolls the details, q, esc and h close them and p pings the eligible approvers
+func (m model) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "h":
+		m.details = nil
+		return m, nil
+	case "p":
+		if len(m.details.approvers) == 0 {
+			return m, nil
+		}
+		if m.readOnly != "" {
+			m.notice = m.readOnly + ", commenting on merge requests is disabled"
+			return m, nil
+		}
+		return m, m.pingApprovers(m.details.id, m.details.approvers)
+	}
+	var cmd tea.Cmd
+	m.details.view, cmd = m.details.view.Update(msg)
+	return m, cmd
+}
+
+func (m model) detailsContent(d *targetDetails) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", d.title)
+	now := time.Now()
+	if d.target == nil {
+		b.WriteString("auto-merge was never enabled for this merge request\n")
+	} else {
+		t := d.target
+		fmt.Fprintf(&b, "Info:         %s\n", t.Info)
+		if t.Active {
+			fmt.Fprintf(&b, "Next attempt: %s\n", humanize.RelTime(t.NextAttempt, now, "ago", "from now"))
+		}
+		b.WriteString("\n")
+		history := t.History[max(0, len(t.History)-historyLines):]
+		for i := len(history) - 1; i >= 0; i-- {
+			e := history[i]
+			fmt.Fprintf(&b, "%s  %-12s %s\n", e.Time.Format(time.DateTime), e.State, e.Info)
+		}
+	}
+	width := max(20, d.view.Width-2)
+	if d.approvers != nil || d.approversErr != nil {
+		b.WriteString("\n" + sectionStyle.Render("Who can unblock this") + "\n")
+	}
+	if d.approversErr != nil {
+		fmt.Fprintf(&b, "error loading the approval rules: %v\n", d.approversErr)
+	}
+	for _, r := range d.approvers {
+		left := ""
+		if r.Left > 0 {
+			left = fmt.Sprintf(" (%d left)", r.Left)
+		}
+		approvers := "nobody eligible"
+		if len(r.Approvers) > 0 {
+			approvers = "@" + strings.Join(r.Approvers, " @")
+		}
+		fmt.Fprintf(&b, "%s%s: %s\n", r.Name, left, approvers)
+	}
+	if d.conflictsErr != nil {
+		b.WriteString("\n" + sectionStyle.Render("Conflicts") + "\n")
+		fmt.Fprintf(&b, "error comparing with the target branch: %v\n", d.conflictsErr)
+	} else if d.conflicts != nil {
+		b.WriteString("\n" + sectionStyle.Render(fmt.Sprintf("Conflicts (%d)", len(d.conflicts.Files))) + "\n")
+		fmt.Fprintf(&b, "the target branch has %d commits since the merge request branched off, changed on both sides:\n", d.conflicts.TargetCommits)
+		for _, file := range d.conflicts.Files {
+			b.WriteString("  " + file + "\n")
+		}
+		switch {
+		case len(d.conflicts.Files) == 0:
+			b.WriteString("no file changed on both sides, a rebase probably resolves the conflict\n")
+		case d.conflicts.Generated:
+			b.WriteString("only generated files, a rebase regenerating them (e.g. by renovate) probably resolves the conflict\n")
+		default:
+			b.WriteString("a human probably needs to resolve the conflict\n")
+		}
+	}
+	b.WriteString("\n" + sectionStyle.Render("Description") + "\n")
+	if strings.TrimSpace(d.description) == "" {
+		b.WriteString("no description\n")
+	} else {
+		b.WriteString(renderMarkdown(d.description, m.markdownStyle, width) + "\n")
+	}
+	b.WriteString("\n" + sectionStyle.Render(fmt.Sprintf("Comments (%d)", len(d.notes))) + "\n")
+	if d.notesErr != nil {
+		fmt.Fprintf(&b, "error loading the comments: %v\n", d.notesErr)
+	}
+	for _, n := range d.notes {
+		when := ""
+		if n.CreatedAt != nil {
+			when = humanize.RelTime(*n.CreatedAt, now, "ago", "from now")
+		}
+		fmt.Fprintf(&b, "\n%s\n", statusStyle.Render(n.Author.Username+" "+when))
+		b.WriteString(renderMarkdown(n.Body, m.markdownStyle, width) + "\n")
+	}
+	return b.String()
+}
+
+func (m model) detailsView() string {
+	keys := "[q] close  [up/down] scroll"
+	if len(m.details.approvers) > 0 {
+		keys += "  [p] ping approvers"
+	}
+	return m.details.view.View() + "\n" + statusStyle.Render(keys)
+}
+
+// approversPinged confirms the comment mentioning the eligible approvers
+type approversPinged struct{}
+
+func (m model) pingApprovers(id int, rules []ggl.ApproverRule) tea.Cmd {
+	return func() tea.Msg {
+		err := m.mrm.PingApprovers(m.ctx, id, rules)
+		if err != nil {
+			log.Println("Error pinging approvers", err)
+			return err
+		}
+		return approversPinged{}
+	}
+}
@@ -0,0 +1,109 @@
+package glui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"slices"
+	"strings"
+)
+
+var defaultChipStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFFFF")).
+	Background(lipgloss.Color("240")).
+	Padding(0, 1)
+
+// chip renders a label with its gitlab colors
+func chip(l ggl.Label) string {
+	s := defaultChipStyle
+	if l.Color != "" {
+		s = s.Background(lipgloss.Color(l.Color))
+	}
+	if l.TextColor != "" {
+		s = s.Foreground(lipgloss.Color(l.TextColor))
+	}
+	return s.Render(l.Name)
+}
+
+// labelNames joins the label names for the table, cells can't hold colors as the table truncates by bytes
+func labelNames(labels []ggl.Label) string {
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// availableLabels returns the distinct labels of the merge requests sorted by name
+func (m model) availableLabels() []ggl.Label {
+	var labels []ggl.Label
+	for _, r := range m.mergeRequests {
+		for _, l := range r.Labels {
+			if !slices.ContainsFunc(labels, func(o ggl.Label) bool { return o.Name == l.Name }) {
+				labels = append(labels, l)
+			}
+		}
+	}
+	slices.SortFunc(labels, func(a, b ggl.Label) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return labels
+}
+
+// labelBar renders the labels as chips, the one filtered by is marked
+func (m model) labelBar() string {
+	labels := m.availableLabels()
+	if len(labels) == 0 {
+		return statusStyle.Render(" no labels")
+	}
+	chips := make([]string, len(labels))
+	for i, l := range labels {
+		chips[i] = m.labelChip(l)
+	}
+	return " " + strings.Join(chips, " ")
+}
+
+func (m model) labelChip(l ggl.Label) string {
+	if l.Name == m.labelFilter {
+		return chip(l) + sym.filtered
+	}
+	return chip(l)
+}
+
+// labelAt returns the name of the label chip at column x of the label bar
+func (m model) labelAt(x int) (string, bool) {
+	offset := 1
+	for _, l := range m.availableLabels() {
+		width := lipgloss.Width(m.labelChip(l))
+		if x >= offset && x < offset+width {
+			return l.Name, true
+		}
+		offset += width + 1
+	}
+	return "", false
+}
+
+// nextLabelFilter cycles the filter through the available labels and back to no filter
+func (m model) nextLabelFilter() string {
+	labels := m.availableLabels()
+	i := slices.IndexFunc(labels, func(l ggl.Label) bool { return l.Name == m.labelFilter })
+	if i+1 >= len(labels) {
+		return ""
+	}
+	return labels[i+1].Name
+}
+
+// toggleLabelFilter filters by the label or removes the filter if it is already set
+func (m model) toggleLabelFilter(name string) string {
+	if m.labelFilter == name {
+		return ""
+	}
+	return name
+}
+
+// validLabelFilter drops a filter on a label that none of the merge requests has anymore
+func (m model) validLabelFilter() string {
+	if slices.ContainsFunc(m.availableLabels(), func(l ggl.Label) bool { return l.Name == m.labelFilter }) {
+		return m.labelFilter
+	}
+	return ""
+}
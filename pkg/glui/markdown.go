@@ -0,0 +1,41 @@
+package glui
+
+import (
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"log"
+	"strings"
+)
+
+// markdownStyle picks the glamour style for the terminal background, it is determined once before the ui starts as
+// querying the terminal while the ui runs garbles the input. Without colors or in ascii mode the plain ascii style
+// is used.
+func markdownStyle() string {
+	if noColor || ascii {
+		return "ascii"
+	}
+	if lipgloss.HasDarkBackground() {
+		return "dark"
+	}
+	return "light"
+}
+
+// renderMarkdown formats gitlab markdown for the terminal: task lists like renovate's rebase checkbox become [ ] and
+// [x], html like renovate's comments and details tags is dropped. The raw text is returned if it can't be rendered.
+func renderMarkdown(md string, style string, width int) string {
+	options := []glamour.TermRendererOption{glamour.WithStandardStyle(style), glamour.WithWordWrap(width)}
+	if !ascii {
+		options = append(options, glamour.WithEmoji())
+	}
+	r, err := glamour.NewTermRenderer(options...)
+	if err != nil {
+		log.Println("Error creating markdown renderer", err)
+		return md
+	}
+	out, err := r.Render(md)
+	if err != nil {
+		log.Println("Error rendering markdown", err)
+		return md
+	}
+	return strings.Trim(out, "\n")
+}
@@ -0,0 +1,104 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// doubleClickInterval is the longest time between two clicks on the same row that opens it
+const doubleClickInterval = 400 * time.Millisecond
+
+// wheelRows is the number of rows a turn of the mouse wheel scrolls the table
+const wheelRows = 3
+
+// updateTableMouse selects the clicked row, opens it on a double click like enter, scrolls the table with the wheel
+// and sorts by the clicked header cell
+func (m model) updateTableMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.table.MoveUp(wheelRows)
+		return m, nil
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.table.MoveDown(wheelRows)
+		return m, nil
+	case msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress:
+		return m, nil
+	}
+	// the label bar and the top border of the table are above the header
+	headerLines := lipgloss.Height(m.table.View()) - m.table.Height()
+	line := msg.Y - 2
+	if line == 0 {
+		if title, ok := m.columnAt(msg.X); ok {
+			m.toggleSort(title)
+			m.setColumns(m.table.Width())
+			m.updateRows(time.Now())
+			return m, m.saveViewState()
+		}
+		return m, nil
+	}
+	row, ok := m.rowAt(line - headerLines)
+	if !ok {
+		return m, nil
+	}
+	now := time.Now()
+	double := row == m.table.Cursor() && now.Sub(m.lastClick) < doubleClickInterval
+	// move like the arrow keys do, setting the cursor would scroll the clicked row away
+	if row < m.table.Cursor() {
+		m.table.MoveUp(m.table.Cursor() - row)
+	} else {
+		m.table.MoveDown(row - m.table.Cursor())
+	}
+	m.lastClick = now
+	if double {
+		m.lastClick = time.Time{}
+		return m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	return m, nil
+}
+
+// columnAt returns the title of the column at x, the left border is followed by the cells padded by one space on
+// both sides
+func (m model) columnAt(x int) (string, bool) {
+	left := 1
+	for i, c := range m.fittedColumns {
+		right := left + c.Width + 2
+		if x >= left && x < right {
+			return m.columns[i].title, true
+		}
+		left = right
+	}
+	return "", false
+}
+
+// rowAt returns the index of the row shown on the line of the table body. The table doesn't tell which rows are
+// scrolled into view, so a copy of it is rendered with the row indices as cells and the index on the line is read.
+func (m model) rowAt(line int) (int, bool) {
+	if line < 0 || line >= m.table.Height() || len(m.fittedColumns) == 0 {
+		return 0, false
+	}
+	widest := 0
+	for i, c := range m.fittedColumns {
+		if c.Width > m.fittedColumns[widest].Width {
+			widest = i
+		}
+	}
+	probe := m.table
+	rows := make([]table.Row, len(m.table.Rows()))
+	for i := range rows {
+		rows[i] = make(table.Row, len(m.fittedColumns))
+		rows[i][widest] = strconv.Itoa(i)
+	}
+	probe.SetRows(rows)
+	lines := strings.Split(probe.View(), "\n")
+	headerLines := len(lines) - m.table.Height()
+	if headerLines+line >= len(lines) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(ansi.Strip(lines[headerLines+line])))
+	return i, err == nil
+}
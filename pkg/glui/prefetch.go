@@ -0,0 +1,44 @@
+package glui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"log"
+	"time"
+)
+
+// prefetchDelay spaces the prefetch requests so that they do not crowd out the merge processing
+const prefetchDelay = 250 * time.Millisecond
+
+type prefetchDone struct{}
+
+// prefetchDiffs pulls the diffs of the merge requests around the cursor into the cache in the background, so that
+// opening them is instant. It stops early when less than a tenth of the rate limit is left.
+func (m model) prefetchDiffs() tea.Cmd {
+	var ids []int
+	cursor, height := m.table.Cursor(), m.table.Height()
+	for i := max(0, cursor-height); i < min(len(m.visible), cursor+height); i++ {
+		if r := m.visible[i]; !r.isHeader() && !m.mrm.IsDiffCached(r.Id) {
+			ids = append(ids, r.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		for _, id := range ids {
+			if limit, remaining := m.mrm.APIStats().RateLimit(); remaining >= 0 && remaining < limit/10 {
+				log.Println("Stopping diff prefetch, rate limit almost used up", remaining, limit)
+				break
+			}
+			if _, err := m.mrm.PullDiff(m.ctx, id); err != nil {
+				log.Println("Error prefetching diff", id, err)
+			}
+			select {
+			case <-time.After(prefetchDelay):
+			case <-m.ctx.Done():
+				return prefetchDone{}
+			}
+		}
+		return prefetchDone{}
+	}
+}
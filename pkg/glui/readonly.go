@@ -0,0 +1,54 @@
+package glui
+
+import (
+	"context"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"log"
+	"slices"
+	"time"
+)
+
+// mutatingKeys are the keys of the actions that change merge requests or merge targets, per view
+var (
+	mutatingTableKeys = map[string]bool{"c": true, "R": true, "x": true, "t": true, "b": true, "B": true, "A": true, "m": true, "i": true, "p": true, "e": true}
+	mutatingDiffKeys  = map[string]bool{"m": true, "a": true, "M": true}
+)
+
+// blockedByReadOnly reports whether key triggers an action that is disabled because the view is read-only
+func (m model) blockedByReadOnly(key string) bool {
+	if m.readOnly == "" {
+		return false
+	}
+	if m.diff != nil {
+		return mutatingDiffKeys[key]
+	}
+	return mutatingTableKeys[key]
+}
+
+// blockedByExclusion reports whether key triggers an action on a merge request of an excluded author, actions on
+// all merge requests (e.g. enabling all green ones) skip them on their own
+func (m model) blockedByExclusion(key string) bool {
+	id := m.diffId
+	if m.diff == nil {
+		r, ok := m.selected()
+		if !ok || !mutatingTableKeys[key] || key == "A" {
+			return false
+		}
+		id = r.Id
+	} else if !mutatingDiffKeys[key] {
+		return false
+	}
+	i := slices.IndexFunc(m.mergeRequests, func(r mergeRequest) bool { return r.Id == id })
+	return i >= 0 && m.mergeRequests[i].Excluded
+}
+
+// tokenAccess probes the scopes of the token, a failed probe is logged and treated as a token that can write
+func tokenAccess(ctx context.Context, mrm *ggl.MergeRequestManager) ggl.TokenAccess {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	access, err := mrm.TokenAccess(ctx)
+	if err != nil {
+		log.Println("Error reading token scopes", err)
+	}
+	return access
+}
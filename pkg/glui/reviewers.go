@@ -0,0 +1,107 @@
+package glui
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"log"
+	"strings"
+)
+
+// reviewerPicker selects the reviewers of a merge request from the project members
+type reviewerPicker struct {
+	mrId     int
+	title    string
+	members  []ggl.Member
+	selected map[int]bool
+	cursor   int
+}
+
+type reviewersUpdated struct{}
+
+// loadReviewerPicker opens the picker with the current reviewers of the merge request selected
+func (m model) loadReviewerPicker(id int, title string) tea.Cmd {
+	return func() tea.Msg {
+		mr, err := m.mrm.GetMergeRequest(id)
+		if err != nil {
+			log.Println("Error loading merge request", err)
+			return err
+		}
+		members, err := m.mrm.ProjectMembers(m.ctx, mr.ProjectID)
+		if err != nil {
+			log.Println("Error fetching project members", err)
+			return err
+		}
+		p := &reviewerPicker{mrId: id, title: title, members: members, selected: make(map[int]bool)}
+		for _, r := range mr.Reviewers {
+			p.selected[r.ID] = true
+		}
+		return p
+	}
+}
+
+// updatePicker handles the keys while the picker is open
+func (m model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+	switch msg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.members)-1 {
+			p.cursor++
+		}
+	case " ", "x":
+		if len(p.members) > 0 {
+			id := p.members[p.cursor].ID
+			p.selected[id] = !p.selected[id]
+		}
+	case "enter":
+		m.picker = nil
+		m.loading = "Updating reviewers of " + p.title
+		return m, m.setReviewers(p)
+	case "esc", "q":
+		m.picker = nil
+	}
+	return m, nil
+}
+
+func (m model) setReviewers(p *reviewerPicker) tea.Cmd {
+	return func() tea.Msg {
+		ids := []int{}
+		for _, member := range p.members {
+			if p.selected[member.ID] {
+				ids = append(ids, member.ID)
+			}
+		}
+		err := m.mrm.SetReviewers(m.ctx, p.mrId, ids)
+		if err != nil {
+			log.Println("Error updating reviewers", err)
+			return err
+		}
+		return reviewersUpdated{}
+	}
+}
+
+func (m model) pickerView() string {
+	p := m.picker
+	var b strings.Builder
+	fmt.Fprintf(&b, "Reviewers of %s\n\n", p.title)
+	if len(p.members) == 0 {
+		b.WriteString("no project members found\n")
+	}
+	for i, member := range p.members {
+		cursor, check := " ", " "
+		if i == p.cursor {
+			cursor = ">"
+		}
+		if p.selected[member.ID] {
+			check = "x"
+		}
+		fmt.Fprintf(&b, "%s [%s] %s (%s)\n", cursor, check, member.Username, member.Name)
+	}
+	b.WriteString("\n[space] toggle  [enter] save  [esc] cancel")
+	return lipgloss.Place(m.table.Width(), m.table.Height(), lipgloss.Center, lipgloss.Center, confirmStyle.Render(b.String()))
+}
@@ -0,0 +1,84 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/table"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// staleAfter is the age after which a row is marked as stale, the merge requests are fetched every minute while the
+// ui is open, so older data means that the fetches fail
+const staleAfter = 5 * time.Minute
+
+// renderedRow is a table row together with the merge request it was rendered from
+type renderedRow struct {
+	source mergeRequest
+	row    table.Row
+}
+
+// updateRows replaces the table rows after the merge requests or the filter changed. Only merge requests that
+// changed since they were rendered last are rendered again and the cursor stays on the selected merge request.
+func (m *model) updateRows(now time.Time) {
+	selected, hasSelection := m.cursorRow()
+	m.visible = m.group(m.visibleMergeRequests())
+	rendered := make(map[int]renderedRow, len(m.visible))
+	rows := make([]table.Row, len(m.visible))
+	for i, r := range m.visible {
+		if prev, ok := m.rendered[r.Id]; ok && reflect.DeepEqual(prev.source, r) {
+			rendered[r.Id] = prev
+		} else {
+			rendered[r.Id] = renderedRow{source: r, row: m.row(r, now)}
+		}
+		rows[i] = rendered[r.Id].row
+	}
+	m.rendered = rendered
+	m.rowsStale = false
+	m.table.SetRows(rows)
+
+	cursor := 0
+	if hasSelection {
+		cursor = max(0, slices.IndexFunc(m.visible, func(r mergeRequest) bool { return r.Id == selected.Id }))
+	}
+	m.table.SetCursor(cursor)
+}
+
+// refreshWindow renders the rows around the cursor again to update their relative times, rows outside of the
+// window are not displayed by the table and are refreshed once the merge requests change
+func (m *model) refreshWindow(now time.Time) {
+	rows := m.table.Rows()
+	if len(rows) != len(m.visible) {
+		return
+	}
+	cursor, height := m.table.Cursor(), m.table.Height()
+	for i := max(0, cursor-height); i < min(len(rows), cursor+height); i++ {
+		r := m.visible[i]
+		rows[i] = m.row(r, now)
+		m.rendered[r.Id] = renderedRow{source: r, row: rows[i]}
+	}
+	m.table.SetRows(rows)
+}
+
+// row renders the cells of the configured columns, relative times are computed against now
+func (m model) row(r mergeRequest, now time.Time) table.Row {
+	if r.isHeader() {
+		return m.headerRow(r)
+	}
+	row := make(table.Row, len(m.columns))
+	for j, c := range m.columns {
+		row[j] = c.cell(r, now)
+	}
+	if r.Excluded && len(row) > 0 {
+		row[0] = sym.excluded + row[0]
+	}
+	if !r.FetchedAt.IsZero() && now.Sub(r.FetchedAt) > staleAfter && len(row) > 0 {
+		row[0] = sym.stale + row[0]
+	}
+	return fitASCII(row, m.fittedColumns)
+}
+
+// modalOpen reports whether the diff, a confirmation, the reviewer picker or the target details are shown on top of
+// the table
+func (m model) modalOpen() bool {
+	return m.diff != nil || m.confirm != noAction || m.picker != nil || m.details != nil || m.passwordPrompt != nil
+}
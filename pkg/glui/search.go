@@ -0,0 +1,52 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"strconv"
+	"time"
+)
+
+func newSearchInput(query string) *textinput.Model {
+	input := textinput.New()
+	input.Prompt = "/"
+	input.Placeholder = "search title, description, branch or project"
+	input.SetValue(query)
+	input.Focus()
+	return &input
+}
+
+// updateSearch handles the keys while the search is edited, the table is filtered while typing
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.searchInput = nil
+		return m, nil
+	case "esc":
+		m.searchInput = nil
+		m.search = ""
+		m.updateRows(time.Now())
+		return m, nil
+	}
+	input, cmd := m.searchInput.Update(msg)
+	m.searchInput = &input
+	if input.Value() != m.search {
+		m.search = input.Value()
+		m.updateRows(time.Now())
+	}
+	return m, cmd
+}
+
+// matchesSearch reports whether the merge request contains all terms of the search
+func (m model) matchesSearch(r mergeRequest) bool {
+	return ggl.MatchesQuery(m.search, r.Title, r.Description, r.SourceBranch, r.Project, r.HumanId)
+}
+
+// searchInfo shows the active search in the header
+func (m model) searchInfo() string {
+	if m.search == "" {
+		return ""
+	}
+	return statusStyle.Render("  search " + strconv.Quote(m.search) + " (/ to change, esc to clear)")
+}
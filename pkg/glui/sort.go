@@ -0,0 +1,105 @@
+package glui
+
+import (
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/x/ansi"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sortOrder is a column and direction the table can be sorted by
+type sortOrder struct {
+	column string
+	desc   bool
+}
+
+// sortCycle are the orders stepped through with s, it starts with the default order
+var sortCycle = []sortOrder{
+	{"Updated", true}, {"Updated", false},
+	{"#", false}, {"#", true},
+	{"State", false}, {"State", true},
+	{"Next Try", false}, {"Next Try", true},
+}
+
+// compare orders two merge requests by the column, by their times for the time columns and by the cell text
+// otherwise
+func (c column) compare(a, b mergeRequest, now time.Time) int {
+	if c.order != nil {
+		return c.order(a, b)
+	}
+	return strings.Compare(c.cell(a, now), c.cell(b, now))
+}
+
+// sortMergeRequests orders the merge requests by the sort column
+func (m model) sortMergeRequests(mrs []mergeRequest) []mergeRequest {
+	c, ok := columnByTitle(m.sortColumn)
+	if !ok {
+		return mrs
+	}
+	now := time.Now()
+	sorted := slices.Clone(mrs)
+	slices.SortStableFunc(sorted, func(a, b mergeRequest) int {
+		if m.sortDesc {
+			return c.compare(b, a, now)
+		}
+		return c.compare(a, b, now)
+	})
+	return sorted
+}
+
+// toggleSort sorts by the column, ascending first and descending if it is sorted by it already
+func (m *model) toggleSort(title string) {
+	if strings.EqualFold(m.sortColumn, title) {
+		m.sortDesc = !m.sortDesc
+		return
+	}
+	m.sortColumn, m.sortDesc = title, false
+}
+
+// cycleSort switches to the next order of the sortCycle, a column sorted by a click on its header continues with
+// the first one
+func (m *model) cycleSort() {
+	i := slices.IndexFunc(sortCycle, func(o sortOrder) bool { return strings.EqualFold(o.column, m.sortColumn) && o.desc == m.sortDesc })
+	next := sortCycle[(i+1)%len(sortCycle)]
+	m.sortColumn, m.sortDesc = next.column, next.desc
+}
+
+// sortOrder restores the sort order, the first of the sortCycle if none was picked
+func (s viewState) sortOrder() sortOrder {
+	if s.SortColumn == "" {
+		return sortCycle[0]
+	}
+	return sortOrder{s.SortColumn, s.SortDesc}
+}
+
+// markSortColumn shows the direction next to the title of the sort column
+func (m model) markSortColumn(columns []table.Column) []table.Column {
+	for i, c := range columns {
+		if !strings.EqualFold(c.Title, m.sortColumn) {
+			continue
+		}
+		if m.sortDesc {
+			columns[i].Title += sym.descending
+		} else {
+			columns[i].Title += sym.ascending
+		}
+	}
+	return columns
+}
+
+// setColumns fits the columns to the width of the table and marks the sort column
+func (m *model) setColumns(width int) {
+	m.fittedColumns = m.markSortColumn(fitColumns(m.columns, width))
+	if !ascii {
+		m.table.SetColumns(m.fittedColumns)
+		return
+	}
+	for i, c := range m.fittedColumns {
+		m.fittedColumns[i].Title = ansi.Truncate(c.Title, c.Width, sym.ellipsis)
+	}
+	m.table.SetColumns(m.fittedColumns)
+	// the rows are truncated to the widths of the columns
+	m.rendered = nil
+	m.updateRows(time.Now())
+}
@@ -0,0 +1,226 @@
+package glui
+
+import (
+	"cmp"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// tab is one of the views of the auto merge ui, they share the MergeRequestManager
+type tab int
+
+const (
+	mergeRequestsTab tab = iota
+	targetsTab
+	pipelinesTab
+	activityTab
+)
+
+var tabNames = []string{"Merge Requests", "Targets", "Pipelines", "Activity"}
+
+// activityLines is the number of the latest events kept for the activity tab
+const activityLines = 200
+
+// targetHistoryLines is the number of the latest transitions shown per target in the targets tab
+const targetHistoryLines = 3
+
+var (
+	activeTabStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57")).Padding(0, 1)
+	inactiveTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Padding(0, 1)
+)
+
+// loadedTargets are the merge targets shown in the targets tab
+type loadedTargets []ggl.TargetStatus
+
+func (m model) loadTargets() tea.Msg {
+	targets, err := m.mrm.Targets()
+	if err != nil {
+		log.Println("Error loading merge targets", err)
+		return err
+	}
+	return loadedTargets(targets)
+}
+
+// switchTab handles the keys selecting a tab: tab and shift+tab cycle, alt+1 to alt+4 select one directly as the
+// number keys filter the merge requests
+func (m model) switchTab(key string) (tea.Model, tea.Cmd, bool) {
+	next := m.tab
+	switch key {
+	case "tab":
+		next = (m.tab + 1) % tab(len(tabNames))
+	case "shift+tab":
+		next = (m.tab + tab(len(tabNames)) - 1) % tab(len(tabNames))
+	case "alt+1", "alt+2", "alt+3", "alt+4":
+		next = tab(key[len(key)-1] - '1')
+	default:
+		return m, nil, false
+	}
+	m.tab = next
+	m.tabView.GotoTop()
+	if m.tab == targetsTab {
+		return m, m.loadTargets, true
+	}
+	return m, nil, true
+}
+
+// updateTab scrolls the targets, pipelines and activity tabs, they don't have actions
+func (m model) updateTab(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "q" {
+		return m, tea.Quit
+	}
+	m.tabView.SetContent(m.tabContent())
+	var cmd tea.Cmd
+	m.tabView, cmd = m.tabView.Update(msg)
+	return m, cmd
+}
+
+// recordActivity keeps the event for the activity tab, the newest first
+func (m *model) recordActivity(e ggl.Event) {
+	if e.Type == ggl.EventProgress {
+		return
+	}
+	m.activity = append([]ggl.Event{e}, m.activity[:min(len(m.activity), activityLines-1)]...)
+}
+
+func (m model) tabBar() string {
+	tabs := make([]string, len(tabNames))
+	for i, name := range tabNames {
+		label := fmt.Sprintf("%d %s", i+1, name)
+		if tab(i) == m.tab && noColor {
+			label = "[" + label + "]"
+		}
+		if tab(i) == m.tab {
+			tabs[i] = activeTabStyle.Render(label)
+		} else {
+			tabs[i] = inactiveTabStyle.Render(label)
+		}
+	}
+	return strings.Join(tabs, " ") + statusStyle.Render("  tab/alt+1-4 switch")
+}
+
+// tabViewContent renders the selected tab other than the merge requests
+func (m model) tabViewContent() string {
+	v := m.tabView
+	v.SetContent(m.tabContent())
+	return v.View()
+}
+
+func (m model) tabContent() string {
+	switch m.tab {
+	case targetsTab:
+		return m.targetsContent(time.Now())
+	case pipelinesTab:
+		return m.pipelinesContent()
+	case activityTab:
+		return m.activityContent()
+	}
+	return ""
+}
+
+// targetsContent lists the quarantined targets and the active ones with their latest transitions, ordered by the
+// next attempt
+func (m model) targetsContent(now time.Time) string {
+	var b strings.Builder
+	inactive := 0
+	for _, t := range m.targets {
+		if t.Outcome.State != ggl.OutcomeQuarantined {
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s  %s\n", sym.warning, t.Reference, t.Title)
+		fmt.Fprintf(&b, "    %s | since %s\n", t.Info, humanize.RelTime(t.LastAttempt, now, "ago", "from now"))
+	}
+	if b.Len() > 0 {
+		b.WriteString("    see the stack traces with [h] on the merge request or targets show\n\n")
+	}
+	for _, t := range m.targets {
+		if !t.Active {
+			if t.Outcome.State != ggl.OutcomeQuarantined {
+				inactive++
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%s  %s\n", t.Reference, t.Title)
+		fmt.Fprintf(&b, "    %s | next attempt %s\n", t.Info, humanize.RelTime(t.NextAttempt, now, "ago", "from now"))
+		history := t.History[max(0, len(t.History)-targetHistoryLines):]
+		for i := len(history) - 1; i >= 0; i-- {
+			e := history[i]
+			fmt.Fprintf(&b, "    %s  %-12s %s\n", e.Time.Format(time.DateTime), e.State, e.Info)
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		b.WriteString("no active merge targets\n")
+	}
+	fmt.Fprintf(&b, "%d inactive targets, see them with [h] on the merge request or the targets command\n", inactive)
+	fmt.Fprintf(&b, "%d targets waiting in the process queue\n", m.mrm.QueueDepth())
+	return b.String()
+}
+
+// pipelineOrder puts failed pipelines first, then running ones
+var pipelineOrder = []string{"failed", "canceled", "running", "pending", "created", "manual", "success", "skipped"}
+
+// pipelinesContent lists the head pipeline of every merge request as far as it is known from processing
+func (m model) pipelinesContent() string {
+	rank := func(r mergeRequest) int {
+		if i := slices.Index(pipelineOrder, r.Pipeline); i >= 0 {
+			return i
+		}
+		return len(pipelineOrder)
+	}
+	mrs := slices.Clone(m.mergeRequests)
+	slices.SortStableFunc(mrs, func(a, b mergeRequest) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+	var b strings.Builder
+	for _, r := range mrs {
+		pipeline := r.Pipeline
+		if pipeline == "" {
+			pipeline = "unknown"
+		}
+		fmt.Fprintf(&b, "%-10s %-28s %-20s %s\n", pipeline, r.HumanId, r.MergeStatus, r.Title)
+		if r.PipelineURL != "" {
+			fmt.Fprintf(&b, "           %s\n", r.PipelineURL)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("no merge requests\n")
+	}
+	return b.String()
+}
+
+// activityContent lists the latest events of the manager, the newest first
+func (m model) activityContent() string {
+	titles := make(map[int]string, len(m.mergeRequests))
+	for _, r := range m.mergeRequests {
+		titles[r.Id] = r.HumanId + " " + r.Title
+	}
+	var b strings.Builder
+	for _, e := range m.activity {
+		line := fmt.Sprintf("%s  %-12s", e.Time.Format(time.TimeOnly), e.Type)
+		if e.TargetID != 0 {
+			title, ok := titles[e.TargetID]
+			if !ok {
+				title = fmt.Sprintf("merge request %d", e.TargetID)
+			}
+			line += " " + title
+		}
+		if e.Outcome.State != "" {
+			line += " - " + e.Outcome.Info()
+		}
+		if e.Info != "" {
+			line += " " + e.Info
+		}
+		b.WriteString(line + "\n")
+	}
+	if b.Len() == 0 {
+		b.WriteString("no activity since the start\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package glui
+
+import (
+	"context"
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/muesli/termenv"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchColumns are the columns printed by Watch
+var watchColumns = []string{"#", "State", "Approvals", "Next Try", "Action Info", "Title"}
+
+// WatchOptions configure Watch
+type WatchOptions struct {
+	Interval time.Duration
+	// Color forces colors even if the output is not a terminal
+	Color bool
+}
+
+// Watch prints the merge requests of the manager as a compact table every interval until ctx is done. On a
+// terminal the table is redrawn in place, otherwise every refresh is appended with a timestamp so that the output
+// also reads well in job logs.
+func Watch(ctx context.Context, mrm *ggl.MergeRequestManager, out *os.File, opts WatchOptions) error {
+	tty := isTerminal(out)
+	r := lipgloss.NewRenderer(out)
+	if noColor {
+		r.SetColorProfile(termenv.Ascii)
+	} else if opts.Color {
+		r.SetColorProfile(termenv.ANSI)
+	}
+	m := model{ctx: ctx, mrm: mrm}
+	for {
+		requests, err := mrm.GetOrFetchMergeRequests(ctx, false)
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		now := time.Now()
+		if tty {
+			_, _ = io.WriteString(out, "\033[H\033[2J")
+		}
+		_, _ = fmt.Fprintf(out, "%s  every %s\n", now.Format(time.DateTime), opts.Interval)
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "error fetching merge requests: %v\n", err)
+		} else {
+			_, _ = io.WriteString(out, renderWatch(r, m.toMergeRequests(requests, true).requests, now))
+		}
+		if !tty {
+			_, _ = io.WriteString(out, "\n")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// renderWatch renders the merge requests as a table padded to the widest cell of each column (at most the
+// column's default width) with the state colored by whether the merge request is blocked
+func renderWatch(r *lipgloss.Renderer, mrs []mergeRequest, now time.Time) string {
+	headerStyle := r.NewStyle().Bold(true)
+	mergeableStyle := r.NewStyle().Foreground(lipgloss.Color("2"))
+	progressingStyle := r.NewStyle().Foreground(lipgloss.Color("3"))
+	blockedStyle := r.NewStyle().Foreground(lipgloss.Color("1"))
+
+	columns := make([]column, len(watchColumns))
+	widths := make([]int, len(watchColumns))
+	cells := make([][]string, len(mrs))
+	for j, title := range watchColumns {
+		columns[j], _ = columnByTitle(title)
+		widths[j] = len(title)
+	}
+	for i, mr := range mrs {
+		cells[i] = make([]string, len(columns))
+		for j, c := range columns {
+			cell := c.cell(mr, now)
+			if len([]rune(cell)) > c.width {
+				cell = string([]rune(cell)[:c.width-1]) + sym.ellipsis
+			}
+			cells[i][j] = cell
+			widths[j] = max(widths[j], len([]rune(cell)))
+		}
+	}
+
+	var b strings.Builder
+	pad := func(s string, j int) string {
+		if j == len(widths)-1 {
+			return s
+		}
+		return s + strings.Repeat(" ", widths[j]-len([]rune(s))+2)
+	}
+	for j, title := range watchColumns {
+		b.WriteString(headerStyle.Render(pad(title, j)))
+	}
+	b.WriteString("\n")
+	for i, mr := range mrs {
+		for j, cell := range cells[i] {
+			if watchColumns[j] != "State" {
+				b.WriteString(pad(cell, j))
+				continue
+			}
+			style := blockedStyle
+			switch {
+			case mr.MergeStatus == "mergeable":
+				style = mergeableStyle
+			case slices.Contains(progressingStatuses, mr.MergeStatus):
+				style = progressingStyle
+			}
+			b.WriteString(style.Render(pad(cell, j)))
+		}
+		b.WriteString("\n")
+	}
+	_, _ = fmt.Fprintf(&b, "%d merge requests\n", len(mrs))
+	return b.String()
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
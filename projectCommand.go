@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"github.com/xanzy/go-gitlab"
+	"io"
+)
+
+// projectCommand holds the project subcommands
+func projectCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "project",
+		Usage: "project commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "search",
+				Usage:     "search projects by name or path in the local cache",
+				ArgsUsage: "<term>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "remote",
+						Usage: "search on gitlab instead of the local cache",
+					},
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						var projects []gitlab.Project
+						var err error
+						if c.Bool("remote") {
+							projects, err = mrm.SearchRemoteProjects(c.Context, c.Args().First())
+						} else {
+							projects, err = mrm.SearchProjects(c.Context, c.Args().First())
+						}
+						if err != nil {
+							return err
+						}
+						return printOutput(c, projects, func(w io.Writer) {
+							for _, p := range projects {
+								_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", p.ID, p.PathWithNamespace, p.WebURL)
+							}
+						})
+					})
+				},
+			},
+		},
+	}
+}
+
+// groupCommand holds the group subcommands
+func groupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "group",
+		Usage: "group commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list the groups visible to the token",
+				Flags: []cli.Flag{
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						groups, err := mrm.ListGroups(c.Context)
+						if err != nil {
+							return err
+						}
+						return printOutput(c, groups, func(w io.Writer) {
+							for _, g := range groups {
+								_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", g.ID, g.FullPath, g.WebURL)
+							}
+						})
+					})
+				},
+			},
+		},
+	}
+}
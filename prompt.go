@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// passphrase and instance are remembered after the first prompt, so that e.g. rotating the token at the start of
+// auto-merge and opening the manager ask only once
+var (
+	enteredPassphrase []byte
+	pickedInstance    string
+)
+
+// stdinIsTerminal reports whether the user can be asked for the passphrase and instance
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptPassphrase asks for the passphrase of the encrypted token on the terminal
+func promptPassphrase() ([]byte, error) {
+	if enteredPassphrase != nil {
+		return enteredPassphrase, nil
+	}
+	_, _ = fmt.Fprint(os.Stderr, "token passphrase: ")
+	p, err := term.ReadPassword(int(os.Stdin.Fd()))
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	enteredPassphrase = p
+	return enteredPassphrase, nil
+}
+
+// pickInstance asks on the terminal which of the gitlab instances with a stored token to use
+func pickInstance(instances []string) (string, error) {
+	if pickedInstance != "" {
+		return pickedInstance, nil
+	}
+	for i, instance := range instances {
+		_, _ = fmt.Fprintf(os.Stderr, "%d) %s\n", i+1, instance)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		_, _ = fmt.Fprintf(os.Stderr, "gitlab instance [1-%d]: ", len(instances))
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && i >= 1 && i <= len(instances) {
+			pickedInstance = instances[i-1]
+			return pickedInstance, nil
+		}
+	}
+}
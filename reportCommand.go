@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"time"
+)
+
+// reportCommand summarizes what auto-merge did in a time span
+func reportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "summarize merges, aborts and time to merge of auto-merge",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "time span to summarize (e.g. 24h, 7d or 2w)",
+				Value: "7d",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "output format (table, markdown or json)",
+				Value:   "table",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			since, err := ggl.ParseSince(c.String("since"))
+			if err != nil {
+				return err
+			}
+			return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+				r, err := mrm.Report(time.Now().Add(-since))
+				if err != nil {
+					return err
+				}
+				if c.String("output") == "markdown" {
+					writeMarkdownReport(os.Stdout, r)
+					return nil
+				}
+				return printOutput(c, r, func(w io.Writer) {
+					_, _ = fmt.Fprintf(w, "since\t%s\n", r.Since.Format(time.DateTime))
+					_, _ = fmt.Fprintf(w, "merged\t%d\n", r.Merged)
+					_, _ = fmt.Fprintf(w, "average time to merge\t%s\n", r.AverageTimeToMerge.Round(time.Minute))
+					_, _ = fmt.Fprintf(w, "aborted\t%d\n", r.Aborted)
+					for _, reason := range r.AbortReasons() {
+						_, _ = fmt.Fprintf(w, "  %s\t%d\n", reason, r.AbortsByReason[reason])
+					}
+					_, _ = fmt.Fprintln(w)
+					for _, p := range r.Projects {
+						_, _ = fmt.Fprintf(w, "%s\t%d merged\t%d aborted\n", p.Project, p.Merged, p.Aborted)
+					}
+				})
+			})
+		},
+	}
+}
+
+// writeMarkdownReport renders the report as markdown for posting it to chat
+func writeMarkdownReport(w io.Writer, r ggl.Report) {
+	_, _ = fmt.Fprintf(w, "## Auto-merge report since %s\n\n", r.Since.Format(time.DateOnly))
+	_, _ = fmt.Fprintf(w, "- **%d** merged, average time to merge %s\n", r.Merged, r.AverageTimeToMerge.Round(time.Minute))
+	_, _ = fmt.Fprintf(w, "- **%d** aborted\n", r.Aborted)
+	for _, reason := range r.AbortReasons() {
+		_, _ = fmt.Fprintf(w, "  - %s: %d\n", reason, r.AbortsByReason[reason])
+	}
+	if len(r.Projects) == 0 {
+		return
+	}
+	_, _ = fmt.Fprint(w, "\n| Project | Merged | Aborted |\n|---|---:|---:|\n")
+	for _, p := range r.Projects {
+		_, _ = fmt.Fprintf(w, "| %s | %d | %d |\n", p.Project, p.Merged, p.Aborted)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"io"
+	"strings"
+)
+
+// searchCommand finds merge requests and projects in the cache and optionally on gitlab
+func searchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "search the cached merge requests (title, description, branch, project) and projects",
+		ArgsUsage: "<query>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "remote",
+				Usage: "query the gitlab search api if nothing cached matches",
+			},
+			outputFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return cli.ShowSubcommandHelp(c)
+			}
+			query := strings.Join(c.Args().Slice(), " ")
+			return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+				results, err := mrm.Search(query)
+				if err != nil {
+					return err
+				}
+				if len(results) == 0 && c.Bool("remote") {
+					results, err = mrm.SearchRemote(c.Context, query)
+					if err != nil {
+						return err
+					}
+				}
+				return printOutput(c, results, func(w io.Writer) {
+					for _, r := range results {
+						source := "cache"
+						if r.Remote {
+							source = "gitlab"
+						}
+						_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Kind, r.Reference, r.Title, r.WebURL, source)
+					}
+				})
+			})
+		},
+	}
+}
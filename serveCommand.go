@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// shutdownTimeout is how long the health server gets to finish its requests on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// tokenRotationInterval is how often serve checks whether the stored token expires within --rotate-token-days
+const tokenRotationInterval = 6 * time.Hour
+
+// healthAddrFlag is the listen address of the health endpoint, shared by serve and healthcheck
+func healthAddrFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "health-addr",
+		Usage:   "listen address of the /healthz and /readyz endpoints (empty disables them)",
+		Value:   ":8080",
+		EnvVars: []string{"GITLAB_UTIL_HEALTH_ADDR"},
+	}
+}
+
+// serveCommand runs auto-merge without ui until it gets SIGTERM or SIGINT, e.g. in a container
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "run auto-merge as daemon without ui: enable all mergeable merge requests of the filter and merge them until stopped",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "author",
+				Usage:   "author of the merge requests to auto merge (e.g. renovate-bot)",
+				EnvVars: []string{"GITLAB_UTIL_AUTHOR"},
+			},
+			&cli.StringFlag{
+				Name:    "reviewer",
+				Usage:   "reviewer of the merge requests to auto merge",
+				EnvVars: []string{"GITLAB_UTIL_REVIEWER"},
+			},
+			&cli.StringFlag{
+				Name:    "reviewer-group",
+				Usage:   "also auto merge the merge requests with a member of this group (path or id) as reviewer, e.g. a code owner team",
+				EnvVars: []string{"GITLAB_UTIL_REVIEWER_GROUP"},
+			},
+			&cli.StringFlag{
+				Name:    "assignee",
+				Usage:   "assignee of the merge requests to auto merge (username, None or Any)",
+				EnvVars: []string{"GITLAB_UTIL_ASSIGNEE"},
+			},
+			&cli.StringFlag{
+				Name:    "milestone",
+				Usage:   "only auto merge merge requests of this milestone (title, None or Any)",
+				EnvVars: []string{"GITLAB_UTIL_MILESTONE"},
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "how often the merge requests are fetched to enable new ones",
+				Value:   5 * time.Minute,
+				EnvVars: []string{"GITLAB_UTIL_INTERVAL"},
+			},
+			&cli.StringFlag{
+				Name:    "leader-lock",
+				Usage:   "project (path or id) holding the gitlab-util-leader label, only the instance holding its lease approves and merges, the others stand by (for several instances against the same gitlab)",
+				EnvVars: []string{"GITLAB_UTIL_LEADER_LOCK"},
+			},
+			&cli.StringFlag{
+				Name:    "leader-id",
+				Usage:   "name of this instance in the leader lock (default is hostname and pid)",
+				EnvVars: []string{"GITLAB_UTIL_LEADER_ID"},
+			},
+			&cli.IntFlag{
+				Name:    "rotate-token-days",
+				Usage:   "rotate the stored token when it expires within this many days, checked at the start and every 6 hours (0 disables)",
+				EnvVars: []string{"GITLAB_UTIL_ROTATE_TOKEN_DAYS"},
+			},
+			healthAddrFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if !hasMergeRequestFilter(c) {
+				return cli.Exit("serve needs --author, --reviewer, --reviewer-group and/or --assignee", 1)
+			}
+			if c.Duration("interval") <= 0 {
+				return cli.Exit("--interval must be positive", 1)
+			}
+			if project := c.String("leader-lock"); project != "" {
+				managerOptions = append(managerOptions, ggl.WithLeaderLock(project, leaderID(c)))
+			}
+			return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+				return serve(c, mrm)
+			})
+		},
+	}
+}
+
+// serve processes the merge targets in the background and enables the matching merge requests every interval. On
+// cancellation the merge step in progress is finished before the database is closed.
+func serve(c *cli.Context, mrm *ggl.MergeRequestManager) error {
+	ctx := c.Context
+	access, err := mrm.TokenAccess(ctx)
+	if err != nil {
+		slog.Warn("error reading token scopes", "err", err)
+	}
+	if !access.CanWrite {
+		return cli.Exit("the token has no api scope and can't approve or merge", 1)
+	}
+	if err := filterMergeRequests(c, mrm); err != nil {
+		return err
+	}
+
+	var health *http.Server
+	if addr := c.String("health-addr"); addr != "" {
+		health, err = startHealthServer(addr, mrm)
+		if err != nil {
+			return err
+		}
+	}
+
+	go logEvents(ctx, mrm)
+	mrm.Start(ctx)
+	slog.Info("serving auto-merge", "interval", c.Duration("interval"), "healthAddr", c.String("health-addr"))
+	var rotationChecked time.Time
+	for ctx.Err() == nil {
+		if days := c.Int("rotate-token-days"); days > 0 && time.Since(rotationChecked) >= tokenRotationInterval {
+			rotationChecked = time.Now()
+			rotateServeToken(mrm, days)
+		}
+		if err := mrm.FetchMergeRequests(ctx); err != nil {
+			slog.Error("error fetching merge requests", "err", err)
+		} else if _, err := mrm.EnableAllMatching(ctx); err != nil {
+			slog.Error("error enabling merge requests", "err", err)
+		}
+		select {
+		case <-time.After(c.Duration("interval")):
+		case <-ctx.Done():
+		}
+	}
+
+	slog.Info("stopping, finishing the merge step in progress")
+	mrm.Wait()
+	if health != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := health.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("error stopping health server", "err", err)
+		}
+	}
+	slog.Info("stopped")
+	return nil
+}
+
+// rotateServeToken rotates the stored token if it expires within days and switches the running manager to the new
+// one. Errors are logged only, the daemon keeps running until the token expires.
+func rotateServeToken(mrm *ggl.MergeRequestManager, days int) {
+	token, err := mrm.RotateExpiringToken(time.Duration(days)*24*time.Hour, ggl.DefaultTokenValidity)
+	if err != nil && token != nil {
+		fmt.Println("new token (store it manually):", token.Token)
+	}
+	if err != nil {
+		slog.Error("error rotating token", "err", err)
+		return
+	}
+	if token != nil {
+		slog.Info("rotated expiring token", "name", token.Name, "expiresAt", token.ExpiresAt.String())
+	}
+}
+
+// leaderID names this instance in the leader lock, hostnames are unique per pod or container
+func leaderID(c *cli.Context) string {
+	if id := c.String("leader-id"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// logEvents logs the outcomes of the processing, the daemon has no ui showing them. Stalls are logged by the manager.
+func logEvents(ctx context.Context, mrm *ggl.MergeRequestManager) {
+	for e := range mrm.Subscribe(ctx) {
+		if e.Type == ggl.EventProgress || e.Type == ggl.EventFetched || e.Type == ggl.EventStalled {
+			continue
+		}
+		slog.Info("auto-merge "+string(e.Type), "target", e.TargetID, "outcome", e.Outcome.Info())
+	}
+}
+
+// startHealthServer serves /healthz, failing if the processing stalled, and /readyz, which also fails if the database
+// or gitlab can't be reached. Both answer with the heartbeats and the problems found as json.
+func startHealthServer(addr string, mrm *ggl.MergeRequestManager) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, mrm.Liveness())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, mrm.Readiness(r.Context()))
+	})
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("health endpoint: %w", err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: shutdownTimeout}
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("health server stopped", "err", err)
+		}
+	}()
+	return server, nil
+}
+
+func writeHealth(w http.ResponseWriter, h ggl.Health) {
+	w.Header().Set("Content-Type", "application/json")
+	if !h.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(h)
+}
+
+// healthcheckCommand probes the health endpoint of a running serve, for container healthchecks in images without curl
+func healthcheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "healthcheck",
+		Usage: "check the /healthz endpoint of a running serve, exits with 1 if it is unhealthy",
+		Flags: []cli.Flag{healthAddrFlag()},
+		Action: func(c *cli.Context) error {
+			addr := c.String("health-addr")
+			if strings.HasPrefix(addr, ":") {
+				addr = "localhost" + addr
+			}
+			client := http.Client{Timeout: shutdownTimeout}
+			resp, err := client.Get("http://" + addr + "/healthz")
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return cli.Exit("unhealthy: "+resp.Status, 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"io"
+	"strconv"
+	"time"
+)
+
+// targetsCommand holds the subcommands inspecting the merge targets of auto-merge
+func targetsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "targets",
+		Usage: "merge target commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list the merge targets with their state, info and next attempt",
+				Flags: []cli.Flag{
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						targets, err := mrm.Targets()
+						if err != nil {
+							return err
+						}
+						now := time.Now()
+						return printOutput(c, targets, func(w io.Writer) {
+							for _, t := range targets {
+								_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Reference, targetState(t), t.Info,
+									relTime(t.LastAttempt, now), relTime(t.NextAttempt, now))
+							}
+						})
+					})
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "show a merge target with the history of its state transitions",
+				ArgsUsage: "<merge request id>",
+				Flags: []cli.Flag{
+					outputFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.ShowSubcommandHelp(c)
+					}
+					id, err := strconv.Atoi(c.Args().First())
+					if err != nil {
+						return cli.Exit("invalid merge request id "+c.Args().First(), 1)
+					}
+					return withMergeRequestManager(func(mrm *ggl.MergeRequestManager) error {
+						t, err := mrm.Target(id)
+						if err != nil {
+							return err
+						}
+						now := time.Now()
+						return printOutput(c, t, func(w io.Writer) {
+							_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", t.ID, t.Reference, t.Title)
+							_, _ = fmt.Fprintf(w, "state\t%s\t%s\n", targetState(t), t.Info)
+							_, _ = fmt.Fprintf(w, "next attempt\t%s\n", relTime(t.NextAttempt, now))
+							for _, e := range t.History {
+								_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", e.Time.Format(time.DateTime), e.State, e.Info)
+							}
+						})
+					})
+				},
+			},
+		},
+	}
+}
+
+// targetState is the outcome of the last attempt, or active/inactive if there was none yet
+func targetState(t ggl.TargetStatus) string {
+	switch {
+	case t.Outcome.NeedsHuman():
+		return "needs human"
+	case t.Outcome.State != "":
+		return string(t.Outcome.State)
+	case t.Active:
+		return "active"
+	}
+	return "inactive"
+}
+
+func relTime(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return humanize.RelTime(t, now, "ago", "from now")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gitu/gitlab-util/pkg/ggl"
+	"github.com/urfave/cli/v2"
+	"log/slog"
+	"time"
+)
+
+// tokenCommand holds the subcommands managing the stored token
+func tokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "token",
+		Usage: "token commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "encrypt",
+				Usage:     "replace the plaintext token file by one encrypted with a passphrase (asked for or read from " + ggl.PassphraseEnv + ")",
+				ArgsUsage: "[url]",
+				Action: func(c *cli.Context) error {
+					return ggl.EncryptToken(c.Args().First(), managerOptions...)
+				},
+			},
+			{
+				Name:      "rotate",
+				Usage:     "replace the stored token by a fresh one, gitlab revokes the old token",
+				ArgsUsage: "[url]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "expires-in-days",
+						Usage: "days until the new token expires",
+						Value: int(ggl.DefaultTokenValidity.Hours() / 24),
+					},
+				},
+				Action: func(c *cli.Context) error {
+					token, err := ggl.RotateToken(c.Args().First(), time.Duration(c.Int("expires-in-days"))*24*time.Hour, managerOptions...)
+					if err != nil && token != nil {
+						fmt.Println("new token (store it manually):", token.Token)
+					}
+					if err != nil {
+						return err
+					}
+					slog.Info("rotated token", "name", token.Name, "expiresAt", token.ExpiresAt.String())
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// rotateExpiringToken rotates the stored token at the start of auto-merge if --rotate-token-days is set
+func rotateExpiringToken(c *cli.Context) error {
+	days := c.Int("rotate-token-days")
+	if days <= 0 {
+		return nil
+	}
+	token, err := ggl.RotateTokenIfExpiring("", time.Duration(days)*24*time.Hour, ggl.DefaultTokenValidity, managerOptions...)
+	if err != nil && token != nil {
+		fmt.Println("new token (store it manually):", token.Token)
+	}
+	if err != nil {
+		return fmt.Errorf("rotating token: %w", err)
+	}
+	if token != nil {
+		slog.Info("rotated expiring token", "name", token.Name, "expiresAt", token.ExpiresAt.String())
+	}
+	return nil
+}